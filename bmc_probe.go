@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rmcpPresencePing is an ASF presence ping sent over RMCP. Every IPMI over LAN capable BMC answers it with a presence pong.
+var rmcpPresencePing = []byte{
+	0x06, 0x00, 0xff, 0x06, //RMCP version 1.0, reserved, no ack sequence, ASF class
+	0x00, 0x00, 0x11, 0xbe, //ASF IANA enterprise number
+	0x80, 0x00, 0x00, 0x00, //presence ping, message tag, reserved, data length
+}
+
+const rmcpPresencePong = 0x40
+
+// rmcpPing sends an RMCP presence ping to the IPMI port of a BMC and returns the round trip time
+func rmcpPing(host string, port int, timeout time.Duration) (time.Duration, error) {
+
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+
+	conn.SetDeadline(start.Add(timeout))
+
+	if _, err := conn.Write(rmcpPresencePing); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return 0, fmt.Errorf("no answer after %s", timeout)
+		}
+		return 0, err
+	}
+
+	if n < 9 || buf[8] != rmcpPresencePong {
+		return 0, fmt.Errorf("unexpected answer to the presence ping")
+	}
+
+	return time.Since(start), nil
+}
+
+// tlsProbe connects to a TCP port and returns the connection time and the certificate presented in the TLS handshake.
+// The certificate is not verified as BMCs usually use self signed certificates.
+func tlsProbe(host string, port int, timeout time.Duration) (time.Duration, *x509.Certificate, error) {
+
+	start := time.Now()
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer conn.Close()
+
+	latency := time.Since(start)
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+
+	if err := tlsConn.Handshake(); err != nil {
+		return latency, nil, fmt.Errorf("TLS handshake failed: %v", err)
+	}
+
+	certificates := tlsConn.ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return latency, nil, fmt.Errorf("no TLS certificate presented")
+	}
+
+	return latency, certificates[0], nil
+}
+
+// redfishServiceRoot holds the fields of the Redfish service root that identify a BMC
+type redfishServiceRoot struct {
+	RedfishVersion string                     `json:"RedfishVersion"`
+	Vendor         string                     `json:"Vendor"`
+	Product        string                     `json:"Product"`
+	UUID           string                     `json:"UUID"`
+	Oem            map[string]json.RawMessage `json:"Oem"`
+}
+
+// GetVendor returns the vendor of the BMC. Older Redfish versions have no Vendor property, in which case the OEM extension is used.
+func (r redfishServiceRoot) GetVendor() string {
+	if r.Vendor != "" {
+		return r.Vendor
+	}
+
+	for vendor := range r.Oem {
+		return vendor
+	}
+
+	return ""
+}
+
+// getRedfishServiceRoot performs a GET /redfish/v1 request against a BMC. As BMC certificates are usually self signed
+// they cannot be verified, so the connection is pinned to the certificate seen by tlsProbe instead. The credentials are
+// never sent to a BMC presenting another certificate.
+func getRedfishServiceRoot(host string, port int, pinned *x509.Certificate, username string, password string, timeout time.Duration) (*redfishServiceRoot, error) {
+
+	if pinned == nil {
+		return nil, fmt.Errorf("no TLS certificate to verify the BMC with")
+	}
+
+	client := http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+					if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], pinned.Raw) {
+						return fmt.Errorf("the BMC presented a different TLS certificate than the one probed")
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("https://%s/redfish/v1", net.JoinHostPort(host, strconv.Itoa(port))), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("redfish returned %s", resp.Status)
+	}
+
+	var root redfishServiceRoot
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid redfish service root: %v", err)
+	}
+
+	return &root, nil
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", float64(d)/float64(time.Millisecond))
+}
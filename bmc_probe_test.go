@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// startRMCPResponder starts a local UDP server that answers presence pings like a BMC
+func startRMCPResponder(t *testing.T) int {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 || buf[8] != 0x80 {
+				continue
+			}
+			pong := make([]byte, 28)
+			copy(pong, buf[:8])
+			pong[8] = rmcpPresencePong
+			pong[11] = 16
+			conn.WriteTo(pong, addr)
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// startMockRedfishServer starts a local HTTPS server that serves a Redfish service root to the given credentials
+func startMockRedfishServer(t *testing.T, username string, password string, serviceRoot string) (string, int) {
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/redfish/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(serviceRoot))
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u.Hostname(), port
+}
+
+func TestRMCPPing(t *testing.T) {
+	RegisterTestingT(t)
+
+	port := startRMCPResponder(t)
+
+	latency, err := rmcpPing("127.0.0.1", port, time.Second)
+	Expect(err).To(BeNil())
+	Expect(latency).To(BeNumerically(">", 0))
+
+	//nothing listens on this port
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	defer silent.Close()
+
+	_, err = rmcpPing("127.0.0.1", silent.LocalAddr().(*net.UDPAddr).Port, 100*time.Millisecond)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestTLSProbe(t *testing.T) {
+	RegisterTestingT(t)
+
+	host, port := startMockRedfishServer(t, "admin", "pass", "{}")
+
+	latency, cert, err := tlsProbe(host, port, time.Second)
+	Expect(err).To(BeNil())
+	Expect(latency).To(BeNumerically(">", 0))
+	Expect(cert).NotTo(BeNil())
+
+	//a closed port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	closedPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	_, _, err = tlsProbe("127.0.0.1", closedPort, time.Second)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestGetRedfishServiceRoot(t *testing.T) {
+	RegisterTestingT(t)
+
+	host, port := startMockRedfishServer(t, "admin", "pass", `{"RedfishVersion":"1.6.0","Product":"PowerEdge R640","Oem":{"Dell":{}}}`)
+
+	_, cert, err := tlsProbe(host, port, time.Second)
+	Expect(err).To(BeNil())
+
+	root, err := getRedfishServiceRoot(host, port, cert, "admin", "pass", time.Second)
+	Expect(err).To(BeNil())
+	Expect(root.RedfishVersion).To(Equal("1.6.0"))
+	Expect(root.GetVendor()).To(Equal("Dell"))
+
+	_, err = getRedfishServiceRoot(host, port, cert, "admin", "wrong", time.Second)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("401"))
+
+	//the credentials are not sent to a BMC with another certificate
+	_, err = getRedfishServiceRoot(host, port, &x509.Certificate{Raw: []byte("other")}, "admin", "pass", time.Second)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("different TLS certificate"))
+
+	_, err = getRedfishServiceRoot(host, port, nil, "admin", "pass", time.Second)
+	Expect(err).NotTo(BeNil())
+}
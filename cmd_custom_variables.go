@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+)
+
+var customVariablesCmds = []Command{
+	{
+		Description:  "Show the custom variables of an instance, instance array or infrastructure.",
+		Subject:      "custom-variables",
+		AltSubject:   "cv",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get custom variables", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_id":                c.FlagSet.Int("instance", _nilDefaultInt, "Instance's id."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "InstanceArray's id or label. Note that the label can be ambigous."),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Infrastructure's id or label. Note that the label can be ambigous."),
+				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: customVariablesGetCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli custom-variables get --instance 100 #shows the variables the instance will see, including those inherited from its instance array and infrastructure
+`,
+	},
+	{
+		Description:  "Set custom variables of an instance, instance array or infrastructure.",
+		Subject:      "custom-variables",
+		AltSubject:   "cv",
+		Predicate:    "set",
+		AltPredicate: "add",
+		FlagSet:      flag.NewFlagSet("set custom variables", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_id":                c.FlagSet.Int("instance", _nilDefaultInt, "Instance's id."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "InstanceArray's id or label. Note that the label can be ambigous."),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Infrastructure's id or label. Note that the label can be ambigous."),
+				"format":                     c.FlagSet.String("format", "json", "The input format of the variables file. Supported values are 'json','yaml'. The default format is json."),
+				"read_config_from_file":      c.FlagSet.String("raw-config", _nilDefaultStr, "Read the variables from a file in the format specified with --format. Values can be objects or lists."),
+				"read_config_from_pipe":      c.FlagSet.Bool("pipe", false, green("(Flag)")+" If set, read the variables from pipe instead of from a file."),
+			}
+		},
+		ExecuteFunc: customVariablesSetCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli custom-variables set --ia 100 ntp_server=10.0.0.1 env=prod
+metalcloud-cli custom-variables set --infra my-infra --format yaml --raw-config ./vars.yaml
+`,
+	},
+	{
+		Description:  "Remove custom variables of an instance, instance array or infrastructure.",
+		Subject:      "custom-variables",
+		AltSubject:   "cv",
+		Predicate:    "unset",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("unset custom variables", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_id":                c.FlagSet.Int("instance", _nilDefaultInt, "Instance's id."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "InstanceArray's id or label. Note that the label can be ambigous."),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Infrastructure's id or label. Note that the label can be ambigous."),
+			}
+		},
+		ExecuteFunc: customVariablesUnsetCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli custom-variables unset --ia 100 ntp_server env
+`,
+	},
+}
+
+// customVariablesLevel holds the custom variables of an infrastructure, instance array or instance
+type customVariablesLevel struct {
+	Kind      string
+	ID        int
+	Label     string
+	Variables map[string]interface{}
+}
+
+// customVariablesTarget is the object whose custom variables are edited, along with the objects it inherits variables from
+type customVariablesTarget struct {
+	// Levels are ordered by precedence, from the infrastructure to the target itself
+	Levels []customVariablesLevel
+	save   func(variables map[string]interface{}) error
+}
+
+func (t customVariablesTarget) Target() customVariablesLevel {
+	return t.Levels[len(t.Levels)-1]
+}
+
+func customVariablesGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	target, err := getCustomVariablesTarget(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "KEY",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "VALUE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "SOURCE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	values := map[string]interface{}{}
+	sources := map[string]string{}
+
+	for _, level := range target.Levels {
+		for k, v := range level.Variables {
+			source := level.Kind
+			if previous, ok := sources[k]; ok {
+				source = fmt.Sprintf("%s (overrides %s)", level.Kind, previous)
+			}
+			values[k] = v
+			sources[k] = source
+		}
+	}
+
+	keys := []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	data := [][]interface{}{}
+	for _, k := range keys {
+		data = append(data, []interface{}{
+			k,
+			customVariableToString(values[k]),
+			sources[k],
+		})
+	}
+
+	t := target.Target()
+
+	subtitle := ""
+	if len(target.Levels) > 1 {
+		kinds := []string{}
+		for _, level := range target.Levels {
+			kinds = append(kinds, level.Kind)
+		}
+		subtitle = fmt.Sprintf("Effective variables, precedence: %s", strings.Join(kinds, " -> "))
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTable(fmt.Sprintf("Custom variables of %s %s (#%d)", t.Kind, t.Label, t.ID), subtitle, getStringParam(c.Arguments["format"]))
+}
+
+func customVariablesSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	variables := map[string]interface{}{}
+
+	_, fileOk := getStringParamOk(c.Arguments["read_config_from_file"])
+	if fileOk || getBoolParam(c.Arguments["read_config_from_pipe"]) {
+		err := getRawObjectFromCommand(c, &variables)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	pairs, err := getCustomVariablesFromArgs(c)
+	if err != nil {
+		return "", err
+	}
+
+	for k, v := range pairs {
+		variables[k] = v
+	}
+
+	if len(variables) == 0 {
+		return "", fmt.Errorf("at least one key=value pair, --raw-config or --pipe is required")
+	}
+
+	target, err := getCustomVariablesTarget(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	current := target.Target().Variables
+	for k, v := range variables {
+		current[k] = v
+	}
+
+	return "", target.save(current)
+}
+
+func customVariablesUnsetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	keys := []string{}
+	if c.FlagSet != nil {
+		keys = c.FlagSet.Args()
+	}
+
+	if len(keys) == 0 {
+		return "", fmt.Errorf("at least one variable name is required")
+	}
+
+	target, err := getCustomVariablesTarget(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	t := target.Target()
+
+	for _, k := range keys {
+		if _, ok := t.Variables[k]; !ok {
+			return "", fmt.Errorf("variable %s is not set on %s %s (#%d)", k, t.Kind, t.Label, t.ID)
+		}
+		delete(t.Variables, k)
+	}
+
+	return "", target.save(t.Variables)
+}
+
+// getCustomVariablesFromArgs parses the key=value pairs given after the flags. The value is everything after the first =.
+func getCustomVariablesFromArgs(c *Command) (map[string]interface{}, error) {
+
+	m := map[string]interface{}{}
+
+	if c.FlagSet == nil {
+		return m, nil
+	}
+
+	for _, arg := range c.FlagSet.Args() {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("variable has invalid format expecting key=value, given %s", arg)
+		}
+		m[arg[:i]] = arg[i+1:]
+	}
+
+	return m, nil
+}
+
+// getCustomVariablesTarget returns the instance, instance array or infrastructure designated by the command's arguments
+// along with the objects it inherits custom variables from. Pending (not yet deployed) changes are included.
+func getCustomVariablesTarget(c *Command, client metalcloud.MetalCloudClient) (*customVariablesTarget, error) {
+
+	instanceID, instanceOk := getIntParamOk(c.Arguments["instance_id"])
+	_, iaOk := getStringParamOk(c.Arguments["instance_array_id_or_label"])
+	_, infraOk := getStringParamOk(c.Arguments["infrastructure_id_or_label"])
+
+	count := 0
+	for _, ok := range []bool{instanceOk, iaOk, infraOk} {
+		if ok {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return nil, fmt.Errorf("exactly one of --instance, --ia or --infra is required")
+	}
+
+	target := customVariablesTarget{}
+
+	var infrastructureID int
+	var instanceArrayID int
+	var instance *metalcloud.Instance
+
+	switch {
+	case instanceOk:
+		var err error
+		instance, err = client.InstanceGet(instanceID)
+		if err != nil {
+			return nil, err
+		}
+		instanceArrayID = instance.InstanceArrayID
+	case iaOk:
+		ia, err := getInstanceArrayFromCommand("ia", c, client)
+		if err != nil {
+			return nil, err
+		}
+		instanceArrayID = ia.InstanceArrayID
+	case infraOk:
+		infra, err := getInfrastructureFromCommand("infra", c, client)
+		if err != nil {
+			return nil, err
+		}
+		infrastructureID = infra.InfrastructureID
+	}
+
+	var ia *metalcloud.InstanceArray
+	if instanceArrayID != 0 {
+		var err error
+		ia, err = client.InstanceArrayGet(instanceArrayID)
+		if err != nil {
+			return nil, err
+		}
+		infrastructureID = ia.InfrastructureID
+	}
+
+	infra, err := client.InfrastructureGet(infrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	target.Levels = append(target.Levels, customVariablesLevel{
+		Kind:      "infrastructure",
+		ID:        infra.InfrastructureID,
+		Label:     infra.InfrastructureOperation.InfrastructureLabel,
+		Variables: getCustomVariablesMap(infra.InfrastructureOperation.InfrastructureCustomVariables),
+	})
+
+	target.save = func(variables map[string]interface{}) error {
+		op := infra.InfrastructureOperation
+		op.InfrastructureCustomVariables = variables
+		_, err := client.InfrastructureEdit(infra.InfrastructureID, op)
+		return err
+	}
+
+	if ia != nil {
+		iao := ia.InstanceArrayOperation
+		if iao == nil {
+			iao = &metalcloud.InstanceArrayOperation{}
+			copyInstanceArrayToOperation(*ia, iao)
+		}
+
+		target.Levels = append(target.Levels, customVariablesLevel{
+			Kind:      "instance array",
+			ID:        ia.InstanceArrayID,
+			Label:     iao.InstanceArrayLabel,
+			Variables: getCustomVariablesMap(iao.InstanceArrayCustomVariables),
+		})
+
+		target.save = func(variables map[string]interface{}) error {
+			op := *iao
+			op.InstanceArrayCustomVariables = variables
+			_, err := client.InstanceArrayEdit(ia.InstanceArrayID, op, nil, nil, nil, nil)
+			return err
+		}
+	}
+
+	if instance != nil {
+		target.Levels = append(target.Levels, customVariablesLevel{
+			Kind:      "instance",
+			ID:        instance.InstanceID,
+			Label:     instance.InstanceLabel,
+			Variables: getCustomVariablesMap(instance.InstanceOperation.InstanceCustomVariables),
+		})
+
+		target.save = func(variables map[string]interface{}) error {
+			op := instance.InstanceOperation
+			op.InstanceCustomVariables = variables
+			_, err := client.InstanceEdit(instance.InstanceID, op)
+			return err
+		}
+	}
+
+	return &target, nil
+}
+
+// getCustomVariablesMap returns a copy of the custom variables as a map. Empty variables are returned by the API as an empty list.
+func getCustomVariablesMap(v interface{}) map[string]interface{} {
+
+	m := map[string]interface{}{}
+
+	switch vars := v.(type) {
+	case map[string]interface{}:
+		for k, v := range vars {
+			m[k] = v
+		}
+	case map[string]string:
+		for k, v := range vars {
+			m[k] = v
+		}
+	}
+
+	return m
+}
+
+// customVariableToString returns strings as they are and complex values as json
+func customVariableToString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
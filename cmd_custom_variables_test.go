@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+func TestCustomVariablesGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10).
+		Return(&metalcloud.Infrastructure{
+			InfrastructureID: 10,
+			InfrastructureOperation: metalcloud.InfrastructureOperation{
+				InfrastructureLabel: "infra",
+				InfrastructureCustomVariables: map[string]interface{}{
+					"env": "prod",
+					"ntp": "10.0.0.1",
+				},
+			},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&metalcloud.InstanceArray{
+			InstanceArrayID:  100,
+			InfrastructureID: 10,
+			InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+				InstanceArrayLabel: "ia",
+				InstanceArrayCustomVariables: map[string]interface{}{
+					"ntp":  "10.0.0.2",
+					"dns":  []interface{}{"1.1.1.1", "8.8.8.8"},
+					"role": "web",
+				},
+			},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceGet(200).
+		Return(&metalcloud.Instance{
+			InstanceID:      200,
+			InstanceLabel:   "instance-200",
+			InstanceArrayID: 100,
+			InstanceOperation: metalcloud.InstanceOperation{
+				InstanceCustomVariables: map[string]interface{}{
+					"role": "db",
+				},
+			},
+		}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_id": 200,
+		"format":      "json",
+	})
+
+	ret, err := customVariablesGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(4))
+
+	Expect(rows[0]["KEY"]).To(Equal("dns"))
+	Expect(rows[0]["VALUE"]).To(Equal(`["1.1.1.1","8.8.8.8"]`))
+	Expect(rows[0]["SOURCE"]).To(Equal("instance array"))
+
+	Expect(rows[1]["KEY"]).To(Equal("env"))
+	Expect(rows[1]["SOURCE"]).To(Equal("infrastructure"))
+
+	Expect(rows[2]["KEY"]).To(Equal("ntp"))
+	Expect(rows[2]["VALUE"]).To(Equal("10.0.0.2"))
+	Expect(rows[2]["SOURCE"]).To(Equal("instance array (overrides infrastructure)"))
+
+	Expect(rows[3]["KEY"]).To(Equal("role"))
+	Expect(rows[3]["VALUE"]).To(Equal("db"))
+	Expect(rows[3]["SOURCE"]).To(Equal("instance (overrides instance array)"))
+
+	//only one target is allowed
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_id":                200,
+		"infrastructure_id_or_label": "10",
+	})
+
+	_, err = customVariablesGetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeEmptyCommand()
+	_, err = customVariablesGetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestCustomVariablesSetUnsetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID: 10,
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureLabel: "infra",
+		},
+	}
+
+	client.EXPECT().
+		InfrastructureGet(10).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&metalcloud.InstanceArray{
+			InstanceArrayID:  100,
+			InfrastructureID: 10,
+			InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+				InstanceArrayID:    100,
+				InstanceArrayLabel: "ia",
+				InstanceArrayCustomVariables: map[string]interface{}{
+					"env": "prod",
+					"ntp": "10.0.0.1",
+				},
+			},
+		}, nil).
+		AnyTimes()
+
+	var saved map[string]interface{}
+
+	client.EXPECT().
+		InstanceArrayEdit(100, gomock.Any(), nil, nil, nil, nil).
+		DoAndReturn(func(id int, op metalcloud.InstanceArrayOperation, swap *bool, keep *bool, matches *metalcloud.ServerTypeMatches, deleted *[]int) (*metalcloud.InstanceArray, error) {
+			saved = op.InstanceArrayCustomVariables.(map[string]interface{})
+			return nil, nil
+		}).
+		AnyTimes()
+
+	dir := t.TempDir()
+	varsFile := filepath.Join(dir, "vars.yaml")
+	Expect(os.WriteFile(varsFile, []byte("dns:\n  - 1.1.1.1\n  - 8.8.8.8\nrole: web\n"), 0600)).To(BeNil())
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"format":                     "yaml",
+		"read_config_from_file":      varsFile,
+	})
+	cmd.FlagSet = flag.NewFlagSet("set", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"ntp=10.0.0.2", "url=http://host/?a=b"})).To(BeNil())
+
+	_, err := customVariablesSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(saved).To(HaveLen(5))
+	Expect(saved["env"]).To(Equal("prod"))
+	Expect(saved["ntp"]).To(Equal("10.0.0.2"))
+	Expect(saved["url"]).To(Equal("http://host/?a=b"))
+	Expect(saved["role"]).To(Equal("web"))
+	Expect(saved["dns"]).To(Equal([]interface{}{"1.1.1.1", "8.8.8.8"}))
+
+	//invalid pair
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+	})
+	cmd.FlagSet = flag.NewFlagSet("set", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"=value"})).To(BeNil())
+
+	_, err = customVariablesSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//nothing to set
+	cmd.FlagSet = flag.NewFlagSet("set", flag.ContinueOnError)
+	_, err = customVariablesSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd.FlagSet = flag.NewFlagSet("unset", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"ntp"})).To(BeNil())
+
+	_, err = customVariablesUnsetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(saved).To(Equal(map[string]interface{}{"env": "prod"}))
+
+	cmd.FlagSet = flag.NewFlagSet("unset", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"missing"})).To(BeNil())
+
+	_, err = customVariablesUnsetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//infrastructure variables returned as an empty list by the api
+	infra.InfrastructureOperation.InfrastructureCustomVariables = []interface{}{}
+
+	client.EXPECT().
+		InfrastructureEdit(10, gomock.Any()).
+		DoAndReturn(func(id int, op metalcloud.InfrastructureOperation) (*metalcloud.Infrastructure, error) {
+			saved = op.InfrastructureCustomVariables.(map[string]interface{})
+			return nil, nil
+		}).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10",
+	})
+	cmd.FlagSet = flag.NewFlagSet("set", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"env=dev"})).To(BeNil())
+
+	_, err = customVariablesSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(saved).To(Equal(map[string]interface{}{"env": "dev"}))
+}
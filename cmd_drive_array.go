@@ -102,6 +102,32 @@ var driveArrayCmds = []Command{
 		},
 		ExecuteFunc: driveArrayGetCmd,
 	},
+	{
+		Description:  "Grow the drives of a drive array.",
+		Subject:      "drive-array",
+		AltSubject:   "da",
+		Predicate:    "resize",
+		AltPredicate: "grow",
+		FlagSet:      flag.NewFlagSet("resize drive_array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"drive_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Drive Array's ID or label. Note that using the label can be ambiguous and is slower."),
+				"drive_size_mbytes":       c.FlagSet.Int("size", _nilDefaultInt, red("(Required)")+" The new size of each drive in MBytes. Drives can only grow."),
+				"deploy":                  c.FlagSet.Bool("deploy", false, green("(Flag)")+" If set the infrastructure will be deployed after the change."),
+				"allow_data_loss":         c.FlagSet.Bool("allow-data-loss", false, green("(Flag)")+" If set, deploy will not throw error if data loss is expected."),
+				"block_until_deployed":    c.FlagSet.Bool("blocking", false, green("(Flag)")+" If set, the operation will wait until deployment finishes."),
+				"block_timeout":           c.FlagSet.Int("block-timeout", 180*60, "Block timeout in seconds. After this timeout the application will return an error. Defaults to 180 minutes."),
+				"block_check_interval":    c.FlagSet.Int("block-check-interval", 10, "Check interval for when blocking. Defaults to 10 seconds."),
+				"autoconfirm":             c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc:   driveArrayResizeCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+		Example: `
+metalcloud-cli drive-array resize --id 100 --size 102400 --deploy --blocking
+`,
+	},
 }
 
 func driveArrayCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -551,3 +577,106 @@ func getDriveArrayFromCommand(c *Command, client metalcloud.MetalCloudClient) (*
 
 	return client.DriveArrayGetByLabel(label)
 }
+
+func driveArrayResizeCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retDA, err := getDriveArrayFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	newSize, ok := getIntParamOk(c.Arguments["drive_size_mbytes"])
+	if !ok {
+		return "", fmt.Errorf("-size is required")
+	}
+
+	dao := retDA.DriveArrayOperation
+	if dao == nil {
+		return "", fmt.Errorf("drive array %s (#%d) has no operation object", retDA.DriveArrayLabel, retDA.DriveArrayID)
+	}
+
+	currentSize := dao.DriveSizeMBytesDefault
+	if currentSize == 0 {
+		currentSize = retDA.DriveSizeMBytesDefault
+	}
+
+	if newSize < currentSize {
+		return "", fmt.Errorf("drives cannot be shrunk: drive array %s (#%d) has drives of %d MB", retDA.DriveArrayLabel, retDA.DriveArrayID, currentSize)
+	}
+
+	if newSize == currentSize {
+		return "", fmt.Errorf("drive array %s (#%d) already has drives of %d MB", retDA.DriveArrayLabel, retDA.DriveArrayID, currentSize)
+	}
+
+	if dao.VolumeTemplateID != 0 {
+		vt, err := client.VolumeTemplateGet(dao.VolumeTemplateID)
+		if err != nil {
+			return "", err
+		}
+
+		if newSize < vt.VolumeTemplateSizeMBytes {
+			return "", fmt.Errorf("the new size of %d MB is smaller than the %d MB required by volume template %s (#%d)",
+				newSize, vt.VolumeTemplateSizeMBytes, vt.VolumeTemplateLabel, vt.VolumeTemplateID)
+		}
+	}
+
+	drives, err := client.DriveArrayDrives(retDA.DriveArrayID)
+	if err != nil {
+		return "", err
+	}
+
+	driveCount := 0
+	for _, d := range *drives {
+		if d.DriveServiceStatus != "deleted" {
+			driveCount++
+		}
+	}
+	if driveCount == 0 {
+		driveCount = dao.DriveArrayCount
+	}
+
+	growth := (newSize - currentSize) * driveCount
+
+	if retDA.StoragePoolID != 0 {
+		pool, err := client.StoragePoolGet(retDA.StoragePoolID, false)
+		if err != nil {
+			fmt.Fprintf(GetStdout(), "%s could not check the free capacity of storage pool #%d: %v\n", yellow("WARNING:"), retDA.StoragePoolID, err)
+		} else if growth > pool.StoragePoolCapacityFreeCachedRealMbytes {
+			return "", fmt.Errorf("growing %d drives by %d MB needs %d MB but storage pool %s (#%d) has only %d MB free",
+				driveCount, newSize-currentSize, growth, pool.StoragePoolName, pool.StoragePoolID, pool.StoragePoolCapacityFreeCachedRealMbytes)
+		}
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Resizing the %d drives of drive array %s (%d) from %d MB to %d MB (%d MB in total). Drives cannot be shrunk afterwards. Are you sure? Type \"yes\" to continue:",
+			driveCount,
+			retDA.DriveArrayLabel, retDA.DriveArrayID,
+			currentSize, newSize,
+			growth)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	op := *dao
+	op.DriveSizeMBytesDefault = newSize
+
+	_, err = client.DriveArrayEdit(retDA.DriveArrayID, op)
+	if err != nil {
+		return "", err
+	}
+
+	return "", instanceArrayDeployIfRequested(retDA.InfrastructureID, c, client)
+}
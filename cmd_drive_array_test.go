@@ -681,3 +681,107 @@ func TestDriveArrayGetCmd(t *testing.T) {
 	testGetCommand(driveArrayGetCmd, cases, client, expectedFirstRow, t)
 
 }
+
+func TestDriveArrayResizeCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:           100,
+		DriveArrayLabel:        "da",
+		InfrastructureID:       10,
+		StoragePoolID:          5,
+		DriveSizeMBytesDefault: 40960,
+		DriveArrayOperation: &metalcloud.DriveArrayOperation{
+			DriveArrayID:           100,
+			DriveArrayLabel:        "da",
+			VolumeTemplateID:       20,
+			DriveSizeMBytesDefault: 40960,
+		},
+	}
+
+	client.EXPECT().
+		DriveArrayGet(100).
+		Return(&da, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		VolumeTemplateGet(20).
+		Return(&metalcloud.VolumeTemplate{VolumeTemplateID: 20, VolumeTemplateSizeMBytes: 40960}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrayDrives(100).
+		Return(&map[string]metalcloud.Drive{
+			"d1": {DriveID: 1, DriveServiceStatus: "active"},
+			"d2": {DriveID: 2, DriveServiceStatus: "active"},
+			"d3": {DriveID: 3, DriveServiceStatus: "deleted"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		StoragePoolGet(5, false).
+		Return(&metalcloud.StoragePool{StoragePoolID: 5, StoragePoolName: "pool", StoragePoolCapacityFreeCachedRealMbytes: 100000}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrayEdit(100, gomock.Any()).
+		DoAndReturn(func(id int, op metalcloud.DriveArrayOperation) (*metalcloud.DriveArray, error) {
+			Expect(op.DriveSizeMBytesDefault).To(Equal(81920))
+			Expect(op.VolumeTemplateID).To(Equal(20))
+			return &da, nil
+		}).
+		Times(1)
+
+	cases := []CommandTestCase{
+		{
+			name: "grow",
+			cmd: MakeCommand(map[string]interface{}{
+				"drive_array_id_or_label": 100,
+				"drive_size_mbytes":       81920,
+				"autoconfirm":             true,
+			}),
+			good: true,
+		},
+		{
+			name: "shrink",
+			cmd: MakeCommand(map[string]interface{}{
+				"drive_array_id_or_label": 100,
+				"drive_size_mbytes":       20480,
+				"autoconfirm":             true,
+			}),
+			good: false,
+		},
+		{
+			name: "same size",
+			cmd: MakeCommand(map[string]interface{}{
+				"drive_array_id_or_label": 100,
+				"drive_size_mbytes":       40960,
+				"autoconfirm":             true,
+			}),
+			good: false,
+		},
+		{
+			//2 drives growing by 61440 MB need more than the 100000 MB free
+			name: "not enough free capacity",
+			cmd: MakeCommand(map[string]interface{}{
+				"drive_array_id_or_label": 100,
+				"drive_size_mbytes":       102400,
+				"autoconfirm":             true,
+			}),
+			good: false,
+		},
+		{
+			name: "size missing",
+			cmd: MakeCommand(map[string]interface{}{
+				"drive_array_id_or_label": 100,
+				"autoconfirm":             true,
+			}),
+			good: false,
+		},
+	}
+
+	testCreateCommand(driveArrayResizeCmd, cases, client, t)
+}
@@ -4,7 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
@@ -73,6 +75,120 @@ var driveSnapshotCmds = []Command{
 		},
 		ExecuteFunc: driveSnapshotRollbackCmd,
 	},
+	{
+		Description:  "Creates a snapshot policy.",
+		Subject:      "drive-snapshot",
+		AltSubject:   "snapshot",
+		Predicate:    "policy-create",
+		AltPredicate: "policy-new",
+		FlagSet:      flag.NewFlagSet("drive snapshot policy create", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_label":            c.FlagSet.String("label", _nilDefaultStr, red("(Required)")+" The label of the policy."),
+				"drive_array_id_or_label": c.FlagSet.String("drive-array", _nilDefaultStr, "Drive Array's ID or label. All the drives of the drive array are snapshotted. Either this or --drive is required."),
+				"drive_id":                c.FlagSet.Int("drive", _nilDefaultInt, "The id of a drive to snapshot. Either this or --drive-array is required."),
+				"schedule":                c.FlagSet.String("schedule", _nilDefaultStr, red("(Required)")+" Cron-like schedule: minute hour day-of-month month day-of-week, for example '0 2 * * *'. @hourly, @daily, @weekly and @monthly are also accepted."),
+				"keep_last":               c.FlagSet.Int("keep-last", _nilDefaultInt, "Number of snapshots created by the policy to keep for each drive. When used with --max-age a snapshot is kept if either rule keeps it."),
+				"max_age_days":            c.FlagSet.Int("max-age", _nilDefaultInt, "Snapshots created by the policy older than this number of days are deleted, unless --keep-last keeps them. The most recent snapshot is always kept."),
+				"return_id":               c.FlagSet.Bool("return-id", false, "(Optional) Will print the ID of the created policy. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: driveSnapshotPolicyCreateCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli drive-snapshot policy-create --label nightly --drive-array 100 --schedule "0 2 * * *" --keep-last 7
+`,
+	},
+	{
+		Description:  "Lists snapshot policies.",
+		Subject:      "drive-snapshot",
+		AltSubject:   "snapshot",
+		Predicate:    "policy-list",
+		AltPredicate: "policy-ls",
+		FlagSet:      flag.NewFlagSet("drive snapshot policy list", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"format": c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: driveSnapshotPolicyListCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Deletes a snapshot policy. The snapshots created by the policy are kept.",
+		Subject:      "drive-snapshot",
+		AltSubject:   "snapshot",
+		Predicate:    "policy-delete",
+		AltPredicate: "policy-rm",
+		FlagSet:      flag.NewFlagSet("drive snapshot policy delete", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id":   c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy."),
+				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: driveSnapshotPolicyDeleteCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Creates the snapshots of the policies that are due and deletes the snapshots outside of the retention rules.",
+		Subject:      "drive-snapshot",
+		AltSubject:   "snapshot",
+		Predicate:    "run-policies",
+		AltPredicate: "policies-run",
+		FlagSet:      flag.NewFlagSet("drive snapshot run policies", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id": c.FlagSet.Int("id", _nilDefaultInt, "Only run the policy with this id."),
+				"force":     c.FlagSet.Bool("force", false, green("(Flag)")+" If set snapshots are created even if the policies are not due."),
+				"dry_run":   c.FlagSet.Bool("dry-run", false, green("(Flag)")+" If set only shows what would be created and deleted."),
+				"format":    c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: driveSnapshotRunPoliciesCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+# Add the following to crontab to check the policies every minute:
+* * * * * metalcloud-cli drive-snapshot run-policies
+`,
+	},
+}
+
+// snapshotPoliciesFile is the local state file holding the snapshot policies
+const snapshotPoliciesFile = "snapshot_policies.json"
+
+// snapshotPolicy periodically snapshots a drive or all the drives of a drive array.
+// Only the snapshots created by the policy are subject to its retention rules.
+// PendingDriveIDs holds the drives whose snapshot failed, which are retried on the next runs.
+type snapshotPolicy struct {
+	ID              int              `json:"id"`
+	Label           string           `json:"label"`
+	DriveArrayID    int              `json:"drive_array_id,omitempty"`
+	DriveID         int              `json:"drive_id,omitempty"`
+	Schedule        string           `json:"schedule"`
+	KeepLast        int              `json:"keep_last,omitempty"`
+	MaxAgeDays      int              `json:"max_age_days,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	LastRun         time.Time        `json:"last_run"`
+	PendingDriveIDs []int            `json:"pending_drive_ids,omitempty"`
+	Snapshots       []policySnapshot `json:"snapshots"`
+}
+
+// policySnapshot is a snapshot created by a policy
+type policySnapshot struct {
+	DriveID    int       `json:"drive_id"`
+	SnapshotID int       `json:"snapshot_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func readSnapshotPolicies() ([]snapshotPolicy, error) {
+	list := []snapshotPolicy{}
+	err := readLocalState(snapshotPoliciesFile, &list)
+	return list, err
+}
+
+func writeSnapshotPolicies(list []snapshotPolicy) error {
+	return writeLocalState(snapshotPoliciesFile, list)
 }
 
 func driveSnapshotCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -231,3 +347,543 @@ func driveSnapshotRollbackCmd(c *Command, client metalcloud.MetalCloudClient) (s
 
 	return "", err
 }
+
+func driveSnapshotPolicyCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	label, ok := getStringParamOk(c.Arguments["policy_label"])
+	if !ok {
+		return "", fmt.Errorf("-label is required")
+	}
+
+	schedule, ok := getStringParamOk(c.Arguments["schedule"])
+	if !ok {
+		return "", fmt.Errorf("-schedule is required")
+	}
+
+	if _, err := parseCronSchedule(schedule); err != nil {
+		return "", err
+	}
+
+	policy := snapshotPolicy{
+		Label:     label,
+		Schedule:  schedule,
+		CreatedAt: time.Now(),
+		Snapshots: []policySnapshot{},
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["drive_array_id_or_label"]); ok {
+		daID, err := getIDOrDo(v, func(label string) (int, error) {
+			da, err := client.DriveArrayGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return da.DriveArrayID, nil
+		})
+		if err != nil {
+			return "", err
+		}
+		policy.DriveArrayID = daID
+	}
+
+	if v, ok := getIntParamOk(c.Arguments["drive_id"]); ok {
+		policy.DriveID = v
+	}
+
+	if (policy.DriveArrayID == 0) == (policy.DriveID == 0) {
+		return "", fmt.Errorf("exactly one of -drive-array or -drive is required")
+	}
+
+	policy.KeepLast = getIntParam(c.Arguments["keep_last"])
+	policy.MaxAgeDays = getIntParam(c.Arguments["max_age_days"])
+
+	if policy.KeepLast <= 0 && policy.MaxAgeDays <= 0 {
+		return "", fmt.Errorf("at least one retention rule is required: -keep-last or -max-age")
+	}
+
+	if policy.DriveArrayID != 0 {
+		if _, err := client.DriveArrayGet(policy.DriveArrayID); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := client.DriveSnapshots(policy.DriveID); err != nil {
+			return "", err
+		}
+	}
+
+	list, err := readSnapshotPolicies()
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range list {
+		if p.ID > policy.ID {
+			policy.ID = p.ID
+		}
+	}
+	policy.ID++
+
+	list = append(list, policy)
+
+	if err := writeSnapshotPolicies(list); err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", policy.ID), nil
+	}
+
+	return "", nil
+}
+
+// describeSnapshotPolicyTarget returns the drive or drive array snapshotted by a policy
+func describeSnapshotPolicyTarget(p snapshotPolicy) string {
+	if p.DriveArrayID != 0 {
+		return fmt.Sprintf("drive array #%d", p.DriveArrayID)
+	}
+	return fmt.Sprintf("drive #%d", p.DriveID)
+}
+
+// describeSnapshotPolicyRetention returns the retention rules of a policy
+func describeSnapshotPolicyRetention(p snapshotPolicy) string {
+	rules := []string{}
+	if p.KeepLast > 0 {
+		rules = append(rules, fmt.Sprintf("keep last %d", p.KeepLast))
+	}
+	if p.MaxAgeDays > 0 {
+		rules = append(rules, fmt.Sprintf("max %d days", p.MaxAgeDays))
+	}
+	return strings.Join(rules, ", ")
+}
+
+func driveSnapshotPolicyListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	list, err := readSnapshotPolicies()
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "TARGET",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "SCHEDULE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "RETENTION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "LAST RUN",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "NEXT RUN",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "SNAPSHOTS",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, p := range list {
+
+		lastRun := ""
+		if !p.LastRun.IsZero() {
+			lastRun = p.LastRun.Format(time.RFC3339)
+		}
+
+		nextRun := ""
+		if schedule, err := parseCronSchedule(p.Schedule); err == nil {
+			if next, ok := schedule.Next(getSnapshotPolicyBaseline(p)); ok {
+				nextRun = next.Format(time.RFC3339)
+			}
+		}
+
+		data = append(data, []interface{}{
+			p.ID,
+			p.Label,
+			describeSnapshotPolicyTarget(p),
+			p.Schedule,
+			describeSnapshotPolicyRetention(p),
+			lastRun,
+			nextRun,
+			len(p.Snapshots),
+		})
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTable("Snapshot policies", "", getStringParam(c.Arguments["format"]))
+}
+
+func driveSnapshotPolicyDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	id, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	list, err := readSnapshotPolicies()
+	if err != nil {
+		return "", err
+	}
+
+	remaining := []snapshotPolicy{}
+	var policy *snapshotPolicy
+	for i, p := range list {
+		if p.ID == id {
+			policy = &list[i]
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+
+	if policy == nil {
+		return "", fmt.Errorf("snapshot policy #%d not found", id)
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting snapshot policy %s (%d) of %s. The %d snapshots created by it are kept. Are you sure? Type \"yes\" to continue:",
+			policy.Label, policy.ID,
+			describeSnapshotPolicyTarget(*policy),
+			len(policy.Snapshots))
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", writeSnapshotPolicies(remaining)
+}
+
+func driveSnapshotRunPoliciesCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	list, err := readSnapshotPolicies()
+	if err != nil {
+		return "", err
+	}
+
+	policyID, onlyOne := getIntParamOk(c.Arguments["policy_id"])
+	force := getBoolParam(c.Arguments["force"])
+	dryRun := getBoolParam(c.Arguments["dry_run"])
+
+	now := time.Now()
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "POLICY",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "DRIVE",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "ACTION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+	}
+
+	data := [][]interface{}{}
+	failed := 0
+	found := false
+
+	for i := range list {
+		if onlyOne && list[i].ID != policyID {
+			continue
+		}
+		found = true
+
+		rows, failures, err := runSnapshotPolicy(&list[i], now, force, dryRun, client)
+		if err != nil {
+			return "", err
+		}
+
+		data = append(data, rows...)
+		failed += failures
+	}
+
+	if onlyOne && !found {
+		return "", fmt.Errorf("snapshot policy #%d not found", policyID)
+	}
+
+	if !dryRun {
+		if err := writeSnapshotPolicies(list); err != nil {
+			return "", err
+		}
+	}
+
+	if len(data) == 0 {
+		return "", nil
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	title := "Snapshot policies"
+	if dryRun {
+		title = "Snapshot policies (dry run)"
+	}
+
+	ret, err := table.RenderTable(title, "", getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("%d snapshot policy operations failed", failed)
+	}
+
+	return ret, nil
+}
+
+// getSnapshotPolicyBaseline returns the time after which the next run of the policy is scheduled
+func getSnapshotPolicyBaseline(p snapshotPolicy) time.Time {
+	if p.LastRun.IsZero() {
+		return p.CreatedAt
+	}
+	return p.LastRun
+}
+
+// runSnapshotPolicy creates a snapshot of each of the policy's drives if the policy is due (or forced) and deletes
+// the snapshots of the policy that fall outside of its retention rules. Drives whose snapshot failed are retried on
+// the following runs, without snapshotting the other drives again. Failures are reported in the returned rows
+// and counted, the error is only returned if the policy itself is invalid.
+func runSnapshotPolicy(p *snapshotPolicy, now time.Time, force bool, dryRun bool, client metalcloud.MetalCloudClient) ([][]interface{}, int, error) {
+
+	schedule, err := parseCronSchedule(p.Schedule)
+	if err != nil {
+		return nil, 0, fmt.Errorf("policy %s (#%d): %v", p.Label, p.ID, err)
+	}
+
+	policyName := fmt.Sprintf("%s (#%d)", p.Label, p.ID)
+	rows := [][]interface{}{}
+	failed := 0
+
+	next, ok := schedule.Next(getSnapshotPolicyBaseline(*p))
+	due := force || (ok && !next.After(now))
+
+	records := p.Snapshots
+
+	var driveIDs []int
+
+	if due {
+		driveIDs, err = getSnapshotPolicyDrives(*p, client)
+		if err != nil {
+			//no snapshot was created so the whole run is retried
+			driveIDs = nil
+			failed++
+			rows = append(rows, []interface{}{policyName, 0, "create", red(err.Error())})
+		} else if !dryRun {
+			p.LastRun = now
+			p.PendingDriveIDs = nil
+		}
+	} else {
+		driveIDs = p.PendingDriveIDs
+		if !dryRun {
+			p.PendingDriveIDs = nil
+		}
+	}
+
+	for _, driveID := range driveIDs {
+
+		action := "create"
+		if !due {
+			action = "retry create"
+		}
+
+		if dryRun {
+			records = append(records, policySnapshot{DriveID: driveID, CreatedAt: now})
+			rows = append(rows, []interface{}{policyName, driveID, action, "would create"})
+			continue
+		}
+
+		snapshot, err := client.DriveSnapshotCreate(driveID)
+		if err != nil {
+			failed++
+			p.PendingDriveIDs = append(p.PendingDriveIDs, driveID)
+			rows = append(rows, []interface{}{policyName, driveID, action, red(err.Error())})
+			continue
+		}
+
+		records = append(records, policySnapshot{DriveID: driveID, SnapshotID: snapshot.DriveSnapshotID, CreatedAt: now})
+		rows = append(rows, []interface{}{policyName, driveID, action, green(fmt.Sprintf("created #%d", snapshot.DriveSnapshotID))})
+	}
+
+	records, err = reconcileSnapshotPolicyRecords(records, client)
+	if err != nil {
+		failed++
+		rows = append(rows, []interface{}{policyName, 0, "prune", red(err.Error())})
+		if !dryRun {
+			p.Snapshots = records
+		}
+		return rows, failed, nil
+	}
+
+	kept := []policySnapshot{}
+	for _, s := range records {
+		if !snapshotOutsideRetention(s, records, p.KeepLast, p.MaxAgeDays, now) {
+			kept = append(kept, s)
+			continue
+		}
+
+		action := fmt.Sprintf("delete #%d", s.SnapshotID)
+
+		if dryRun {
+			rows = append(rows, []interface{}{policyName, s.DriveID, action, "would delete"})
+			continue
+		}
+
+		if err := client.DriveSnapshotDelete(s.SnapshotID); err != nil {
+			failed++
+			kept = append(kept, s)
+			rows = append(rows, []interface{}{policyName, s.DriveID, action, red(err.Error())})
+			continue
+		}
+
+		rows = append(rows, []interface{}{policyName, s.DriveID, action, green("deleted")})
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].DriveID < kept[j].DriveID })
+
+	if !dryRun {
+		p.Snapshots = kept
+	}
+
+	return rows, failed, nil
+}
+
+// getSnapshotPolicyDrives returns the ids of the drives snapshotted by a policy
+func getSnapshotPolicyDrives(p snapshotPolicy, client metalcloud.MetalCloudClient) ([]int, error) {
+
+	if p.DriveArrayID == 0 {
+		return []int{p.DriveID}, nil
+	}
+
+	drives, err := client.DriveArrayDrives(p.DriveArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for _, d := range *drives {
+		if d.DriveServiceStatus == "deleted" {
+			continue
+		}
+		ids = append(ids, d.DriveID)
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// reconcileSnapshotPolicyRecords drops the records of snapshots that no longer exist, such as those deleted manually
+func reconcileSnapshotPolicyRecords(records []policySnapshot, client metalcloud.MetalCloudClient) ([]policySnapshot, error) {
+
+	existing := map[int]map[int]bool{}
+
+	ret := []policySnapshot{}
+	for _, s := range records {
+
+		//not created yet, in dry run mode
+		if s.SnapshotID == 0 {
+			ret = append(ret, s)
+			continue
+		}
+
+		if _, ok := existing[s.DriveID]; !ok {
+			snapshots, err := client.DriveSnapshots(s.DriveID)
+			if err != nil {
+				return records, err
+			}
+
+			existing[s.DriveID] = map[int]bool{}
+			for _, snapshot := range *snapshots {
+				existing[s.DriveID][snapshot.DriveSnapshotID] = true
+			}
+		}
+
+		if existing[s.DriveID][s.SnapshotID] {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret, nil
+}
+
+// snapshotOutsideRetention returns true if a snapshot is neither among the last keepLast snapshots of its drive
+// nor younger than maxAgeDays. A rule that is not set does not keep any snapshot. The most recent snapshot of a
+// drive is always kept.
+func snapshotOutsideRetention(s policySnapshot, records []policySnapshot, keepLast int, maxAgeDays int, now time.Time) bool {
+
+	if keepLast <= 0 && maxAgeDays <= 0 {
+		return false
+	}
+
+	//the number of more recent snapshots of the same drive
+	newer := 0
+	for _, r := range records {
+		if r.DriveID != s.DriveID || r == s {
+			continue
+		}
+		if r.CreatedAt.After(s.CreatedAt) || (r.CreatedAt.Equal(s.CreatedAt) && (r.SnapshotID == 0 || r.SnapshotID > s.SnapshotID)) {
+			newer++
+		}
+	}
+
+	if newer == 0 {
+		return false
+	}
+
+	keptByCount := keepLast > 0 && newer < keepLast
+	keptByAge := maxAgeDays > 0 && now.Sub(s.CreatedAt) <= time.Duration(maxAgeDays)*24*time.Hour
+
+	return !keptByCount && !keptByAge
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"testing"
+	"time"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
@@ -119,3 +122,270 @@ func TestDriveSnapshotRollbackCmd(t *testing.T) {
 	cmd := MakeCommand(map[string]interface{}{"drive_snapshot_id": s.DriveSnapshotID})
 	testCommandWithConfirmation(driveSnapshotRollbackCmd, cmd, client, t)
 }
+
+func TestDriveSnapshotPolicyCreateListDeleteCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	os.Setenv("METALCLOUD_STATE_DIR", dir)
+	defer os.Unsetenv("METALCLOUD_STATE_DIR")
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		DriveArrayGet(100).
+		Return(&metalcloud.DriveArray{DriveArrayID: 100}, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "drive array",
+			cmd: MakeCommand(map[string]interface{}{
+				"policy_label":            "nightly",
+				"drive_array_id_or_label": "100",
+				"schedule":                "0 2 * * *",
+				"keep_last":               7,
+			}),
+			good: true,
+			id:   1,
+		},
+		{
+			name: "invalid schedule",
+			cmd: MakeCommand(map[string]interface{}{
+				"policy_label":            "nightly",
+				"drive_array_id_or_label": "100",
+				"schedule":                "0 25 * * *",
+				"keep_last":               7,
+			}),
+			good: false,
+		},
+		{
+			name: "no retention",
+			cmd: MakeCommand(map[string]interface{}{
+				"policy_label":            "nightly",
+				"drive_array_id_or_label": "100",
+				"schedule":                "0 2 * * *",
+			}),
+			good: false,
+		},
+		{
+			name: "no target",
+			cmd: MakeCommand(map[string]interface{}{
+				"policy_label": "nightly",
+				"schedule":     "0 2 * * *",
+				"keep_last":    7,
+			}),
+			good: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ret, err := driveSnapshotPolicyCreateCmd(&c.cmd, client)
+			if c.good {
+				Expect(err).To(BeNil())
+			} else {
+				Expect(err).NotTo(BeNil())
+				Expect(ret).To(BeEmpty())
+			}
+		})
+	}
+
+	list, err := readSnapshotPolicies()
+	Expect(err).To(BeNil())
+	Expect(list).To(HaveLen(1))
+	Expect(list[0].ID).To(Equal(1))
+	Expect(list[0].DriveArrayID).To(Equal(100))
+
+	cmd := MakeCommand(map[string]interface{}{"format": "csv"})
+	ret, err := driveSnapshotPolicyListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("nightly,drive array #100,0 2 * * *,keep last 7"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":   2,
+		"autoconfirm": true,
+	})
+	_, err = driveSnapshotPolicyDeleteCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":   1,
+		"autoconfirm": true,
+	})
+	_, err = driveSnapshotPolicyDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	list, err = readSnapshotPolicies()
+	Expect(err).To(BeNil())
+	Expect(list).To(BeEmpty())
+}
+
+func TestRunSnapshotPolicy(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	now := time.Date(2022, 3, 2, 2, 0, 30, 0, time.UTC)
+	day := 24 * time.Hour
+
+	newPolicy := func() snapshotPolicy {
+		return snapshotPolicy{
+			ID:           1,
+			Label:        "nightly",
+			DriveArrayID: 100,
+			Schedule:     "0 2 * * *",
+			KeepLast:     2,
+			CreatedAt:    now.Add(-10 * day),
+			LastRun:      now.Add(-day),
+			Snapshots: []policySnapshot{
+				{DriveID: 1, SnapshotID: 11, CreatedAt: now.Add(-2 * day)},
+				{DriveID: 1, SnapshotID: 12, CreatedAt: now.Add(-day)},
+				{DriveID: 2, SnapshotID: 21, CreatedAt: now.Add(-2 * day)},
+				//deleted manually
+				{DriveID: 2, SnapshotID: 29, CreatedAt: now.Add(-day)},
+			},
+		}
+	}
+
+	client.EXPECT().
+		DriveArrayDrives(100).
+		Return(&map[string]metalcloud.Drive{
+			"d1": {DriveID: 1, DriveServiceStatus: "active"},
+			"d2": {DriveID: 2, DriveServiceStatus: "active"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveSnapshots(1).
+		Return(&map[string]metalcloud.Snapshot{
+			"s11": {DriveSnapshotID: 11},
+			"s12": {DriveSnapshotID: 12},
+			"s13": {DriveSnapshotID: 13},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveSnapshots(2).
+		Return(&map[string]metalcloud.Snapshot{
+			"s21": {DriveSnapshotID: 21},
+			"s23": {DriveSnapshotID: 23},
+		}, nil).
+		AnyTimes()
+
+	//dry run changes nothing
+	p := newPolicy()
+	rows, failed, err := runSnapshotPolicy(&p, now, false, true, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(0))
+	Expect(fmt.Sprintf("%v", rows)).To(ContainSubstring("would create"))
+	Expect(fmt.Sprintf("%v", rows)).To(ContainSubstring("delete #11 would delete"))
+	Expect(p).To(Equal(newPolicy()))
+
+	client.EXPECT().
+		DriveSnapshotCreate(1).
+		Return(&metalcloud.Snapshot{DriveSnapshotID: 13, DriveID: 1}, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveSnapshotCreate(2).
+		Return(&metalcloud.Snapshot{DriveSnapshotID: 23, DriveID: 2}, nil).
+		Times(1)
+
+	client.EXPECT().
+		DriveSnapshotDelete(11).
+		Return(nil).
+		Times(1)
+
+	rows, failed, err = runSnapshotPolicy(&p, now, false, false, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(0))
+	Expect(rows).To(HaveLen(3))
+	Expect(p.LastRun).To(Equal(now))
+
+	ids := []int{}
+	for _, s := range p.Snapshots {
+		ids = append(ids, s.SnapshotID)
+	}
+	Expect(ids).To(Equal([]int{12, 13, 21, 23}))
+
+	//not due again until tomorrow
+	rows, failed, err = runSnapshotPolicy(&p, now.Add(time.Hour), false, false, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(0))
+	Expect(rows).To(BeEmpty())
+
+	//failures are reported and only the failed drives are retried on the next run
+	client.EXPECT().
+		DriveSnapshotCreate(1).
+		Return(nil, fmt.Errorf("storage unavailable")).
+		Times(1)
+
+	client.EXPECT().
+		DriveSnapshotCreate(2).
+		Return(&metalcloud.Snapshot{DriveSnapshotID: 24, DriveID: 2}, nil).
+		Times(1)
+
+	rows, failed, err = runSnapshotPolicy(&p, now.Add(time.Hour), true, false, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(1))
+	Expect(rows).To(HaveLen(2))
+	Expect(p.LastRun).To(Equal(now.Add(time.Hour)))
+	Expect(p.PendingDriveIDs).To(Equal([]int{1}))
+
+	client.EXPECT().
+		DriveSnapshotCreate(1).
+		Return(nil, fmt.Errorf("storage unavailable")).
+		Times(1)
+
+	rows, failed, err = runSnapshotPolicy(&p, now.Add(2*time.Hour), false, false, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(1))
+	Expect(fmt.Sprintf("%v", rows)).To(ContainSubstring("retry create"))
+	Expect(p.PendingDriveIDs).To(Equal([]int{1}))
+
+	client.EXPECT().
+		DriveSnapshotCreate(1).
+		Return(&metalcloud.Snapshot{DriveSnapshotID: 14, DriveID: 1}, nil).
+		Times(1)
+
+	rows, failed, err = runSnapshotPolicy(&p, now.Add(3*time.Hour), false, false, client)
+	Expect(err).To(BeNil())
+	Expect(failed).To(Equal(0))
+	Expect(rows).To(HaveLen(1))
+	Expect(p.PendingDriveIDs).To(BeEmpty())
+	Expect(p.LastRun).To(Equal(now.Add(time.Hour)))
+}
+
+func TestSnapshotOutsideRetention(t *testing.T) {
+	RegisterTestingT(t)
+
+	now := time.Date(2022, 3, 2, 2, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	records := []policySnapshot{
+		{DriveID: 1, SnapshotID: 1, CreatedAt: now.Add(-10 * day)},
+		{DriveID: 1, SnapshotID: 2, CreatedAt: now.Add(-5 * day)},
+		{DriveID: 1, SnapshotID: 3, CreatedAt: now.Add(-day)},
+		{DriveID: 2, SnapshotID: 4, CreatedAt: now.Add(-10 * day)},
+	}
+
+	//max age only
+	Expect(snapshotOutsideRetention(records[0], records, 0, 7, now)).To(BeTrue())
+	Expect(snapshotOutsideRetention(records[1], records, 0, 7, now)).To(BeFalse())
+	//the most recent snapshot of a drive is always kept
+	Expect(snapshotOutsideRetention(records[3], records, 0, 7, now)).To(BeFalse())
+
+	//keep last only
+	Expect(snapshotOutsideRetention(records[0], records, 2, 0, now)).To(BeTrue())
+	Expect(snapshotOutsideRetention(records[1], records, 2, 0, now)).To(BeFalse())
+	Expect(snapshotOutsideRetention(records[1], records, 1, 0, now)).To(BeTrue())
+
+	//both rules: a snapshot is kept if either rule keeps it
+	Expect(snapshotOutsideRetention(records[0], records, 2, 7, now)).To(BeTrue())
+	Expect(snapshotOutsideRetention(records[1], records, 1, 7, now)).To(BeFalse())
+	Expect(snapshotOutsideRetention(records[0], records, 3, 7, now)).To(BeFalse())
+	Expect(snapshotOutsideRetention(records[0], records, 1, 3, now)).To(BeTrue())
+}
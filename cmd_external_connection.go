@@ -0,0 +1,337 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+)
+
+var externalConnectionCmds = []Command{
+	{
+		Description:  "Lists all external connections of a datacenter.",
+		Subject:      "external-connection",
+		AltSubject:   "ec",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list external connections", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter": c.FlagSet.String("datacenter", _nilDefaultStr, red("(Required)")+" Label of the datacenter."),
+				"format":     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: externalConnectionListCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Get external connection details.",
+		Subject:      "external-connection",
+		AltSubject:   "ec",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get external connection", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"external_connection_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" External connection's id or label."),
+				"format":                          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"raw":                             c.FlagSet.Bool("raw", false, green("(Flag)")+" If set returns the raw object serialized using specified format"),
+			}
+		},
+		ExecuteFunc: externalConnectionGetCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Create external connection.",
+		Subject:      "external-connection",
+		AltSubject:   "ec",
+		Predicate:    "create",
+		AltPredicate: "new",
+		FlagSet:      flag.NewFlagSet("create external connection", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter":            c.FlagSet.String("datacenter", _nilDefaultStr, "Label of the datacenter. Overrides the datacenter label in the supplied configuration."),
+				"format":                c.FlagSet.String("format", "json", "The input format. Supported values are 'json','yaml'. The default format is json."),
+				"read_config_from_file": c.FlagSet.String("raw-config", _nilDefaultStr, red("(Required)")+" Read  configuration from file in the format specified with --format."),
+				"read_config_from_pipe": c.FlagSet.Bool("pipe", false, green("(Flag)")+" If set, read  configuration from pipe instead of from a file. Either this flag or the --raw-config option must be used."),
+				"return_id":             c.FlagSet.Bool("return-id", false, "Will print the ID of the created object. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: externalConnectionCreateCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+#create file external-connection.yaml:
+label: internet-uplink01
+dc: us02-chi-qts01-dc
+description: Uplink towards the ISP
+hidden: false
+
+#create the external connection from the file:
+metalcloud-cli external-connection create -format yaml -raw-config ./external-connection.yaml
+`,
+	},
+	{
+		Description:  "Edit external connection.",
+		Subject:      "external-connection",
+		AltSubject:   "ec",
+		Predicate:    "edit",
+		AltPredicate: "update",
+		FlagSet:      flag.NewFlagSet("edit external connection", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"external_connection_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" External connection's id or label."),
+				"format":                          c.FlagSet.String("format", "json", "The input format. Supported values are 'json','yaml'. The default format is json."),
+				"read_config_from_file":           c.FlagSet.String("raw-config", _nilDefaultStr, red("(Required)")+" Read  configuration from file in the format specified with --format."),
+				"read_config_from_pipe":           c.FlagSet.Bool("pipe", false, green("(Flag)")+" If set, read  configuration from pipe instead of from a file. Either this flag or the --raw-config option must be used."),
+			}
+		},
+		ExecuteFunc: externalConnectionEditCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Delete external connection.",
+		Subject:      "external-connection",
+		AltSubject:   "ec",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete external connection", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"external_connection_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" External connection's id or label."),
+				"autoconfirm":                     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: externalConnectionDeleteCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+}
+
+func externalConnectionListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	datacenter, ok := getStringParamOk(c.Arguments["datacenter"])
+	if !ok {
+		return "", fmt.Errorf("-datacenter is required")
+	}
+
+	ecList, err := client.ExternalConnections(datacenter)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "DATACENTER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "DESCRIPTION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 40,
+		},
+		{
+			FieldName: "HIDDEN",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, ec := range *ecList {
+		data = append(data, []interface{}{
+			ec.ExternalConnectionID,
+			blue(ec.ExternalConnectionLabel),
+			ec.DatacenterName,
+			ec.ExternalConnectionDescription,
+			ec.ExternalConnectionHidden,
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTable("External connections", "", getStringParam(c.Arguments["format"]))
+}
+
+func externalConnectionGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retEC, err := getExternalConnectionFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	format := getStringParam(c.Arguments["format"])
+
+	if getBoolParam(c.Arguments["raw"]) {
+		return tableformatter.RenderRawObject(*retEC, format, "External connection")
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "DATACENTER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "DESCRIPTION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 40,
+		},
+		{
+			FieldName: "HIDDEN",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{
+		{
+			retEC.ExternalConnectionID,
+			retEC.ExternalConnectionLabel,
+			retEC.DatacenterName,
+			retEC.ExternalConnectionDescription,
+			retEC.ExternalConnectionHidden,
+		},
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTransposedTable("External connection", "", format)
+}
+
+func externalConnectionCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	var obj metalcloud.ExternalConnection
+
+	err := getRawObjectFromCommand(c, &obj)
+	if err != nil {
+		return "", err
+	}
+
+	if datacenter, ok := getStringParamOk(c.Arguments["datacenter"]); ok {
+		obj.DatacenterName = datacenter
+	}
+
+	if obj.DatacenterName == "" {
+		return "", fmt.Errorf("Datacenter name is required.")
+	}
+
+	if obj.ExternalConnectionLabel == "" {
+		return "", fmt.Errorf("External connection label is required.")
+	}
+
+	ret, err := client.ExternalConnectionCreate(obj)
+	if err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", ret.ExternalConnectionID), nil
+	}
+
+	return "", err
+}
+
+func externalConnectionEditCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retEC, err := getExternalConnectionFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	obj := *retEC
+
+	err = getRawObjectFromCommand(c, &obj)
+	if err != nil {
+		return "", err
+	}
+
+	if obj.DatacenterName != retEC.DatacenterName {
+		return "", fmt.Errorf("External connection %d belongs to datacenter %s and cannot be moved to datacenter %s.", retEC.ExternalConnectionID, retEC.DatacenterName, obj.DatacenterName)
+	}
+
+	obj.ExternalConnectionID = retEC.ExternalConnectionID
+
+	_, err = client.ExternalConnectionEdit(retEC.ExternalConnectionID, obj)
+
+	return "", err
+}
+
+func externalConnectionDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retEC, err := getExternalConnectionFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting external connection %s (%d) from datacenter %s.  Are you sure? Type \"yes\" to continue:",
+			retEC.ExternalConnectionLabel, retEC.ExternalConnectionID,
+			retEC.DatacenterName)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	err = client.ExternalConnectionDelete(retEC.ExternalConnectionID)
+
+	return "", err
+}
+
+func getExternalConnectionFromCommand(paramName string, c *Command, client metalcloud.MetalCloudClient) (*metalcloud.ExternalConnection, error) {
+
+	m, err := getParam(c, "external_connection_id_or_label", paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+
+	if isID {
+		return client.ExternalConnectionGet(id)
+	}
+
+	return client.ExternalConnectionGetByLabel(label)
+}
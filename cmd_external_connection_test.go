@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+const _externalConnectionFixtureYaml = `
+label: uplink01
+dc: dc-test
+description: Uplink towards the ISP
+hidden: true
+`
+
+func TestExternalConnectionListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	list := map[int]metalcloud.ExternalConnection{
+		10: {
+			ExternalConnectionID:    10,
+			ExternalConnectionLabel: "uplink01",
+			DatacenterName:          "dc-test",
+		},
+		11: {
+			ExternalConnectionID:    11,
+			ExternalConnectionLabel: "uplink02",
+			DatacenterName:          "dc-test",
+		},
+	}
+
+	client.EXPECT().
+		ExternalConnections("dc-test").
+		Return(&list, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter": "dc-test",
+		"format":     "json",
+	})
+
+	ret, err := externalConnectionListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m).To(HaveLen(2))
+	Expect(int(m[0].(map[string]interface{})["ID"].(float64))).To(Equal(10))
+
+	cmd = MakeCommand(map[string]interface{}{})
+
+	_, err = externalConnectionListCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestExternalConnectionCreateCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	configFile := filepath.Join(t.TempDir(), "ec.yaml")
+	Expect(os.WriteFile(configFile, []byte(_externalConnectionFixtureYaml), 0600)).To(BeNil())
+
+	ec := metalcloud.ExternalConnection{
+		ExternalConnectionLabel:       "uplink01",
+		DatacenterName:                "dc-other",
+		ExternalConnectionDescription: "Uplink towards the ISP",
+		ExternalConnectionHidden:      true,
+	}
+
+	retEC := ec
+	retEC.ExternalConnectionID = 10
+
+	client.EXPECT().
+		ExternalConnectionCreate(ec).
+		Return(&retEC, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter":            "dc-other",
+		"format":                "yaml",
+		"read_config_from_file": configFile,
+		"return_id":             true,
+	})
+
+	ret, err := externalConnectionCreateCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("10"))
+}
+
+func TestExternalConnectionGetEditDeleteCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ec := metalcloud.ExternalConnection{
+		ExternalConnectionID:    10,
+		ExternalConnectionLabel: "uplink01",
+		DatacenterName:          "dc-test",
+	}
+
+	client.EXPECT().
+		ExternalConnectionGet(10).
+		Return(&ec, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ExternalConnectionGetByLabel("uplink01").
+		Return(&ec, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"external_connection_id_or_label": "uplink01",
+		"format":                          "yaml",
+		"raw":                             true,
+	})
+
+	ret, err := externalConnectionGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("label: uplink01"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"external_connection_id_or_label": "10",
+	})
+
+	ret, err = externalConnectionGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("uplink01"))
+
+	configFile := filepath.Join(t.TempDir(), "ec.yaml")
+	Expect(os.WriteFile(configFile, []byte("description: new description\n"), 0600)).To(BeNil())
+
+	edited := ec
+	edited.ExternalConnectionDescription = "new description"
+
+	client.EXPECT().
+		ExternalConnectionEdit(10, edited).
+		Return(&edited, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"external_connection_id_or_label": "10",
+		"format":                          "yaml",
+		"read_config_from_file":           configFile,
+	})
+
+	_, err = externalConnectionEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//moving to another datacenter is not allowed
+	Expect(os.WriteFile(configFile, []byte("dc: dc-other\n"), 0600)).To(BeNil())
+
+	_, err = externalConnectionEditCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	client.EXPECT().
+		ExternalConnectionDelete(10).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"external_connection_id_or_label": "10",
+		"autoconfirm":                     true,
+	})
+
+	_, err = externalConnectionDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
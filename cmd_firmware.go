@@ -0,0 +1,797 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+)
+
+var firmwareCmds = []Command{
+
+	{
+		Description:  "Lists the firmware of the components of a server.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list firmware", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid": c.FlagSet.String("server", _nilDefaultStr, red("(Required)")+" Server's ID or UUID"),
+				"filter":            c.FlagSet.String("filter", "", "Filter to use when searching for components. Check the documentation for examples."),
+				"updateable_only":   c.FlagSet.Bool("updateable-only", false, green("(Flag)")+" If set only the components with an updateable firmware are returned"),
+				"format":            c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: firmwareListCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Sets the firmware version a component is upgraded to at the next upgrade session.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "target-set",
+		AltPredicate: "target-version-set",
+		FlagSet:      flag.NewFlagSet("set firmware target version", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid":   c.FlagSet.String("server", _nilDefaultStr, red("(Required)")+" Server's ID or UUID"),
+				"server_component_id": c.FlagSet.Int("component", _nilDefaultInt, red("(Required)")+" The id of the server component"),
+				"firmware_version":    c.FlagSet.String("version", _nilDefaultStr, red("(Required)")+" The target firmware version"),
+				"firmware_binary_url": c.FlagSet.String("url", _nilDefaultStr, "The url of the firmware binary. If set the version is added to the available versions of the component, replacing any previous url."),
+			}
+		},
+		ExecuteFunc: firmwareTargetSetCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli firmware target-set --server 100 --component 2010 --version 2.12.2
+metalcloud-cli firmware target-set --server 100 --component 2010 --version 2.13.0 --url https://repo.local/bios-2.13.0.bin
+`,
+	},
+	{
+		Description:  "Refreshes the available firmware versions of a component from the vendor catalog.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "versions-update",
+		AltPredicate: "versions-refresh",
+		FlagSet:      flag.NewFlagSet("update firmware available versions", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid":   c.FlagSet.String("server", _nilDefaultStr, red("(Required)")+" Server's ID or UUID"),
+				"server_component_id": c.FlagSet.Int("component", _nilDefaultInt, red("(Required)")+" The id of the server component"),
+			}
+		},
+		ExecuteFunc: firmwareVersionsUpdateCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Upgrades the firmware of a server or of one of its components.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "upgrade",
+		AltPredicate: "update",
+		FlagSet:      flag.NewFlagSet("upgrade firmware", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid":   c.FlagSet.String("server", _nilDefaultStr, red("(Required)")+" Server's ID or UUID"),
+				"server_component_id": c.FlagSet.Int("component", _nilDefaultInt, "The id of the component to upgrade. If not set all the updateable components that have a target version set are upgraded."),
+				"firmware_version":    c.FlagSet.String("version", _nilDefaultStr, "The firmware version to upgrade the component to. Defaults to the component's target version."),
+				"firmware_binary_url": c.FlagSet.String("url", _nilDefaultStr, "The url of the firmware binary. Defaults to the url registered for the version."),
+				"autoconfirm":         c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: firmwareUpgradeCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli firmware upgrade --server 100 # upgrades all components that have a target version set
+metalcloud-cli firmware upgrade --server 100 --component 2010 --version 2.12.2
+`,
+	},
+	{
+		Description:  "Get firmware upgrade policy details.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "policy-get",
+		AltPredicate: "policy-show",
+		FlagSet:      flag.NewFlagSet("get firmware policy", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id": c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy"),
+				"format":    c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: firmwarePolicyGetCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Creates a firmware upgrade policy.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "policy-create",
+		AltPredicate: "policy-new",
+		FlagSet:      flag.NewFlagSet("create firmware policy", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_label":               c.FlagSet.String("label", _nilDefaultStr, red("(Required)")+" The label of the policy. Overrides the label from the config file."),
+				"policy_action":              c.FlagSet.String("action", _nilDefaultStr, "The upgrade action of the policy. Overrides the action from the config file."),
+				"instance_array_id_or_label": c.FlagSet.String("instance-arrays", _nilDefaultStr, "Comma separated list of instance array ids or labels the policy applies to."),
+				"read_config_from_file":      c.FlagSet.String("raw-config", _nilDefaultStr, "Read the policy from a file in the format specified with --format. The file can hold the label, action and rules (property, operation, value) of the policy."),
+				"read_config_from_pipe":      c.FlagSet.Bool("pipe", false, green("(Flag)")+" If set, read the policy from pipe instead of from a file."),
+				"format":                     c.FlagSet.String("format", "json", "The input format. Supported values are 'json','yaml'. The default format is json."),
+				"return_id":                  c.FlagSet.Bool("return-id", false, "(Optional) Will print the ID of the created policy. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: firmwarePolicyCreateCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli firmware policy-create --label bios-policy --raw-config policy.yaml --format yaml --instance-arrays 100,101
+`,
+	},
+	{
+		Description:  "Edits a firmware upgrade policy.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "policy-edit",
+		AltPredicate: "policy-update",
+		FlagSet:      flag.NewFlagSet("edit firmware policy", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id":                  c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy"),
+				"policy_label":               c.FlagSet.String("label", _nilDefaultStr, "The new label of the policy."),
+				"policy_action":              c.FlagSet.String("action", _nilDefaultStr, "The new upgrade action of the policy."),
+				"instance_array_id_or_label": c.FlagSet.String("instance-arrays", _nilDefaultStr, "Comma separated list of instance array ids or labels the policy applies to. Replaces the current list."),
+			}
+		},
+		ExecuteFunc: firmwarePolicyEditCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Deletes a firmware upgrade policy.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "policy-delete",
+		AltPredicate: "policy-rm",
+		FlagSet:      flag.NewFlagSet("delete firmware policy", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id":   c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy"),
+				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: firmwarePolicyDeleteCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Adds a rule to a firmware upgrade policy.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "rule-add",
+		AltPredicate: "rule-new",
+		FlagSet:      flag.NewFlagSet("add firmware policy rule", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id":      c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy"),
+				"rule_property":  c.FlagSet.String("property", _nilDefaultStr, red("(Required)")+" The property the rule is matching"),
+				"rule_operation": c.FlagSet.String("operation", _nilDefaultStr, red("(Required)")+" The operation used to match the property"),
+				"rule_value":     c.FlagSet.String("value", _nilDefaultStr, red("(Required)")+" The value the property is matched against"),
+			}
+		},
+		ExecuteFunc: firmwarePolicyRuleAddCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Deletes a rule from a firmware upgrade policy.",
+		Subject:      "firmware",
+		AltSubject:   "fw",
+		Predicate:    "rule-delete",
+		AltPredicate: "rule-rm",
+		FlagSet:      flag.NewFlagSet("delete firmware policy rule", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"policy_id":      c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" The id of the policy"),
+				"rule_property":  c.FlagSet.String("property", _nilDefaultStr, red("(Required)")+" The property of the rule"),
+				"rule_operation": c.FlagSet.String("operation", _nilDefaultStr, red("(Required)")+" The operation of the rule"),
+				"rule_value":     c.FlagSet.String("value", _nilDefaultStr, red("(Required)")+" The value of the rule"),
+				"autoconfirm":    c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: firmwarePolicyRuleDeleteCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+}
+
+func firmwareListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	server, err := getServerFromCommand("server", c, client, false)
+	if err != nil {
+		return "", err
+	}
+
+	components, err := client.ServerComponents(server.ServerID, getStringParam(c.Arguments["filter"]))
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "NAME",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "VERSION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "TARGET",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "AVAILABLE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "UPDATEABLE",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "UPDATED",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	updateableOnly := getBoolParam(c.Arguments["updateable_only"])
+
+	data := [][]interface{}{}
+	for _, sc := range *components {
+
+		if updateableOnly && !sc.ServerComponentFirmwareUpdateable {
+			continue
+		}
+
+		data = append(data, []interface{}{
+			sc.ServerComponentID,
+			sc.ServerComponentName,
+			sc.ServerComponentType,
+			sc.ServerComponentFirmwareVersion,
+			sc.ServerComponentFirmwareTargetVersion,
+			strings.Join(sc.ServerComponentFirmwareUpdateAvailableVersions, " "),
+			sc.ServerComponentFirmwareUpdateable,
+			sc.ServerComponentFirmwareStatus,
+			sc.ServerComponentFirmwareUpdateTimestamp,
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := fmt.Sprintf("Components of server #%d (%s)", server.ServerID, server.ServerSerialNumber)
+
+	return table.RenderTable("Firmware", subtitle, getStringParam(c.Arguments["format"]))
+}
+
+func firmwareTargetSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	server, component, err := getServerComponentFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	version, ok := getStringParamOk(c.Arguments["firmware_version"])
+	if !ok {
+		return "", fmt.Errorf("-version is required")
+	}
+
+	if !component.ServerComponentFirmwareUpdateable {
+		return "", fmt.Errorf("the firmware of component %s (#%d) of server #%d is not updateable", component.ServerComponentName, component.ServerComponentID, server.ServerID)
+	}
+
+	if url, ok := getStringParamOk(c.Arguments["firmware_binary_url"]); ok {
+		err = client.ServerFirmwareComponentTargetVersionAdd(component.ServerComponentID, version, url)
+		if err != nil {
+			return "", err
+		}
+	} else if !firmwareVersionAvailable(*component, version) {
+		return "", fmt.Errorf("version %s is not available for component %s (#%d). Available versions: %s. Use --url to add it",
+			version,
+			component.ServerComponentName,
+			component.ServerComponentID,
+			strings.Join(component.ServerComponentFirmwareUpdateAvailableVersions, ", "))
+	}
+
+	return "", client.ServerFirmwareComponentTargetVersionSet(component.ServerComponentID, version)
+}
+
+func firmwareVersionsUpdateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	_, component, err := getServerComponentFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	return "", client.ServerFirmwareComponentTargetVersionUpdate(component.ServerComponentID)
+}
+
+func firmwareUpgradeCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	if _, ok := getIntParamOk(c.Arguments["server_component_id"]); ok {
+		return firmwareComponentUpgradeCmd(c, client)
+	}
+
+	server, err := getServerFromCommand("server", c, client, false)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Upgrading the firmware of all the updateable components of server #%d (%s) that have a target version set. The server will be rebooted. Are you sure? Type \"yes\" to continue:",
+			server.ServerID,
+			server.ServerSerialNumber,
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.ServerFirmwareUpgrade(server.ServerID)
+}
+
+func firmwareComponentUpgradeCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	server, component, err := getServerComponentFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if !component.ServerComponentFirmwareUpdateable {
+		return "", fmt.Errorf("the firmware of component %s (#%d) of server #%d is not updateable", component.ServerComponentName, component.ServerComponentID, server.ServerID)
+	}
+
+	version := getStringParam(c.Arguments["firmware_version"])
+	url := getStringParam(c.Arguments["firmware_binary_url"])
+
+	targetVersion := version
+	if targetVersion == "" {
+		targetVersion = component.ServerComponentFirmwareTargetVersion
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Upgrading the firmware of component %s (#%d) of server #%d from %s to %s. The server will be rebooted. Are you sure? Type \"yes\" to continue:",
+			component.ServerComponentName,
+			component.ServerComponentID,
+			server.ServerID,
+			component.ServerComponentFirmwareVersion,
+			targetVersion,
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.ServerFirmwareComponentUpgrade(server.ServerID, component.ServerComponentID, version, url)
+}
+
+func firmwarePolicyGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policyID, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	policy, err := client.ServerFirmwarePolicyGet(policyID)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "ACTION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "INSTANCE_ARRAYS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RULES",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 40,
+		},
+	}
+
+	instanceArrays := []string{}
+	for _, id := range policy.InstanceArrayIDList {
+		instanceArrays = append(instanceArrays, fmt.Sprintf("#%d", id))
+	}
+
+	rules := []string{}
+	for _, r := range policy.ServerFirmwareUpgradePolicyRules {
+		rules = append(rules, firmwarePolicyRuleToString(r))
+	}
+
+	data := [][]interface{}{
+		{
+			policy.ServerFirmwareUpgradePolicyID,
+			policy.ServerFirmwareUpgradePolicyLabel,
+			policy.ServerFirmwareUpgradePolicyAction,
+			strings.Join(instanceArrays, " "),
+			strings.Join(rules, "\n"),
+		},
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTransposedTable("Firmware policy", "", getStringParam(c.Arguments["format"]))
+}
+
+func firmwarePolicyCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policy := metalcloud.ServerFirmwareUpgradePolicy{}
+
+	_, fileOk := getStringParamOk(c.Arguments["read_config_from_file"])
+	if fileOk || getBoolParam(c.Arguments["read_config_from_pipe"]) {
+		err := getRawObjectFromCommand(c, &policy)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["policy_label"]); ok {
+		policy.ServerFirmwareUpgradePolicyLabel = v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["policy_action"]); ok {
+		policy.ServerFirmwareUpgradePolicyAction = v
+	}
+
+	if policy.ServerFirmwareUpgradePolicyLabel == "" {
+		return "", fmt.Errorf("-label is required")
+	}
+
+	instanceArrays, err := getInstanceArrayIDsFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := client.ServerFirmwareUpgradePolicyCreate(&policy)
+	if err != nil {
+		return "", err
+	}
+
+	if instanceArrays == nil {
+		instanceArrays = policy.InstanceArrayIDList
+	}
+
+	if len(instanceArrays) > 0 {
+		err = client.ServerFirmwareUgradePolicyInstanceArraySet(ret.ServerFirmwareUpgradePolicyID, instanceArrays)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", ret.ServerFirmwareUpgradePolicyID), nil
+	}
+
+	return "", nil
+}
+
+func firmwarePolicyEditCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policyID, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	policy, err := client.ServerFirmwarePolicyGet(policyID)
+	if err != nil {
+		return "", err
+	}
+
+	label, labelOk := getStringParamOk(c.Arguments["policy_label"])
+	action, actionOk := getStringParamOk(c.Arguments["policy_action"])
+
+	instanceArrays, err := getInstanceArrayIDsFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if !labelOk && !actionOk && instanceArrays == nil {
+		return "", fmt.Errorf("at least one of -label, -action or -instance-arrays is required")
+	}
+
+	if labelOk {
+		err = client.ServerFirmwareUpgradePolicyLabelSet(policy.ServerFirmwareUpgradePolicyID, label)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if actionOk {
+		err = client.ServerFirmwareUpgradePolicyActionSet(policy.ServerFirmwareUpgradePolicyID, action)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if instanceArrays != nil {
+		err = client.ServerFirmwareUgradePolicyInstanceArraySet(policy.ServerFirmwareUpgradePolicyID, instanceArrays)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+func firmwarePolicyDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policyID, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	policy, err := client.ServerFirmwarePolicyGet(policyID)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting firmware policy %s (%d).  Are you sure? Type \"yes\" to continue:",
+			policy.ServerFirmwareUpgradePolicyLabel,
+			policy.ServerFirmwareUpgradePolicyID,
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.ServerFirmwareUpgradePolicyDelete(policy.ServerFirmwareUpgradePolicyID)
+}
+
+func firmwarePolicyRuleAddCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policyID, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	rule, err := getFirmwarePolicyRuleFromCommand(c)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = client.ServerFirmwarePolicyAddRule(policyID, rule)
+
+	return "", err
+}
+
+func firmwarePolicyRuleDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	policyID, ok := getIntParamOk(c.Arguments["policy_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	rule, err := getFirmwarePolicyRuleFromCommand(c)
+	if err != nil {
+		return "", err
+	}
+
+	policy, err := client.ServerFirmwarePolicyGet(policyID)
+	if err != nil {
+		return "", err
+	}
+
+	found := false
+	for _, r := range policy.ServerFirmwareUpgradePolicyRules {
+		if r == *rule {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("policy %s (#%d) has no rule '%s'", policy.ServerFirmwareUpgradePolicyLabel, policyID, firmwarePolicyRuleToString(*rule))
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting rule '%s' from firmware policy %s (%d).  Are you sure? Type \"yes\" to continue:",
+			firmwarePolicyRuleToString(*rule),
+			policy.ServerFirmwareUpgradePolicyLabel,
+			policy.ServerFirmwareUpgradePolicyID,
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", client.ServerFirmwarePolicyDeleteRule(policyID, rule)
+}
+
+// getServerComponentFromCommand returns the server and the component selected with the server and component arguments.
+// The components are searched on the server because components cannot be retrieved individually.
+func getServerComponentFromCommand(c *Command, client metalcloud.MetalCloudClient) (*metalcloud.Server, *metalcloud.ServerComponent, error) {
+
+	componentID, ok := getIntParamOk(c.Arguments["server_component_id"])
+	if !ok {
+		return nil, nil, fmt.Errorf("-component is required")
+	}
+
+	server, err := getServerFromCommand("server", c, client, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	components, err := client.ServerComponents(server.ServerID, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, sc := range *components {
+		if sc.ServerComponentID == componentID {
+			return server, &sc, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("component #%d not found on server #%d", componentID, server.ServerID)
+}
+
+// getInstanceArrayIDsFromCommand resolves the comma separated instance_array_id_or_label argument.
+// Returns nil if the argument is not set.
+func getInstanceArrayIDsFromCommand(c *Command, client metalcloud.MetalCloudClient) ([]int, error) {
+
+	v, ok := getStringParamOk(c.Arguments["instance_array_id_or_label"])
+	if !ok {
+		return nil, nil
+	}
+
+	ids := []int{}
+	for _, iaIDOrLabel := range strings.Split(v, ",") {
+		iaID, err := getIDOrDo(strings.TrimSpace(iaIDOrLabel), func(label string) (int, error) {
+			ia, err := client.InstanceArrayGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return ia.InstanceArrayID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, iaID)
+	}
+
+	return ids, nil
+}
+
+func getFirmwarePolicyRuleFromCommand(c *Command) (*metalcloud.ServerFirmwareUpgradePolicyRule, error) {
+
+	property, ok := getStringParamOk(c.Arguments["rule_property"])
+	if !ok {
+		return nil, fmt.Errorf("-property is required")
+	}
+
+	operation, ok := getStringParamOk(c.Arguments["rule_operation"])
+	if !ok {
+		return nil, fmt.Errorf("-operation is required")
+	}
+
+	value, ok := getStringParamOk(c.Arguments["rule_value"])
+	if !ok {
+		return nil, fmt.Errorf("-value is required")
+	}
+
+	return &metalcloud.ServerFirmwareUpgradePolicyRule{
+		Property:  property,
+		Operation: operation,
+		Value:     value,
+	}, nil
+}
+
+func firmwarePolicyRuleToString(r metalcloud.ServerFirmwareUpgradePolicyRule) string {
+	return fmt.Sprintf("%s %s %s", r.Property, r.Operation, r.Value)
+}
+
+func firmwareVersionAvailable(sc metalcloud.ServerComponent, version string) bool {
+	for _, v := range sc.ServerComponentFirmwareUpdateAvailableVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+func getTestServerComponents() []metalcloud.ServerComponent {
+	return []metalcloud.ServerComponent{
+		{
+			ServerComponentID:                              2010,
+			ServerID:                                       100,
+			ServerComponentName:                            "BIOS",
+			ServerComponentType:                            "bios",
+			ServerComponentFirmwareVersion:                 "2.10.0",
+			ServerComponentFirmwareUpdateable:              true,
+			ServerComponentFirmwareUpdateAvailableVersions: []string{"2.11.0", "2.12.2"},
+		},
+		{
+			ServerComponentID:              2011,
+			ServerID:                       100,
+			ServerComponentName:            "PSU",
+			ServerComponentType:            "power",
+			ServerComponentFirmwareVersion: "1.0",
+		},
+	}
+}
+
+func TestFirmwareListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	server := metalcloud.Server{ServerID: 100}
+	components := getTestServerComponents()
+
+	client.EXPECT().
+		ServerGet(100, false).
+		Return(&server, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(100, gomock.Any()).
+		Return(&components, nil).
+		AnyTimes()
+
+	expectedFirstRow := map[string]interface{}{
+		"ID":      2010,
+		"NAME":    "BIOS",
+		"VERSION": "2.10.0",
+	}
+
+	cases := []CommandTestCase{
+		{
+			name: "good1",
+			cmd:  MakeCommand(map[string]interface{}{"server_id_or_uuid": 100}),
+			good: true,
+		},
+		{
+			name: "no server",
+			cmd:  MakeCommand(map[string]interface{}{}),
+			good: false,
+		},
+	}
+
+	testGetCommand(firmwareListCmd, cases, client, expectedFirstRow, t)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id_or_uuid": 100,
+		"updateable_only":   true,
+		"format":            "json",
+	})
+
+	ret, err := firmwareListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(1))
+}
+
+func TestFirmwareTargetSetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	server := metalcloud.Server{ServerID: 100}
+	components := getTestServerComponents()
+
+	client.EXPECT().
+		ServerGet(100, false).
+		Return(&server, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(100, "").
+		Return(&components, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerFirmwareComponentTargetVersionSet(2010, "2.12.2").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwareComponentTargetVersionAdd(2010, "2.13.0", "http://repo/bios.bin").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwareComponentTargetVersionSet(2010, "2.13.0").
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2010,
+		"firmware_version":    "2.12.2",
+	})
+
+	_, err := firmwareTargetSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//versions that are not available need an url
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2010,
+		"firmware_version":    "2.13.0",
+	})
+
+	_, err = firmwareTargetSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("2.11.0, 2.12.2"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2010,
+		"firmware_version":    "2.13.0",
+		"firmware_binary_url": "http://repo/bios.bin",
+	})
+
+	_, err = firmwareTargetSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//not updateable
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2011,
+		"firmware_version":    "1.1",
+	})
+
+	_, err = firmwareTargetSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//component of a different server
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 3000,
+		"firmware_version":    "1.1",
+	})
+
+	_, err = firmwareTargetSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestFirmwareUpgradeCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	server := metalcloud.Server{ServerID: 100}
+	components := getTestServerComponents()
+
+	client.EXPECT().
+		ServerGet(100, false).
+		Return(&server, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(100, "").
+		Return(&components, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerFirmwareUpgrade(100).
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwareComponentUpgrade(100, 2010, "2.12.2", "").
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id_or_uuid": 100,
+		"autoconfirm":       true,
+	})
+
+	_, err := firmwareUpgradeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2010,
+		"firmware_version":    "2.12.2",
+		"autoconfirm":         true,
+	})
+
+	_, err = firmwareUpgradeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id_or_uuid":   100,
+		"server_component_id": 2011,
+		"autoconfirm":         true,
+	})
+
+	_, err = firmwareUpgradeCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestFirmwarePolicyCmds(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	rule := metalcloud.ServerFirmwareUpgradePolicyRule{
+		Property:  "server_component_type",
+		Operation: "string_equal",
+		Value:     "bios",
+	}
+
+	policy := metalcloud.ServerFirmwareUpgradePolicy{
+		ServerFirmwareUpgradePolicyID:    10,
+		ServerFirmwareUpgradePolicyLabel: "policy",
+		ServerFirmwareUpgradePolicyRules: []metalcloud.ServerFirmwareUpgradePolicyRule{rule},
+		InstanceArrayIDList:              []int{100},
+	}
+
+	ia := metalcloud.InstanceArray{InstanceArrayID: 101}
+
+	client.EXPECT().
+		ServerFirmwarePolicyGet(10).
+		Return(&policy, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGetByLabel("ia").
+		Return(&ia, nil).
+		AnyTimes()
+
+	//get
+	expectedFirstRow := map[string]interface{}{
+		"ID":    10,
+		"LABEL": "policy",
+	}
+
+	cases := []CommandTestCase{
+		{
+			name: "good1",
+			cmd:  MakeCommand(map[string]interface{}{"policy_id": 10}),
+			good: true,
+		},
+		{
+			name: "no id",
+			cmd:  MakeCommand(map[string]interface{}{}),
+			good: false,
+		},
+	}
+
+	testGetCommand(firmwarePolicyGetCmd, cases, client, expectedFirstRow, t)
+
+	//create from a file, with the label overridden
+	f := filepath.Join(t.TempDir(), "policy.yaml")
+	Expect(os.WriteFile(f, []byte("label: other\naction: upgrade\nrules:\n  - property: server_component_type\n    operation: string_equal\n    value: bios\n"), 0600)).To(BeNil())
+
+	client.EXPECT().
+		ServerFirmwareUpgradePolicyCreate(&metalcloud.ServerFirmwareUpgradePolicy{
+			ServerFirmwareUpgradePolicyLabel:  "policy",
+			ServerFirmwareUpgradePolicyAction: "upgrade",
+			ServerFirmwareUpgradePolicyRules:  []metalcloud.ServerFirmwareUpgradePolicyRule{rule},
+		}).
+		Return(&policy, nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwareUgradePolicyInstanceArraySet(10, []int{100, 101}).
+		Return(nil).
+		Times(2)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"policy_label":               "policy",
+		"instance_array_id_or_label": "100,ia",
+		"read_config_from_file":      f,
+		"format":                     "yaml",
+		"return_id":                  true,
+	})
+
+	ret, err := firmwarePolicyCreateCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("10"))
+
+	//edit
+	client.EXPECT().
+		ServerFirmwareUpgradePolicyLabelSet(10, "new-label").
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":                  10,
+		"policy_label":               "new-label",
+		"instance_array_id_or_label": "100,ia",
+	})
+
+	_, err = firmwarePolicyEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id": 10,
+	})
+
+	_, err = firmwarePolicyEditCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//rules
+	client.EXPECT().
+		ServerFirmwarePolicyAddRule(10, &rule).
+		Return(&policy, nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerFirmwarePolicyDeleteRule(10, &rule).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":      10,
+		"rule_property":  rule.Property,
+		"rule_operation": rule.Operation,
+		"rule_value":     rule.Value,
+		"autoconfirm":    true,
+	})
+
+	_, err = firmwarePolicyRuleAddCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	_, err = firmwarePolicyRuleDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":      10,
+		"rule_property":  rule.Property,
+		"rule_operation": rule.Operation,
+		"rule_value":     "nic",
+		"autoconfirm":    true,
+	})
+
+	_, err = firmwarePolicyRuleDeleteCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//delete
+	client.EXPECT().
+		ServerFirmwareUpgradePolicyDelete(10).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"policy_id":   10,
+		"autoconfirm": true,
+	})
+
+	_, err = firmwarePolicyDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
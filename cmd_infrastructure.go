@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -103,6 +105,8 @@ var infrastructureCmds = []Command{
 				"block_timeout":                  c.FlagSet.Int("block-timeout", 180*60, "Block timeout in seconds. After this timeout the application will return an error. Defaults to 180 minutes."),
 				"block_check_interval":           c.FlagSet.Int("block-check-interval", 10, "Check interval for when blocking. Defaults to 10 seconds."),
 				"autoconfirm":                    c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+				"at":                             c.FlagSet.String("at", _nilDefaultStr, "Schedule the deploy instead of running it now. The time must be in RFC3339 format such as 2026-10-20T02:00:00Z. Scheduled deploys are executed by 'schedule run'."),
+				"window":                         c.FlagSet.String("window", _nilDefaultStr, "Maintenance window policy file (json or yaml). Deploys with allow-data-loss or hard shutdown are refused outside the windows defined in it."),
 			}
 		},
 		ExecuteFunc:   infrastructureDeployCmd,
@@ -143,6 +147,84 @@ var infrastructureCmds = []Command{
 		Endpoint:      UserEndpoint,
 		AdminEndpoint: DeveloperEndpoint,
 	},
+	{
+		Description:  "Edit an infrastructure.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "edit",
+		AltPredicate: "update",
+		FlagSet:      flag.NewFlagSet("edit infrastructure", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"infrastructure_label":       c.FlagSet.String("label", _nilDefaultStr, "Infrastructure's new label"),
+				"custom_variables":           c.FlagSet.String("custom-variables", _nilDefaultStr, "Comma separated list of custom variables such as 'var1=value,var2=value'. If special characters need to be set use urlencode and pass the encoded string"),
+			}
+		},
+		ExecuteFunc:   infrastructureEditCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+	},
+	{
+		Description:  "Show infrastructure usage versus the account limits.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "limits",
+		AltPredicate: "quota",
+		FlagSet:      flag.NewFlagSet("infrastructure limits", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc:   infrastructureLimitsCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+	},
+	{
+		Description:  "Show the resource tree of an infrastructure.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "tree",
+		AltPredicate: "tree",
+		FlagSet:      flag.NewFlagSet("infrastructure tree", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc:   infrastructureTreeCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+	},
+	{
+		Description:  "Control power for all the instances of an infrastructure.",
+		Subject:      "infrastructure",
+		AltSubject:   "infra",
+		Predicate:    "power-control",
+		AltPredicate: "pwr",
+		FlagSet:      flag.NewFlagSet("infrastructure power control", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that using the 'label' might be ambiguous in certain situations."),
+				"operation":                  c.FlagSet.String("operation", _nilDefaultStr, red("(Required)")+" Power control operation, one of: on, off, reset, soft"),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "Maximum number of instances on which the operation is executed at the same time."),
+				"batch_size":                 c.FlagSet.Int("batch-size", _nilDefaultInt, "If set, the instances are processed in batches of this size (rolling mode). Except for reset, a batch starts after the instances of the previous one reached the expected power status. The operation stops at the first batch with failures."),
+				"batch_timeout":              c.FlagSet.Int("batch-timeout", 600, "Seconds to wait in rolling mode for the instances of a batch to reach the expected power status. Defaults to 10 minutes."),
+				"wait_between":               c.FlagSet.Int("wait-between", _nilDefaultInt, "Additional seconds to wait between batches in rolling mode, after the instances of a batch reached the expected power status. For reset this is the only wait between batches."),
+				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: infrastructurePowerControlCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli infrastructure power-control --id 100 --operation soft
+metalcloud-cli infrastructure power-control --id 100 --operation reset --batch-size 5 --wait-between 600 --concurrency 5
+`,
+	},
 	{
 		Description:  "List stages of a workflow.",
 		Subject:      "infrastructure",
@@ -417,9 +499,19 @@ func infrastructureConfirmAndDo(operation string, c *Command, client metalcloud.
 		return "", err
 	}
 
+	//the limits warning is shown even when the deploy is not confirmed interactively
+	warning := ""
+	if operation == "Deploy" {
+		warning, err = infrastructureLimitsWarning(infraID, client)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	confirm := false
 
 	if getBoolParam(c.Arguments["autoconfirm"]) {
+		fmt.Fprint(GetStdout(), warning)
 		confirm = true
 	} else {
 
@@ -428,7 +520,7 @@ func infrastructureConfirmAndDo(operation string, c *Command, client metalcloud.
 			return "", err
 		}
 
-		confirmationMessage := fmt.Sprintf("%s infrastructure %s (%d). Are you sure? Type \"yes\" to continue:", operation, retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+		confirmationMessage := warning + fmt.Sprintf("%s infrastructure %s (%d). Are you sure? Type \"yes\" to continue:", operation, retInfra.InfrastructureLabel, retInfra.InfrastructureID)
 
 		//this is simply so that we don't output a text on the command line under go test
 		if strings.HasSuffix(os.Args[0], ".test") {
@@ -460,37 +552,111 @@ func infrastructureDeployCmd(c *Command, client metalcloud.MetalCloudClient) (st
 	return infrastructureConfirmAndDo("Deploy", c, client,
 		func(infraID int, c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
-			shutDownOptions := metalcloud.ShutdownOptions{
-				HardShutdownAfterTimeout:   !getBoolParam(c.Arguments["no_hard_shutdown_after_timeout"]),
-				AttemptSoftShutdown:        !getBoolParam(c.Arguments["no_attempt_soft_shutdown"]),
-				SoftShutdownTimeoutSeconds: getIntParam(c.Arguments["soft_shutdown_timeout_seconds"]),
-			}
+			shutDownOptions := getShutdownOptionsFromCommand(c)
 
-			err := client.InfrastructureDeploy(
-				infraID,
-				shutDownOptions,
-				getBoolParam(c.Arguments["allow_data_loss"]),
-				getBoolParam(c.Arguments["skip_ansible"]),
-			)
+			allowDataLoss := getBoolParam(c.Arguments["allow_data_loss"])
+
+			windowFile, err := getMaintenanceWindowFileFromCommand(c)
 			if err != nil {
 				return "", err
 			}
 
-			if getBoolParam(c.Arguments["block_until_deployed"]) {
-
-				time.Sleep(time.Duration(getIntParam(c.Arguments["block_check_interval"])) * time.Second) //wait until the system picks up the afc
+			if v, ok := getStringParamOk(c.Arguments["at"]); ok {
+				at, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					return "", fmt.Errorf("-at must be in RFC3339 format such as 2026-10-20T02:00:00Z: %s", err)
+				}
 
-				err := loopUntilInfraReady(infraID, getIntParam(c.Arguments["block_timeout"]), getIntParam(c.Arguments["block_check_interval"]), client)
+				return infrastructureScheduleDeploy(infraID, at, shutDownOptions, allowDataLoss, windowFile, c, client)
+			}
 
-				if err != nil && strings.HasPrefix(err.Error(), "timeout after") {
-					return "", err
-				} //else we ignore errors as they might be infrastrucure not found due to infrastructure being deleted
+			err = checkMaintenanceWindow(windowFile, time.Now(), shutDownOptions, allowDataLoss)
+			if err != nil {
+				return "", err
 			}
 
-			return "", nil
+			return "", infrastructureDeployAndBlock(infraID, shutDownOptions, allowDataLoss, getBoolParam(c.Arguments["skip_ansible"]), c, client)
 		})
 }
 
+// getShutdownOptionsFromCommand returns the shutdown options set with the deploy flags
+func getShutdownOptionsFromCommand(c *Command) metalcloud.ShutdownOptions {
+	return metalcloud.ShutdownOptions{
+		HardShutdownAfterTimeout:   !getBoolParam(c.Arguments["no_hard_shutdown_after_timeout"]),
+		AttemptSoftShutdown:        !getBoolParam(c.Arguments["no_attempt_soft_shutdown"]),
+		SoftShutdownTimeoutSeconds: getIntParam(c.Arguments["soft_shutdown_timeout_seconds"]),
+	}
+}
+
+// getMaintenanceWindowFileFromCommand returns the absolute path of the maintenance window policy file or an empty string if none was set
+func getMaintenanceWindowFileFromCommand(c *Command) (string, error) {
+	v, ok := getStringParamOk(c.Arguments["window"])
+	if !ok {
+		return "", nil
+	}
+
+	return filepath.Abs(v)
+}
+
+// infrastructureDeployAndBlock deploys the infrastructure and, if block_until_deployed is set, waits for the deploy to finish
+func infrastructureDeployAndBlock(infraID int, shutDownOptions metalcloud.ShutdownOptions, allowDataLoss bool, skipAnsible bool, c *Command, client metalcloud.MetalCloudClient) error {
+
+	err := client.InfrastructureDeploy(
+		infraID,
+		shutDownOptions,
+		allowDataLoss,
+		skipAnsible,
+	)
+	if err != nil {
+		return err
+	}
+
+	if getBoolParam(c.Arguments["block_until_deployed"]) {
+
+		time.Sleep(time.Duration(getIntParam(c.Arguments["block_check_interval"])) * time.Second) //wait until the system picks up the afc
+
+		err := loopUntilInfraReady(infraID, getIntParam(c.Arguments["block_timeout"]), getIntParam(c.Arguments["block_check_interval"]), client)
+
+		if err != nil && strings.HasPrefix(err.Error(), "timeout after") {
+			return err
+		} //else we ignore errors as they might be infrastrucure not found due to infrastructure being deleted
+	}
+
+	return nil
+}
+
+// infrastructureScheduleDeploy saves the deploy locally to be executed by 'schedule run' at the given time
+func infrastructureScheduleDeploy(infraID int, at time.Time, shutDownOptions metalcloud.ShutdownOptions, allowDataLoss bool, windowFile string, c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	if err := checkMaintenanceWindow(windowFile, at, shutDownOptions, allowDataLoss); err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(infraID)
+	if err != nil {
+		return "", err
+	}
+
+	s, err := addScheduledDeploy(scheduledDeploy{
+		InfrastructureID:    infraID,
+		InfrastructureLabel: retInfra.InfrastructureLabel,
+		At:                  at,
+		ShutdownOptions:     shutDownOptions,
+		AllowDataLoss:       allowDataLoss,
+		SkipAnsible:         getBoolParam(c.Arguments["skip_ansible"]),
+		WindowFile:          windowFile,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Deploy of infrastructure %s (#%d) scheduled at %s with id %d\n",
+		retInfra.InfrastructureLabel,
+		infraID,
+		at.Format(time.RFC3339),
+		s.ID), nil
+}
+
 func infrastructureRevertCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
 	return infrastructureConfirmAndDo("Revert", c, client,
@@ -499,6 +665,275 @@ func infrastructureRevertCmd(c *Command, client metalcloud.MetalCloudClient) (st
 		})
 }
 
+func infrastructureEditCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	op := retInfra.InfrastructureOperation
+
+	if v, ok := getStringParamOk(c.Arguments["infrastructure_label"]); ok {
+		op.InfrastructureLabel = v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["custom_variables"]); ok {
+		m, err := getKeyValueMapFromString(v)
+		if err != nil {
+			return "", err
+		}
+		op.InfrastructureCustomVariables = m
+	}
+
+	_, err = client.InfrastructureEdit(retInfra.InfrastructureID, op)
+	if err != nil {
+		return "", err
+	}
+
+	return infrastructureLimitsWarning(retInfra.InfrastructureID, client)
+}
+
+// getInfrastructureLimitResource returns the resource constrained by a limit returned by infrastructure_user_limits.
+// The SDK returns the limits as an untyped map so they are matched by the words in their keys. Limits that do not
+// match any resource are still shown by the limits command but without usage.
+func getInfrastructureLimitResource(key string) (string, bool) {
+	k := strings.ToLower(key)
+
+	switch {
+	case strings.Contains(k, "instance") && strings.Contains(k, "count"):
+		return "instances", true
+	case strings.Contains(k, "drive") && strings.Contains(k, "mbytes"):
+		return "storage_mbytes", true
+	case strings.Contains(k, "drive") && strings.Contains(k, "count"):
+		return "drives", true
+	}
+
+	return "", false
+}
+
+// getUnmatchedLimitsWarning returns a warning if the API returned limits but none of them could be matched to a resource,
+// in which case the usage is not checked against any limit
+func getUnmatchedLimitsWarning(limits map[string]interface{}) string {
+
+	keys := []string{}
+	for key := range limits {
+		if _, ok := getInfrastructureLimitResource(key); ok {
+			return ""
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return ""
+	}
+
+	sort.Strings(keys)
+
+	return yellow(fmt.Sprintf("WARNING: None of the account limits (%s) could be matched to instances, drives or storage. The pending changes were not checked against them.\n", strings.Join(keys, ", ")))
+}
+
+// infrastructureUsage holds the resources used by an infrastructure
+type infrastructureUsage map[string]int
+
+// getInfrastructureUsage returns the currently deployed usage and the usage after the pending edits are deployed
+func getInfrastructureUsage(infraID int, client metalcloud.MetalCloudClient) (infrastructureUsage, infrastructureUsage, error) {
+
+	current := infrastructureUsage{}
+	pending := infrastructureUsage{}
+
+	iaList, err := client.InstanceArrays(infraID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, ia := range *iaList {
+		if ia.InstanceArrayServiceStatus == "active" {
+			current["instances"] += ia.InstanceArrayInstanceCount
+		}
+		if ia.InstanceArrayOperation != nil && ia.InstanceArrayOperation.InstanceArrayDeployType != "delete" {
+			pending["instances"] += ia.InstanceArrayOperation.InstanceArrayInstanceCount
+		}
+	}
+
+	daList, err := client.DriveArrays(infraID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, da := range *daList {
+		if da.DriveArrayServiceStatus == "active" {
+			current["drives"] += da.DriveArrayCount
+			current["storage_mbytes"] += da.DriveArrayCount * da.DriveSizeMBytesDefault
+		}
+		if da.DriveArrayOperation != nil && da.DriveArrayOperation.DriveArrayDeployType != "delete" {
+			pending["drives"] += da.DriveArrayOperation.DriveArrayCount
+			pending["storage_mbytes"] += da.DriveArrayOperation.DriveArrayCount * da.DriveArrayOperation.DriveSizeMBytesDefault
+		}
+	}
+
+	sdaList, err := client.SharedDrives(infraID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, sda := range *sdaList {
+		if sda.SharedDriveServiceStatus == "active" {
+			current["storage_mbytes"] += sda.SharedDriveSizeMbytes
+		}
+		if sda.SharedDriveOperation.SharedDriveDeployType != "delete" {
+			pending["storage_mbytes"] += sda.SharedDriveOperation.SharedDriveSizeMbytes
+		}
+	}
+
+	return current, pending, nil
+}
+
+// getLimitValue converts a limit as returned by the API into an int. Returns false if the limit is not numeric.
+func getLimitValue(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	}
+	return 0, false
+}
+
+// infrastructureLimitsWarning returns a warning listing the limits that would be exceeded once the pending edits are deployed
+func infrastructureLimitsWarning(infraID int, client metalcloud.MetalCloudClient) (string, error) {
+
+	limits, err := client.InfrastructureUserLimits(infraID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(*limits) == 0 {
+		return "", nil
+	}
+
+	if warning := getUnmatchedLimitsWarning(*limits); warning != "" {
+		return warning, nil
+	}
+
+	_, pending, err := getInfrastructureUsage(infraID, client)
+	if err != nil {
+		return "", err
+	}
+
+	exceeded := []string{}
+	for key, v := range *limits {
+		resource, known := getInfrastructureLimitResource(key)
+		limit, numeric := getLimitValue(v)
+		if !known || !numeric {
+			continue
+		}
+		if pending[resource] > limit {
+			exceeded = append(exceeded, fmt.Sprintf("%s (%d > %d)", resource, pending[resource], limit))
+		}
+	}
+
+	if len(exceeded) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(exceeded)
+
+	return red(fmt.Sprintf("WARNING: Pending changes exceed the account limits: %s. The deploy will likely fail.\n", strings.Join(exceeded, ", "))), nil
+}
+
+func infrastructureLimitsCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	limits, err := client.InfrastructureUserLimits(retInfra.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	current, pending, err := getInfrastructureUsage(retInfra.InfrastructureID, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "LIMIT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "CURRENT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "PENDING",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "MAX",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	for key, v := range *limits {
+
+		currentStr := ""
+		pendingStr := ""
+		status := ""
+
+		resource, known := getInfrastructureLimitResource(key)
+		limit, numeric := getLimitValue(v)
+
+		if known {
+			currentStr = fmt.Sprintf("%d", current[resource])
+			pendingStr = fmt.Sprintf("%d", pending[resource])
+			if numeric {
+				status = green("ok")
+				if pending[resource] > limit {
+					status = red("exceeded")
+				}
+			}
+		}
+
+		data = append(data, []interface{}{
+			key,
+			currentStr,
+			pendingStr,
+			fmt.Sprintf("%v", v),
+			status,
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	topLine := fmt.Sprintf("Infrastructure %s (%d) usage versus account limits",
+		retInfra.InfrastructureLabel,
+		retInfra.InfrastructureID)
+
+	if warning := getUnmatchedLimitsWarning(*limits); warning != "" {
+		topLine = fmt.Sprintf("%s\n%s", topLine, strings.TrimSpace(warning))
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+	return table.RenderTable("Limits", topLine, getStringParam(c.Arguments["format"]))
+}
+
 func infrastructureGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
 	retInfra, err := getInfrastructureFromCommand("id", c, client)
@@ -669,6 +1104,358 @@ func infrastructureGetCmd(c *Command, client metalcloud.MetalCloudClient) (strin
 	return table.RenderTable("Infrastructures", topLine, getStringParam(c.Arguments["format"]))
 }
 
+// infrastructureTree is the nested representation of an infrastructure used by the tree command
+type infrastructureTree struct {
+	ID             int                     `json:"id" yaml:"id"`
+	Label          string                  `json:"label" yaml:"label"`
+	Datacenter     string                  `json:"datacenter" yaml:"datacenter"`
+	Status         string                  `json:"status" yaml:"status"`
+	InstanceArrays []instanceArrayTreeNode `json:"instance_arrays" yaml:"instanceArrays"`
+	DriveArrays    []driveArrayTreeNode    `json:"drive_arrays" yaml:"driveArrays"`
+	SharedDrives   []sharedDriveTreeNode   `json:"shared_drives" yaml:"sharedDrives"`
+	Networks       []networkTreeNode       `json:"networks" yaml:"networks"`
+}
+
+type instanceArrayTreeNode struct {
+	ID          int                  `json:"id" yaml:"id"`
+	Label       string               `json:"label" yaml:"label"`
+	Status      string               `json:"status" yaml:"status"`
+	Instances   []instanceTreeNode   `json:"instances" yaml:"instances"`
+	DriveArrays []driveArrayTreeNode `json:"drive_arrays" yaml:"driveArrays"`
+	Networks    []networkTreeNode    `json:"networks" yaml:"networks"`
+}
+
+type instanceTreeNode struct {
+	ID          int             `json:"id" yaml:"id"`
+	Label       string          `json:"label" yaml:"label"`
+	Status      string          `json:"status" yaml:"status"`
+	ServerID    int             `json:"server_id,omitempty" yaml:"serverID,omitempty"`
+	PowerStatus string          `json:"power_status,omitempty" yaml:"powerStatus,omitempty"`
+	Drives      []driveTreeNode `json:"drives" yaml:"drives"`
+}
+
+type driveArrayTreeNode struct {
+	ID     int             `json:"id" yaml:"id"`
+	Label  string          `json:"label" yaml:"label"`
+	Status string          `json:"status" yaml:"status"`
+	Drives []driveTreeNode `json:"drives" yaml:"drives"`
+}
+
+type driveTreeNode struct {
+	ID         int    `json:"id" yaml:"id"`
+	Label      string `json:"label" yaml:"label"`
+	Status     string `json:"status" yaml:"status"`
+	SizeMBytes int    `json:"size_mbytes" yaml:"sizeMBytes"`
+	InstanceID int    `json:"instance_id,omitempty" yaml:"instanceID,omitempty"`
+}
+
+type sharedDriveTreeNode struct {
+	ID         int    `json:"id" yaml:"id"`
+	Label      string `json:"label" yaml:"label"`
+	Status     string `json:"status" yaml:"status"`
+	SizeMBytes int    `json:"size_mbytes" yaml:"sizeMBytes"`
+}
+
+type networkTreeNode struct {
+	ID             int    `json:"id" yaml:"id"`
+	Label          string `json:"label" yaml:"label"`
+	Type           string `json:"type" yaml:"type"`
+	InterfaceIndex int    `json:"interface_index,omitempty" yaml:"interfaceIndex,omitempty"`
+}
+
+// getInfrastructureTree retrieves all the elements of an infrastructure and nests them
+func getInfrastructureTree(infra *metalcloud.Infrastructure, client metalcloud.MetalCloudClient) (*infrastructureTree, error) {
+
+	tree := infrastructureTree{
+		ID:             infra.InfrastructureID,
+		Label:          infra.InfrastructureLabel,
+		Datacenter:     infra.DatacenterName,
+		Status:         infra.InfrastructureServiceStatus,
+		InstanceArrays: []instanceArrayTreeNode{},
+		DriveArrays:    []driveArrayTreeNode{},
+		SharedDrives:   []sharedDriveTreeNode{},
+		Networks:       []networkTreeNode{},
+	}
+
+	netList, err := client.Networks(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	networks := map[int]networkTreeNode{}
+	for _, n := range *netList {
+		node := networkTreeNode{
+			ID:    n.NetworkID,
+			Label: n.NetworkLabel,
+			Type:  n.NetworkType,
+		}
+		networks[n.NetworkID] = node
+		tree.Networks = append(tree.Networks, node)
+	}
+	sort.Slice(tree.Networks, func(i, j int) bool { return tree.Networks[i].ID < tree.Networks[j].ID })
+
+	daList, err := client.DriveArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	//drive arrays are attached to instance arrays while drives are attached to instances
+	driveArraysByIA := map[int][]driveArrayTreeNode{}
+	drivesByInstance := map[int][]driveTreeNode{}
+	for _, da := range *daList {
+
+		drives, err := client.DriveArrayDrives(da.DriveArrayID)
+		if err != nil {
+			return nil, err
+		}
+
+		node := driveArrayTreeNode{
+			ID:     da.DriveArrayID,
+			Label:  da.DriveArrayLabel,
+			Status: da.DriveArrayServiceStatus,
+			Drives: []driveTreeNode{},
+		}
+
+		for _, d := range *drives {
+			dn := driveTreeNode{
+				ID:         d.DriveID,
+				Label:      d.DriveLabel,
+				Status:     d.DriveServiceStatus,
+				SizeMBytes: d.DriveSizeMBytes,
+				InstanceID: d.InstanceID,
+			}
+			node.Drives = append(node.Drives, dn)
+			if d.InstanceID != 0 {
+				drivesByInstance[d.InstanceID] = append(drivesByInstance[d.InstanceID], dn)
+			}
+		}
+		sort.Slice(node.Drives, func(i, j int) bool { return node.Drives[i].ID < node.Drives[j].ID })
+
+		if da.InstanceArrayID != 0 {
+			driveArraysByIA[da.InstanceArrayID] = append(driveArraysByIA[da.InstanceArrayID], node)
+		} else {
+			tree.DriveArrays = append(tree.DriveArrays, node)
+		}
+	}
+	sort.Slice(tree.DriveArrays, func(i, j int) bool { return tree.DriveArrays[i].ID < tree.DriveArrays[j].ID })
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ia := range *iaList {
+
+		node := instanceArrayTreeNode{
+			ID:          ia.InstanceArrayID,
+			Label:       ia.InstanceArrayLabel,
+			Status:      ia.InstanceArrayServiceStatus,
+			Instances:   []instanceTreeNode{},
+			DriveArrays: []driveArrayTreeNode{},
+			Networks:    []networkTreeNode{},
+		}
+
+		if l, ok := driveArraysByIA[ia.InstanceArrayID]; ok {
+			node.DriveArrays = l
+			sort.Slice(node.DriveArrays, func(i, j int) bool { return node.DriveArrays[i].ID < node.DriveArrays[j].ID })
+		}
+
+		for _, intf := range ia.InstanceArrayInterfaces {
+			if n, ok := networks[intf.NetworkID]; ok {
+				n.InterfaceIndex = intf.InstanceArrayInterfaceIndex
+				node.Networks = append(node.Networks, n)
+			}
+		}
+		sort.Slice(node.Networks, func(i, j int) bool { return node.Networks[i].InterfaceIndex < node.Networks[j].InterfaceIndex })
+
+		instances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceIDs := []int{}
+		for _, i := range *instances {
+			if i.ServerID != 0 {
+				instanceIDs = append(instanceIDs, i.InstanceID)
+			}
+		}
+
+		powerStatus := map[string]string{}
+		if len(instanceIDs) > 0 {
+			ps, err := client.InstanceServerPowerGetBatch(infra.InfrastructureID, instanceIDs)
+			if err != nil {
+				return nil, err
+			}
+			powerStatus = *ps
+		}
+
+		for _, i := range *instances {
+			in := instanceTreeNode{
+				ID:          i.InstanceID,
+				Label:       i.InstanceLabel,
+				Status:      i.InstanceServiceStatus,
+				ServerID:    i.ServerID,
+				PowerStatus: powerStatus[fmt.Sprintf("%d", i.InstanceID)],
+				Drives:      []driveTreeNode{},
+			}
+			if l, ok := drivesByInstance[i.InstanceID]; ok {
+				in.Drives = l
+				sort.Slice(in.Drives, func(i, j int) bool { return in.Drives[i].ID < in.Drives[j].ID })
+			}
+			node.Instances = append(node.Instances, in)
+		}
+		sort.Slice(node.Instances, func(i, j int) bool { return node.Instances[i].ID < node.Instances[j].ID })
+
+		tree.InstanceArrays = append(tree.InstanceArrays, node)
+	}
+	sort.Slice(tree.InstanceArrays, func(i, j int) bool { return tree.InstanceArrays[i].ID < tree.InstanceArrays[j].ID })
+
+	sdaList, err := client.SharedDrives(infra.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sda := range *sdaList {
+		tree.SharedDrives = append(tree.SharedDrives, sharedDriveTreeNode{
+			ID:         sda.SharedDriveID,
+			Label:      sda.SharedDriveLabel,
+			Status:     sda.SharedDriveServiceStatus,
+			SizeMBytes: sda.SharedDriveSizeMbytes,
+		})
+	}
+	sort.Slice(tree.SharedDrives, func(i, j int) bool { return tree.SharedDrives[i].ID < tree.SharedDrives[j].ID })
+
+	return &tree, nil
+}
+
+// treeNode is a generic node used to render the tree as text
+type treeNode struct {
+	text     string
+	children []treeNode
+}
+
+func renderTreeNodes(sb *strings.Builder, nodes []treeNode, prefix string) {
+	for i, n := range nodes {
+		connector := "├── "
+		childPrefix := prefix + "│   "
+		if i == len(nodes)-1 {
+			connector = "└── "
+			childPrefix = prefix + "    "
+		}
+		sb.WriteString(prefix + connector + n.text + "\n")
+		renderTreeNodes(sb, n.children, childPrefix)
+	}
+}
+
+func driveTreeNodes(drives []driveTreeNode) []treeNode {
+	nodes := []treeNode{}
+	for _, d := range drives {
+		nodes = append(nodes, treeNode{
+			text: fmt.Sprintf("%s %s (#%d) %.1f GB %s", yellow("Drive"), d.Label, d.ID, float64(d.SizeMBytes)/1024, d.Status),
+		})
+	}
+	return nodes
+}
+
+func driveArrayTreeNodes(driveArrays []driveArrayTreeNode) []treeNode {
+	nodes := []treeNode{}
+	for _, da := range driveArrays {
+		nodes = append(nodes, treeNode{
+			text:     fmt.Sprintf("%s %s (#%d) %s", blue("DriveArray"), da.Label, da.ID, da.Status),
+			children: driveTreeNodes(da.Drives),
+		})
+	}
+	return nodes
+}
+
+func colorizePowerStatus(status string) string {
+	switch status {
+	case "on":
+		return green(status)
+	case "off":
+		return red(status)
+	}
+	return status
+}
+
+// render returns the human readable representation of the tree
+func (t infrastructureTree) render() string {
+
+	nodes := []treeNode{}
+
+	for _, ia := range t.InstanceArrays {
+
+		children := []treeNode{}
+
+		for _, n := range ia.Networks {
+			children = append(children, treeNode{
+				text: fmt.Sprintf("%s %s (#%d) %s on interface %d", magenta("Network"), n.Label, n.ID, n.Type, n.InterfaceIndex),
+			})
+		}
+
+		for _, i := range ia.Instances {
+			serverNodes := []treeNode{}
+			if i.ServerID != 0 {
+				serverNodes = append(serverNodes, treeNode{
+					text: fmt.Sprintf("%s #%d power %s", bold("Server"), i.ServerID, colorizePowerStatus(i.PowerStatus)),
+				})
+			}
+			children = append(children, treeNode{
+				text:     fmt.Sprintf("%s %s (#%d) %s", green("Instance"), i.Label, i.ID, i.Status),
+				children: append(serverNodes, driveTreeNodes(i.Drives)...),
+			})
+		}
+
+		children = append(children, driveArrayTreeNodes(ia.DriveArrays)...)
+
+		nodes = append(nodes, treeNode{
+			text:     fmt.Sprintf("%s %s (#%d) %s", green("InstanceArray"), ia.Label, ia.ID, ia.Status),
+			children: children,
+		})
+	}
+
+	nodes = append(nodes, driveArrayTreeNodes(t.DriveArrays)...)
+
+	for _, sda := range t.SharedDrives {
+		nodes = append(nodes, treeNode{
+			text: fmt.Sprintf("%s %s (#%d) %d GB %s", magenta("SharedDrive"), sda.Label, sda.ID, sda.SizeMBytes/1024, sda.Status),
+		})
+	}
+
+	for _, n := range t.Networks {
+		nodes = append(nodes, treeNode{
+			text: fmt.Sprintf("%s %s (#%d) %s", magenta("Network"), n.Label, n.ID, n.Type),
+		})
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Infrastructure %s (#%d) - datacenter %s\n", t.Label, t.ID, t.Datacenter))
+	renderTreeNodes(&sb, nodes, "")
+
+	return sb.String()
+}
+
+func infrastructureTreeCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retInfra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := getInfrastructureTree(retInfra, client)
+	if err != nil {
+		return "", err
+	}
+
+	format := getStringParam(c.Arguments["format"])
+	switch format {
+	case "json", "JSON", "yaml", "YAML":
+		return tableformatter.RenderRawObject(*tree, format, "")
+	}
+
+	return tree.render(), nil
+}
+
 func listWorkflowStagesCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
 	t := *c.Arguments["type"].(*string)
@@ -801,3 +1588,30 @@ func loopUntilInfraReady(infraID int, timeoutSeconds int, checkIntervalSeconds i
 		return fmt.Errorf("timeout after %d seconds while waiting for infrastructure to finish deploying", timeoutSeconds)
 	}
 }
+
+func infrastructurePowerControlCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	iaList, err := client.InstanceArrays(infra.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	instanceArrays := []metalcloud.InstanceArray{}
+	for _, ia := range *iaList {
+		instanceArrays = append(instanceArrays, ia)
+	}
+
+	targets, err := getPowerControlTargets(instanceArrays, client)
+	if err != nil {
+		return "", err
+	}
+
+	scope := fmt.Sprintf("all instance arrays (%d)", len(instanceArrays))
+
+	return bulkPowerControl(*infra, targets, scope, c, client)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
@@ -115,6 +117,26 @@ func TestInfrastructureDeployCmd(t *testing.T) {
 		InstanceArrayGet(ia.InstanceArrayID).
 		Return(&ia, nil).
 		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDrives(10002).
+		Return(&map[string]metalcloud.SharedDrive{}, nil).
+		AnyTimes()
 	//bFalse := true
 	bTrue := true
 	timeout := 256
@@ -609,6 +631,11 @@ func TestDeployBlocking(t *testing.T) {
 			Times(1),
 	)
 
+	client.EXPECT().
+		InfrastructureUserLimits(1000).
+		Return(&map[string]interface{}{}, nil).
+		AnyTimes()
+
 	client.EXPECT().
 		InfrastructureDeploy(1000, gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil).
@@ -662,6 +689,11 @@ func TestDeployBlockingTimeouting(t *testing.T) {
 		}, nil).
 		AnyTimes()
 
+	client.EXPECT().
+		InfrastructureUserLimits(1000).
+		Return(&map[string]interface{}{}, nil).
+		AnyTimes()
+
 	client.EXPECT().
 		InfrastructureDeploy(1000, gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(nil).
@@ -680,3 +712,421 @@ func TestDeployBlockingTimeouting(t *testing.T) {
 	Expect(err).NotTo(BeNil())
 
 }
+
+func TestInfrastructureEditCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		InfrastructureOperation: metalcloud.InfrastructureOperation{
+			InfrastructureID:    10002,
+			InfrastructureLabel: "testinfra",
+		},
+	}
+
+	iao := metalcloud.InstanceArrayOperation{
+		InstanceArrayID:            11,
+		InstanceArrayInstanceCount: 5,
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayInstanceCount: 2,
+		InstanceArrayOperation:     &iao,
+		InstanceArrayServiceStatus: "active",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureEdit(10002, gomock.Any()).
+		DoAndReturn(func(id int, op metalcloud.InfrastructureOperation) (*metalcloud.Infrastructure, error) {
+			Expect(op.InfrastructureLabel).To(Equal("newlabel"))
+			Expect(op.InfrastructureCustomVariables).To(Equal(map[string]string{"a": "b", "c": "d"}))
+			return &infra, nil
+		}).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{"instance_count_max": float64(4)}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDrives(10002).
+		Return(&map[string]metalcloud.SharedDrive{}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"infrastructure_label":       "newlabel",
+		"custom_variables":           "a=b,c=d",
+	})
+
+	ret, err := infrastructureEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("instances (5 > 4)"))
+}
+
+func TestInfrastructureLimitsCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayInstanceCount: 2,
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayInstanceCount: 3,
+		},
+		InstanceArrayServiceStatus: "active",
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:            10,
+		DriveArrayLabel:         "testda",
+		DriveArrayCount:         2,
+		DriveSizeMBytesDefault:  1024,
+		DriveArrayServiceStatus: "active",
+		DriveArrayOperation: &metalcloud.DriveArrayOperation{
+			DriveArrayCount:        3,
+			DriveSizeMBytesDefault: 1024,
+		},
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{
+			"instance_count_max":          float64(10),
+			"drive_count_max":             float64(2),
+			"drive_size_mbytes_total_max": float64(100000),
+			"some_other_limit":            "x",
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{da.DriveArrayLabel: da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDrives(10002).
+		Return(&map[string]metalcloud.SharedDrive{}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"format":                     "json",
+	})
+
+	ret, err := infrastructureLimitsCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(len(m)).To(Equal(4))
+
+	rows := map[string]map[string]interface{}{}
+	for _, r := range m {
+		row := r.(map[string]interface{})
+		rows[row["LIMIT"].(string)] = row
+	}
+
+	Expect(rows["instance_count_max"]["CURRENT"]).To(Equal("2"))
+	Expect(rows["instance_count_max"]["PENDING"]).To(Equal("3"))
+	Expect(rows["drive_count_max"]["PENDING"]).To(Equal("3"))
+	Expect(rows["drive_count_max"]["STATUS"]).To(ContainSubstring("exceeded"))
+	Expect(rows["drive_size_mbytes_total_max"]["CURRENT"]).To(Equal("2048"))
+	Expect(rows["some_other_limit"]["CURRENT"]).To(Equal(""))
+
+	warning, err := infrastructureLimitsWarning(10002, client)
+	Expect(err).To(BeNil())
+	Expect(warning).To(ContainSubstring("drives (3 > 2)"))
+}
+
+func TestInfrastructureLimitsUnmatched(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{
+			"servers_max":  float64(10),
+			"storage_gb":   float64(100),
+			"other_limits": "x",
+		}, nil).
+		AnyTimes()
+
+	warning, err := infrastructureLimitsWarning(10002, client)
+	Expect(err).To(BeNil())
+	Expect(warning).To(ContainSubstring("None of the account limits (other_limits, servers_max, storage_gb) could be matched"))
+
+	resource, ok := getInfrastructureLimitResource("instance_count_max")
+	Expect(ok).To(BeTrue())
+	Expect(resource).To(Equal("instances"))
+
+	resource, ok = getInfrastructureLimitResource("drive_size_mbytes_total_max")
+	Expect(ok).To(BeTrue())
+	Expect(resource).To(Equal("storage_mbytes"))
+}
+
+func TestInfrastructureDeployLimitsWarning(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+		InstanceArrayOperation: &metalcloud.InstanceArrayOperation{
+			InstanceArrayInstanceCount: 3,
+		},
+	}
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{"instance_count_max": float64(2)}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDrives(10002).
+		Return(&map[string]metalcloud.SharedDrive{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureDeploy(10002, gomock.Any(), false, false).
+		Return(nil).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	//the warning is shown with autoconfirm too
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"autoconfirm":                true,
+	})
+
+	_, err := infrastructureDeployCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(stdout.String()).To(ContainSubstring("instances (3 > 2)"))
+}
+
+func TestInfrastructureTreeCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "dc1",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayServiceStatus: "active",
+		InstanceArrayInterfaces: []metalcloud.InstanceArrayInterface{
+			{
+				InstanceArrayInterfaceIndex: 0,
+				NetworkID:                   50,
+			},
+		},
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:            12,
+		DriveArrayLabel:         "testda",
+		InstanceArrayID:         ia.InstanceArrayID,
+		DriveArrayServiceStatus: "active",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		Networks(10002).
+		Return(&map[string]metalcloud.Network{
+			"wan": {NetworkID: 50, NetworkLabel: "wan", NetworkType: "wan"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(10002).
+		Return(&map[string]metalcloud.DriveArray{da.DriveArrayLabel: da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrayDrives(12).
+		Return(&map[string]metalcloud.Drive{
+			"d1": {DriveID: 100, DriveLabel: "d1", InstanceID: 200, DriveSizeMBytes: 2048},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10002).
+		Return(&map[string]metalcloud.InstanceArray{ia.InstanceArrayLabel: ia}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(11).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 200, InstanceLabel: "i1", ServerID: 300, InstanceServiceStatus: "active"},
+			"i2": {InstanceID: 201, InstanceLabel: "i2", InstanceServiceStatus: "ordered"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(10002, []int{200}).
+		Return(&map[string]string{"200": "on"}, nil).
+		Times(2)
+
+	client.EXPECT().
+		SharedDrives(10002).
+		Return(&map[string]metalcloud.SharedDrive{}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"format":                     "json",
+	})
+
+	ret, err := infrastructureTreeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var tree infrastructureTree
+	err = json.Unmarshal([]byte(ret), &tree)
+	Expect(err).To(BeNil())
+
+	Expect(tree.InstanceArrays).To(HaveLen(1))
+	Expect(tree.InstanceArrays[0].Instances).To(HaveLen(2))
+	Expect(tree.InstanceArrays[0].Instances[0].PowerStatus).To(Equal("on"))
+	Expect(tree.InstanceArrays[0].Instances[0].ServerID).To(Equal(300))
+	Expect(tree.InstanceArrays[0].Instances[0].Drives[0].ID).To(Equal(100))
+	Expect(tree.InstanceArrays[0].DriveArrays[0].ID).To(Equal(12))
+	Expect(tree.InstanceArrays[0].Networks[0].Label).To(Equal("wan"))
+	Expect(tree.DriveArrays).To(HaveLen(0))
+	Expect(tree.Networks).To(HaveLen(1))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+	})
+
+	ret, err = infrastructureTreeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("testia"))
+	Expect(ret).To(ContainSubstring("#300"))
+}
+
+func TestInfrastructurePowerControlCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10,
+		InfrastructureLabel: "test",
+	}
+
+	client.EXPECT().
+		InfrastructureGet(10).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrays(10).
+		Return(&map[string]metalcloud.InstanceArray{
+			"ia1": {InstanceArrayID: 100, InstanceArrayLabel: "ia1"},
+			"ia2": {InstanceArrayID: 101, InstanceArrayLabel: "ia2"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(100).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 201, ServerID: 1, InstanceServiceStatus: "active"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(101).
+		Return(&map[string]metalcloud.Instance{
+			"i2": {InstanceID: 202, ServerID: 2, InstanceServiceStatus: "active"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceServerPowerSet(gomock.Any(), "on").
+		Return(nil).
+		Times(2)
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(10, []int{201, 202}).
+		Return(&map[string]string{"201": "on", "202": "on"}, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10",
+		"operation":                  "on",
+		"autoconfirm":                true,
+	})
+
+	ret, err := infrastructurePowerControlCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("on: 2"))
+}
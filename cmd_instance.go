@@ -4,10 +4,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
+	"golang.org/x/crypto/ssh"
 )
 
 //instanceCmds commands affecting instances
@@ -81,6 +84,31 @@ var instanceCmds = []Command{
 		ExecuteFunc: instanceEditCmd,
 		Endpoint:    DeveloperEndpoint,
 	},
+	{
+		Description:  "Open an ssh session or run a command on an instance",
+		Subject:      "instance",
+		AltSubject:   "instance",
+		Predicate:    "ssh",
+		AltPredicate: "ssh",
+		FlagSet:      flag.NewFlagSet("instance ssh", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_id":              c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Instance's id."),
+				"ssh_user":                 c.FlagSet.String("user", _nilDefaultStr, "The user to login as. Defaults to the instance's initial user."),
+				"ssh_port":                 c.FlagSet.Int("port", _nilDefaultInt, "The ssh port. Defaults to the instance's ssh port."),
+				"identity_file":            c.FlagSet.String("identity-file", _nilDefaultStr, "Private key file to authenticate with. Defaults to the keys in ~/.ssh and the instance's initial password."),
+				"known_hosts_file":         c.FlagSet.String("known-hosts-file", _nilDefaultStr, "The known_hosts file the host key is verified against. Defaults to ~/.ssh/known_hosts."),
+				"accept_new_host_key":      c.FlagSet.Bool("accept-new-host-key", false, green("(Flag)")+" If set an unknown host key is added to the known_hosts file. The initial password is not sent to the host until its key is known."),
+				"insecure_ignore_host_key": c.FlagSet.Bool("insecure-ignore-host-key", false, green("(Flag)")+" If set the host key is not verified. The initial password is never sent in this case."),
+			}
+		},
+		ExecuteFunc: instanceSSHCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli instance ssh --id 100 #opens an interactive session
+metalcloud-cli instance ssh --id 100 --identity-file ~/.ssh/deploy_key -- uptime
+`,
+	},
 }
 
 func instancePowerControlCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -479,3 +507,392 @@ func instanceServerReplaceCmd(c *Command, client metalcloud.MetalCloudClient) (s
 
 	return "", err
 }
+
+// powerControlTarget is an instance affected by a bulk power control operation
+type powerControlTarget struct {
+	Instance      metalcloud.Instance
+	InstanceArray metalcloud.InstanceArray
+}
+
+// getPowerControlTargets returns the instances of the given instance arrays that have a server allocated, ordered by id
+func getPowerControlTargets(instanceArrays []metalcloud.InstanceArray, client metalcloud.MetalCloudClient) ([]powerControlTarget, error) {
+
+	targets := []powerControlTarget{}
+
+	for _, ia := range instanceArrays {
+		instances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range *instances {
+			if i.ServerID == 0 || i.InstanceServiceStatus == "deleted" {
+				continue
+			}
+			targets = append(targets, powerControlTarget{
+				Instance:      i,
+				InstanceArray: ia,
+			})
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Instance.InstanceID < targets[j].Instance.InstanceID })
+
+	return targets, nil
+}
+
+func getPowerControlOperationDescription(operation string) (string, error) {
+	switch operation {
+	case "on":
+		return "Turning on", nil
+	case "off":
+		return "Turning off (hard)", nil
+	case "reset":
+		return "Rebooting", nil
+	case "soft":
+		return "Shutting down", nil
+	}
+	return "", fmt.Errorf("-operation must be one of: on, off, reset, soft")
+}
+
+// powerControlBatches splits the targets into batches of batchSize. A batchSize <= 0 means a single batch.
+func powerControlBatches(targets []powerControlTarget, batchSize int) [][]powerControlTarget {
+
+	if batchSize <= 0 || batchSize > len(targets) {
+		batchSize = len(targets)
+	}
+
+	batches := [][]powerControlTarget{}
+	for start := 0; start < len(targets); start += batchSize {
+		end := start + batchSize
+		if end > len(targets) {
+			end = len(targets)
+		}
+		batches = append(batches, targets[start:end])
+	}
+
+	return batches
+}
+
+// getPowerControlExpectedStatus returns the power status an instance reaches after the operation.
+// A reset keeps reporting the server as powered on, so there is no status to wait for and false is returned.
+func getPowerControlExpectedStatus(operation string) (string, bool) {
+	switch operation {
+	case "on":
+		return "on", true
+	case "off", "soft":
+		return "off", true
+	}
+	return "", false
+}
+
+// powerStatusCheckInterval is the time between power status checks while waiting for a batch in rolling mode
+var powerStatusCheckInterval = 10 * time.Second
+
+// waitForPowerStatus polls the power status of the batch until all its instances reach the expected status.
+// It returns the instances that did not reach it before the timeout.
+func waitForPowerStatus(infraID int, batch []powerControlTarget, expected string, timeout int, client metalcloud.MetalCloudClient) ([]int, error) {
+
+	instanceIDs := []int{}
+	for _, t := range batch {
+		instanceIDs = append(instanceIDs, t.Instance.InstanceID)
+	}
+
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+
+	for {
+		pending := instanceIDs
+
+		powerStatus, err := client.InstanceServerPowerGetBatch(infraID, instanceIDs)
+		if err == nil {
+			pending = []int{}
+			for _, id := range instanceIDs {
+				if (*powerStatus)[fmt.Sprintf("%d", id)] != expected {
+					pending = append(pending, id)
+				}
+			}
+			if len(pending) == 0 {
+				return pending, nil
+			}
+		}
+
+		if !time.Now().Add(powerStatusCheckInterval).Before(deadline) {
+			return pending, err
+		}
+
+		time.Sleep(powerStatusCheckInterval)
+	}
+}
+
+// bulkPowerControl applies a power operation on multiple instances of an infrastructure after a single confirmation.
+// In rolling mode (batch_size set) the instances are processed in batches. Except for reset, a batch starts only after
+// the instances of the previous one reached the expected power status. The operation stops at the first batch with failures.
+func bulkPowerControl(infra metalcloud.Infrastructure, targets []powerControlTarget, scope string, c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	operation, ok := getStringParamOk(c.Arguments["operation"])
+	if !ok {
+		return "", fmt.Errorf("-operation is required (one of: on, off, reset, soft)")
+	}
+
+	op, err := getPowerControlOperationDescription(operation)
+	if err != nil {
+		return "", err
+	}
+
+	if len(targets) == 0 {
+		return "", fmt.Errorf("%s has no instances with allocated servers", scope)
+	}
+
+	batchSize := getIntParam(c.Arguments["batch_size"])
+	waitBetween := getIntParam(c.Arguments["wait_between"])
+
+	batchTimeout := 600
+	if v, ok := getIntParamOk(c.Arguments["batch_timeout"]); ok {
+		batchTimeout = v
+	}
+
+	concurrency := 5
+	if v, ok := getIntParamOk(c.Arguments["concurrency"]); ok {
+		concurrency = v
+	}
+
+	batches := powerControlBatches(targets, batchSize)
+
+	confirm, err := confirmCommand(c, func() string {
+
+		labels := []string{}
+		for _, t := range targets {
+			labels = append(labels, fmt.Sprintf("%s (#%d)", t.Instance.InstanceLabel, t.Instance.InstanceID))
+		}
+
+		confirmationMessage := fmt.Sprintf("%s %d instances of %s of infrastructure %s (#%d): %s.",
+			op,
+			len(targets),
+			scope,
+			infra.InfrastructureLabel,
+			infra.InfrastructureID,
+			strings.Join(labels, ", "),
+		)
+
+		if len(batches) > 1 {
+			confirmationMessage = fmt.Sprintf("%s The operation will be done in %d batches of %d instances, waiting %d seconds between batches.",
+				confirmationMessage, len(batches), len(batches[0]), waitBetween)
+
+			if expected, ok := getPowerControlExpectedStatus(operation); ok {
+				confirmationMessage = fmt.Sprintf("%s Each batch starts after the previous one is powered %s.", confirmationMessage, expected)
+			}
+		}
+
+		confirmationMessage += "  Are you sure? Type \"yes\" to continue:"
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	results := map[int]string{}
+	succeeded := 0
+	failed := 0
+
+	for b, batch := range batches {
+
+		if b > 0 && waitBetween > 0 {
+			time.Sleep(time.Duration(waitBetween) * time.Second)
+		}
+
+		if len(batches) > 1 {
+			fmt.Fprintf(GetStdout(), "Batch %d/%d: %s %d instances\n", b+1, len(batches), strings.ToLower(op), len(batch))
+		}
+
+		errs := runConcurrently(len(batch), concurrency, func(i int) error {
+			return client.InstanceServerPowerSet(batch[i].Instance.InstanceID, operation)
+		})
+
+		batchFailed := 0
+		for i, t := range batch {
+			if errs[i] != nil {
+				results[t.Instance.InstanceID] = red(errs[i].Error())
+				batchFailed++
+			} else {
+				results[t.Instance.InstanceID] = green("ok")
+				succeeded++
+			}
+		}
+
+		if expected, ok := getPowerControlExpectedStatus(operation); ok && batchFailed == 0 && b < len(batches)-1 {
+			pending, err := waitForPowerStatus(infra.InfrastructureID, batch, expected, batchTimeout, client)
+
+			reason := fmt.Sprintf("not powered %s after %d seconds", expected, batchTimeout)
+			if err != nil {
+				reason = fmt.Sprintf("%s: %v", reason, err)
+			}
+
+			for _, id := range pending {
+				results[id] = red(reason)
+				succeeded--
+				batchFailed++
+			}
+		}
+
+		failed += batchFailed
+
+		if batchFailed > 0 && b < len(batches)-1 {
+			for _, nextBatch := range batches[b+1:] {
+				for _, t := range nextBatch {
+					results[t.Instance.InstanceID] = yellow("skipped")
+				}
+			}
+			break
+		}
+	}
+
+	instanceIDs := []int{}
+	for _, t := range targets {
+		instanceIDs = append(instanceIDs, t.Instance.InstanceID)
+	}
+
+	//the results of the operation are shown even if the power status cannot be read
+	powerStatus, statusErr := client.InstanceServerPowerGetBatch(infra.InfrastructureID, instanceIDs)
+	if statusErr != nil {
+		powerStatus = &map[string]string{}
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "INSTANCE ARRAY",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "POWER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 6,
+		},
+	}
+
+	data := [][]interface{}{}
+	powerSummary := map[string]int{}
+	for _, t := range targets {
+		power, ok := (*powerStatus)[fmt.Sprintf("%d", t.Instance.InstanceID)]
+		if ok {
+			powerSummary[power]++
+		}
+
+		data = append(data, []interface{}{
+			t.Instance.InstanceID,
+			t.Instance.InstanceLabel,
+			fmt.Sprintf("%s (#%d)", t.InstanceArray.InstanceArrayLabel, t.InstanceArray.InstanceArrayID),
+			results[t.Instance.InstanceID],
+			colorizePowerStatus(power),
+		})
+	}
+
+	summary := []string{}
+	for _, status := range []string{"on", "off", "unknown"} {
+		if n, ok := powerSummary[status]; ok {
+			summary = append(summary, fmt.Sprintf("%s: %d", status, n))
+			delete(powerSummary, status)
+		}
+	}
+	others := []string{}
+	for status := range powerSummary {
+		others = append(others, status)
+	}
+	sort.Strings(others)
+	for _, status := range others {
+		summary = append(summary, fmt.Sprintf("%s: %d", status, powerSummary[status]))
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	title := fmt.Sprintf("Power %s on %d instances: %d succeeded %d failed", operation, len(targets), succeeded, failed)
+	subtitle := fmt.Sprintf("Power status %s", strings.Join(summary, ", "))
+	if statusErr != nil {
+		subtitle = "Power status could not be read"
+	}
+
+	ret, err := table.RenderTable(title, subtitle, getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if statusErr != nil {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("power %s was applied but the power status could not be read: %v", operation, statusErr)
+	}
+
+	return ret, nil
+}
+
+// getRemoteCommand returns the arguments that follow the command's flags (after --) as a single command line
+func getRemoteCommand(c *Command) string {
+	if c.FlagSet == nil {
+		return ""
+	}
+	return strings.Join(c.FlagSet.Args(), " ")
+}
+
+func instanceSSHCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	instanceID, ok := getIntParamOk(c.Arguments["instance_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required (instance id)")
+	}
+
+	instance, err := client.InstanceGet(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := getInstanceSSHTarget(*instance, c)
+	if err != nil {
+		return "", err
+	}
+
+	config, cleanup, err := getSSHClientConfig(target, c)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	command := getRemoteCommand(c)
+
+	if command == "" {
+		return "", sshInteractive(target, config)
+	}
+
+	err = sshRun(target, config, command, GetStdout(), os.Stderr)
+	if exitErr, ok := err.(*ssh.ExitError); ok {
+		return "", fmt.Errorf("command exited with status %d", exitErr.ExitStatus())
+	}
+
+	return "", err
+}
@@ -4,11 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
+	"golang.org/x/crypto/ssh"
 )
 
 //instanceArrayCmds commands affecting instance arrays
@@ -23,16 +27,16 @@ var instanceArrayCmds = []Command{
 		FlagSet:      flag.NewFlagSet("instance-array", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"infrastructure_id_or_label":    c.FlagSet.String("infra", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
-				"instance_array_instance_count": c.FlagSet.Int("instance-count", 1, " Instance count of this instance array"),
-				"instance_array_label":          c.FlagSet.String("label", _nilDefaultStr, "InstanceArray's label"),
-				"server_type":                   c.FlagSet.String("server-type", _nilDefaultStr, "InstanceArray's server type."),
-				//	"instance_array_ram_gbytes":           c.FlagSet.Int("ram", _nilDefaultInt, "InstanceArray's minimum RAM (GB)"),
-				//	"instance_array_processor_count":      c.FlagSet.Int("proc", _nilDefaultInt, "InstanceArray's minimum processor count"),
-				//	"instance_array_processor_core_mhz":   c.FlagSet.Int("proc-freq", _nilDefaultInt, "InstanceArray's minimum processor frequency (Mhz)"),
-				//	"instance_array_processor_core_count": c.FlagSet.Int("proc-core-count", _nilDefaultInt, "InstanceArray's minimum processor core count"),
-				//	"instance_array_disk_count":           c.FlagSet.Int("disks", _nilDefaultInt, "InstanceArray's number of local drives"),
-				//	"instance_array_disk_size_mbytes":     c.FlagSet.Int("disk-size", _nilDefaultInt, "InstanceArray's local disks' size in MB"),
+				"infrastructure_id_or_label":          c.FlagSet.String("infra", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
+				"instance_array_instance_count":       c.FlagSet.Int("instance-count", 1, " Instance count of this instance array"),
+				"instance_array_label":                c.FlagSet.String("label", _nilDefaultStr, "InstanceArray's label"),
+				"server_type":                         c.FlagSet.String("server-type", _nilDefaultStr, "InstanceArray's server type."),
+				"instance_array_ram_gbytes":           c.FlagSet.Int("ram", _nilDefaultInt, "InstanceArray's minimum RAM (GB)"),
+				"instance_array_processor_count":      c.FlagSet.Int("proc", _nilDefaultInt, "InstanceArray's minimum processor count"),
+				"instance_array_processor_core_mhz":   c.FlagSet.Int("proc-freq", _nilDefaultInt, "InstanceArray's minimum processor frequency (Mhz)"),
+				"instance_array_processor_core_count": c.FlagSet.Int("proc-core-count", _nilDefaultInt, "InstanceArray's minimum processor core count"),
+				"instance_array_disk_count":           c.FlagSet.Int("disks", _nilDefaultInt, "InstanceArray's number of local drives"),
+				"instance_array_disk_size_mbytes":     c.FlagSet.Int("disk-size", _nilDefaultInt, "InstanceArray's local disks' size in MB"),
 				"instance_array_boot_method":          c.FlagSet.String("boot", _nilDefaultStr, "InstanceArray's boot type:'pxe_iscsi','local_drives'"),
 				"instance_array_firewall_not_managed": c.FlagSet.Bool("firewall-management-disabled", false, green("(Flag)")+" If set InstanceArray's firewall management on or off"),
 				"volume_template_id_or_label":         c.FlagSet.String("local-install-template", _nilDefaultStr, "InstanceArray's volume template when booting from for local drives"),
@@ -40,6 +44,7 @@ var instanceArrayCmds = []Command{
 				"da_volume_disk_size":                 c.FlagSet.Int("drive-array-disk-size", _nilDefaultInt, "The attached DriveArray's  volume size (in MB) when booting from iscsi drives, If ommited the default size of the volume template will be used."),
 				"custom_variables":                    c.FlagSet.String("custom-variables", _nilDefaultStr, "Comma separated list of custom variables such as 'var1=value,var2=value'. If special characters need to be set use urlencode and pass the encoded string"),
 				"return_id":                           c.FlagSet.Bool("return-id", false, green("(Flag)")+" If set will print the ID of the created Instance Array. Useful for automating tasks."),
+				"dry_run":                             c.FlagSet.Bool("dry-run", false, green("(Flag)")+" If set will only show the server types matching the hardware configuration without creating the Instance Array."),
 			}
 		},
 		ExecuteFunc: instanceArrayCreateCmd,
@@ -142,6 +147,203 @@ var instanceArrayCmds = []Command{
 		ExecuteFunc: instanceArrayGetCmd,
 		Endpoint:    UserEndpoint,
 	},
+	{
+		Description:  "Start instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "start",
+		AltPredicate: "start",
+		FlagSet:      flag.NewFlagSet("start instance array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"deploy":                         c.FlagSet.Bool("deploy", false, green("(Flag)")+" If set the infrastructure will be deployed after the change."),
+				"allow_data_loss":                c.FlagSet.Bool("allow-data-loss", false, green("(Flag)")+" If set, deploy will not throw error if data loss is expected."),
+				"no_hard_shutdown_after_timeout": c.FlagSet.Bool("no-hard-shutdown-after-timeout", false, green("(Flag)")+" If set do not force a hard power off after timeout expired and the server is not powered off."),
+				"no_attempt_soft_shutdown":       c.FlagSet.Bool("no-attempt-soft-shutdown", false, green("(Flag)")+" If set,do not atempt a soft (ACPI) power off of all the servers in the infrastructure before the deploy"),
+				"soft_shutdown_timeout_seconds":  c.FlagSet.Int("soft-shutdown-timeout-seconds", 180, "(Optional, default 180) Timeout to wait if hard_shutdown_after_timeout is set."),
+				"skip_ansible":                   c.FlagSet.Bool("skip-ansible", false, green("(Flag)")+" If set, some automatic provisioning steps will be skipped. This parameter should generally be ignored."),
+				"window":                         c.FlagSet.String("window", _nilDefaultStr, "Maintenance window policy file (json or yaml). Deploys with allow-data-loss or hard shutdown are refused outside the windows defined in it."),
+				"block_until_deployed":           c.FlagSet.Bool("blocking", false, green("(Flag)")+" If set, the operation will wait until deployment finishes."),
+				"block_timeout":                  c.FlagSet.Int("block-timeout", 180*60, "Block timeout in seconds. After this timeout the application will return an error. Defaults to 180 minutes."),
+				"block_check_interval":           c.FlagSet.Int("block-check-interval", 10, "Check interval for when blocking. Defaults to 10 seconds."),
+				"autoconfirm":                    c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayStartCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Stop instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "stop",
+		AltPredicate: "stop",
+		FlagSet:      flag.NewFlagSet("stop instance array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"deploy":                         c.FlagSet.Bool("deploy", false, green("(Flag)")+" If set the infrastructure will be deployed after the change."),
+				"allow_data_loss":                c.FlagSet.Bool("allow-data-loss", false, green("(Flag)")+" If set, deploy will not throw error if data loss is expected."),
+				"no_hard_shutdown_after_timeout": c.FlagSet.Bool("no-hard-shutdown-after-timeout", false, green("(Flag)")+" If set do not force a hard power off after timeout expired and the server is not powered off."),
+				"no_attempt_soft_shutdown":       c.FlagSet.Bool("no-attempt-soft-shutdown", false, green("(Flag)")+" If set,do not atempt a soft (ACPI) power off of all the servers in the infrastructure before the deploy"),
+				"soft_shutdown_timeout_seconds":  c.FlagSet.Int("soft-shutdown-timeout-seconds", 180, "(Optional, default 180) Timeout to wait if hard_shutdown_after_timeout is set."),
+				"skip_ansible":                   c.FlagSet.Bool("skip-ansible", false, green("(Flag)")+" If set, some automatic provisioning steps will be skipped. This parameter should generally be ignored."),
+				"window":                         c.FlagSet.String("window", _nilDefaultStr, "Maintenance window policy file (json or yaml). Deploys with allow-data-loss or hard shutdown are refused outside the windows defined in it."),
+				"block_until_deployed":           c.FlagSet.Bool("blocking", false, green("(Flag)")+" If set, the operation will wait until deployment finishes."),
+				"block_timeout":                  c.FlagSet.Int("block-timeout", 180*60, "Block timeout in seconds. After this timeout the application will return an error. Defaults to 180 minutes."),
+				"block_check_interval":           c.FlagSet.Int("block-check-interval", 10, "Check interval for when blocking. Defaults to 10 seconds."),
+				"autoconfirm":                    c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayStopCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Scale instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "scale",
+		AltPredicate: "resize",
+		FlagSet:      flag.NewFlagSet("scale instance array", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label":     c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"count":                          c.FlagSet.String("count", _nilDefaultStr, red("(Required)")+" The new instance count. Use +N or -N to add or remove N instances."),
+				"no_bKeepDetachingDrives":        c.FlagSet.Bool("do-not-keep-detaching-drives", false, green("(Flag)")+" If set and the number of Instance objects is reduced, then the detaching Drive objects will be deleted. If it's set to true, the detaching Drive objects will not be deleted."),
+				"deploy":                         c.FlagSet.Bool("deploy", false, green("(Flag)")+" If set the infrastructure will be deployed after the change."),
+				"allow_data_loss":                c.FlagSet.Bool("allow-data-loss", false, green("(Flag)")+" If set, deploy will not throw error if data loss is expected."),
+				"no_hard_shutdown_after_timeout": c.FlagSet.Bool("no-hard-shutdown-after-timeout", false, green("(Flag)")+" If set do not force a hard power off after timeout expired and the server is not powered off."),
+				"no_attempt_soft_shutdown":       c.FlagSet.Bool("no-attempt-soft-shutdown", false, green("(Flag)")+" If set,do not atempt a soft (ACPI) power off of all the servers in the infrastructure before the deploy"),
+				"soft_shutdown_timeout_seconds":  c.FlagSet.Int("soft-shutdown-timeout-seconds", 180, "(Optional, default 180) Timeout to wait if hard_shutdown_after_timeout is set."),
+				"skip_ansible":                   c.FlagSet.Bool("skip-ansible", false, green("(Flag)")+" If set, some automatic provisioning steps will be skipped. This parameter should generally be ignored."),
+				"window":                         c.FlagSet.String("window", _nilDefaultStr, "Maintenance window policy file (json or yaml). Deploys with allow-data-loss or hard shutdown are refused outside the windows defined in it."),
+				"block_until_deployed":           c.FlagSet.Bool("blocking", false, green("(Flag)")+" If set, the operation will wait until deployment finishes."),
+				"block_timeout":                  c.FlagSet.Int("block-timeout", 180*60, "Block timeout in seconds. After this timeout the application will return an error. Defaults to 180 minutes."),
+				"block_check_interval":           c.FlagSet.Int("block-check-interval", 10, "Check interval for when blocking. Defaults to 10 seconds."),
+				"autoconfirm":                    c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayScaleCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli instance-array scale --id 100 --count +2 --deploy --blocking
+metalcloud-cli instance-array scale --id 100 --count 3 --do-not-keep-detaching-drives
+`,
+	},
+	{
+		Description:  "Control power for all the instances of an instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "power-control",
+		AltPredicate: "pwr",
+		FlagSet:      flag.NewFlagSet("instance_array power control", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"operation":                  c.FlagSet.String("operation", _nilDefaultStr, red("(Required)")+" Power control operation, one of: on, off, reset, soft"),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "Maximum number of instances on which the operation is executed at the same time."),
+				"batch_size":                 c.FlagSet.Int("batch-size", _nilDefaultInt, "If set, the instances are processed in batches of this size (rolling mode). Except for reset, a batch starts after the instances of the previous one reached the expected power status. The operation stops at the first batch with failures."),
+				"batch_timeout":              c.FlagSet.Int("batch-timeout", 600, "Seconds to wait in rolling mode for the instances of a batch to reach the expected power status. Defaults to 10 minutes."),
+				"wait_between":               c.FlagSet.Int("wait-between", _nilDefaultInt, "Additional seconds to wait between batches in rolling mode, after the instances of a batch reached the expected power status. For reset this is the only wait between batches."),
+				"format":                     c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayPowerControlCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli instance-array power-control --id 100 --operation reset
+metalcloud-cli instance-array power-control --id 100 --operation reset --batch-size 2 --wait-between 300 #reboot 2 instances at a time
+`,
+	},
+	{
+		Description:  "Run a command on all the instances of an instance array.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "exec",
+		AltPredicate: "run",
+		FlagSet:      flag.NewFlagSet("instance_array exec", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"ssh_user":                   c.FlagSet.String("user", _nilDefaultStr, "The user to login as. Defaults to each instance's initial user."),
+				"ssh_port":                   c.FlagSet.Int("port", _nilDefaultInt, "The ssh port. Defaults to each instance's ssh port."),
+				"identity_file":              c.FlagSet.String("identity-file", _nilDefaultStr, "Private key file to authenticate with. Defaults to the keys in ~/.ssh and each instance's initial password."),
+				"known_hosts_file":           c.FlagSet.String("known-hosts-file", _nilDefaultStr, "The known_hosts file the host keys are verified against. Defaults to ~/.ssh/known_hosts."),
+				"accept_new_host_key":        c.FlagSet.Bool("accept-new-host-key", false, green("(Flag)")+" If set unknown host keys are added to the known_hosts file. The initial password is not sent to a host until its key is known."),
+				"insecure_ignore_host_key":   c.FlagSet.Bool("insecure-ignore-host-key", false, green("(Flag)")+" If set the host keys are not verified. The initial passwords are never sent in this case."),
+				"concurrency":                c.FlagSet.Int("concurrency", 10, "Maximum number of instances on which the command runs at the same time."),
+			}
+		},
+		ExecuteFunc: instanceArrayExecCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli instance-array exec --id 100 -- systemctl restart nginx
+`,
+	},
+	{
+		Description:  "Attach an instance array interface to a network.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "interface-attach",
+		AltPredicate: "if-attach",
+		FlagSet:      flag.NewFlagSet("instance_array interface attach", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"interface_index":            c.FlagSet.Int("interface-index", _nilDefaultInt, red("(Required)")+" The interface's port number as shown by 'network list', starting from 1."),
+				"network_id_or_label":        c.FlagSet.String("network", _nilDefaultStr, red("(Required)")+" Network's id or label. Note that the label can be ambigous."),
+			}
+		},
+		ExecuteFunc: instanceArrayInterfaceAttachCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli instance-array interface-attach --ia 100 --interface-index 2 --network 200
+`,
+	},
+	{
+		Description:  "Detach an instance array interface from its network.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "interface-detach",
+		AltPredicate: "if-detach",
+		FlagSet:      flag.NewFlagSet("instance_array interface detach", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"interface_index":            c.FlagSet.Int("interface-index", _nilDefaultInt, red("(Required)")+" The interface's port number as shown by 'network list', starting from 1."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayInterfaceDetachCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Replace the servers of all the instances of an instance array with servers of another type.",
+		Subject:      "instance-array",
+		AltSubject:   "ia",
+		Predicate:    "server-replace",
+		AltPredicate: "server-change",
+		FlagSet:      flag.NewFlagSet("instance_array server replace", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"instance_array_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"server_type":                c.FlagSet.String("server-type", _nilDefaultStr, red("(Required)")+" The id or label of the server type of the new servers."),
+				"batch_size":                 c.FlagSet.Int("batch-size", 1, "Number of instances whose server is replaced at the same time."),
+				"block_timeout":              c.FlagSet.Int("block-timeout", 180*60, "Timeout in seconds for each batch. Defaults to 180 minutes."),
+				"block_check_interval":       c.FlagSet.Int("block-check-interval", 10, "Check interval for the replacement jobs. Defaults to 10 seconds."),
+				"restart":                    c.FlagSet.Bool("restart", false, green("(Flag)")+" If set the state of a previous failed or interrupted replacement is discarded instead of resumed."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: instanceArrayServerReplaceCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli instance-array server-replace --id 100 --server-type M.16.16.2
+metalcloud-cli instance-array server-replace --id 100 --server-type M.16.16.2 --batch-size 2 #replace 2 servers at a time
+`,
+	},
 }
 
 func instanceArrayCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -160,6 +362,36 @@ func instanceArrayCreateCmd(c *Command, client metalcloud.MetalCloudClient) (str
 		return "", fmt.Errorf("-label is required")
 	}
 
+	_, serverTypeSet := getStringParamOk(c.Arguments["server_type"])
+	dryRun := getBoolParam(c.Arguments["dry_run"])
+
+	if hw, ok := getHardwareConfigurationFromInstanceArray(*ia); ok && !serverTypeSet {
+		matches, err := getServerTypesMatchingHardwareConfiguration(*infra, hw, client)
+		if err != nil {
+			return "", err
+		}
+
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no server type in datacenter %s matches the requested hardware configuration", infra.DatacenterName)
+		}
+
+		ret, err := renderServerTypeMatches(matches, infra.DatacenterName, ia.InstanceArrayInstanceCount)
+		if err != nil {
+			return "", err
+		}
+
+		if dryRun {
+			return ret, nil
+		}
+
+		//the output of return_id must only contain the ID
+		if !getBoolParam(c.Arguments["return_id"]) {
+			fmt.Fprint(GetStdout(), ret)
+		}
+	} else if dryRun {
+		return "", fmt.Errorf("-dry-run requires a hardware configuration (-ram, -proc, -proc-freq, -proc-core-count, -disks or -disk-size)")
+	}
+
 	retIA, err := client.InstanceArrayCreate(infra.InfrastructureID, *ia)
 	if err != nil {
 		return "", err
@@ -721,150 +953,482 @@ func instanceArrayInstancesListCmd(c *Command, client metalcloud.MetalCloudClien
 	return table.RenderTable("Instances", subtitle, getStringParam(c.Arguments["format"]))
 }
 
-func argsToInstanceArray(m map[string]interface{}, c *Command, client metalcloud.MetalCloudClient) (*metalcloud.InstanceArray, error) {
-	ia := metalcloud.InstanceArray{}
+func instanceArrayPowerControlCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
-	if v, ok := getIntParamOk(m["instance_array_instance_count"]); ok {
-		ia.InstanceArrayInstanceCount = v
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getStringParamOk(m["instance_array_label"]); ok {
-		ia.InstanceArrayLabel = v
+	retInfra, err := client.InfrastructureGet(retIA.InfrastructureID)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_ram_gbytes"]); ok {
-		ia.InstanceArrayRAMGbytes = v
+	targets, err := getPowerControlTargets([]metalcloud.InstanceArray{*retIA}, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_processor_count"]); ok {
-		ia.InstanceArrayProcessorCount = v
-	}
+	scope := fmt.Sprintf("instance array %s (#%d)", retIA.InstanceArrayLabel, retIA.InstanceArrayID)
 
-	if v, ok := getIntParamOk(m["instance_array_processor_core_mhz"]); ok {
-		ia.InstanceArrayProcessorCoreMHZ = v
+	return bulkPowerControl(*retInfra, targets, scope, c, client)
+}
+
+func instanceArrayExecCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_processor_core_count"]); ok {
-		ia.InstanceArrayProcessorCoreCount = v
+	command := getRemoteCommand(c)
+	if command == "" {
+		return "", fmt.Errorf("a command is required after --")
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_disk_count"]); ok {
-		ia.InstanceArrayDiskCount = v
+	instances, err := client.InstanceArrayInstances(retIA.InstanceArrayID)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_disk_size_mbytes"]); ok {
-		ia.InstanceArrayDiskSizeMBytes = v
+	list := []metalcloud.Instance{}
+	for _, i := range *instances {
+		if i.ServerID == 0 || i.InstanceServiceStatus != "active" {
+			continue
+		}
+		list = append(list, i)
 	}
 
-	if v, ok := getStringParamOk(m["instance_array_boot_method"]); ok {
-		ia.InstanceArrayBootMethod = v
+	if len(list) == 0 {
+		return "", fmt.Errorf("instance array %s (#%d) has no active instances", retIA.InstanceArrayLabel, retIA.InstanceArrayID)
 	}
 
-	if v, ok := getBoolParamOk(m["instance_array_firewall_not_managed"]); ok {
-		ia.InstanceArrayFirewallManaged = !v
+	sort.Slice(list, func(i, j int) bool { return list[i].InstanceID < list[j].InstanceID })
+
+	concurrency := 10
+	if v, ok := getIntParamOk(c.Arguments["concurrency"]); ok {
+		concurrency = v
 	}
 
-	if v, ok := getStringParamOk(c.Arguments["volume_template_id_or_label"]); ok {
-		vtID, err := getIDOrDo(v, func(label string) (int, error) {
-			vt, err := client.VolumeTemplateGetByLabel(label)
-			if err != nil {
-				return 0, err
-			}
-			return vt.VolumeTemplateID, nil
-		},
-		)
+	var mu sync.Mutex
+
+	errs := runConcurrently(len(list), concurrency, func(i int) error {
+
+		target, err := getInstanceSSHTarget(list[i], c)
 		if err != nil {
-			ia.VolumeTemplateID = 0
+			return err
 		}
-		ia.VolumeTemplateID = vtID
-	}
 
-	if v, ok := getStringParamOk(c.Arguments["custom_variables"]); ok {
-
-		m, err := getKeyValueMapFromString(v)
+		config, cleanup, err := getSSHClientConfig(target, c)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		defer cleanup()
 
-		ia.InstanceArrayCustomVariables = m
+		prefix := fmt.Sprintf("[%s]", target.Label)
+		stdout := newPrefixWriter(prefix, GetStdout(), &mu)
+		stderr := newPrefixWriter(prefix, os.Stderr, &mu)
+
+		err = sshRun(target, config, command, stdout, stderr)
+
+		stdout.Flush()
+		stderr.Flush()
+
+		return err
+	})
+
+	failed := []string{}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			failed = append(failed, fmt.Sprintf("%s: exited with status %d", list[i].InstanceLabel, exitErr.ExitStatus()))
+		} else {
+			failed = append(failed, fmt.Sprintf("%s: %v", list[i].InstanceLabel, err))
+		}
 	}
 
-	return &ia, nil
+	if len(failed) > 0 {
+		return "", fmt.Errorf("command failed on %d of %d instances:\n%s\n", len(failed), len(list), strings.Join(failed, "\n"))
+	}
+
+	return fmt.Sprintf("Command succeeded on %d instances.\n", len(list)), nil
 }
 
-func argsToInstanceArrayOperation(m map[string]interface{}, iao *metalcloud.InstanceArrayOperation, c *Command, client metalcloud.MetalCloudClient) error {
-	if v, ok := getIntParamOk(m["instance_array_instance_count"]); ok {
-		iao.InstanceArrayInstanceCount = v
+func instanceArrayInterfaceAttachCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getStringParamOk(m["instance_array_label"]); ok {
-		iao.InstanceArrayLabel = v
+	iaInterface, err := getInstanceArrayInterfaceFromCommand(*retIA, c)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_ram_gbytes"]); ok {
-		iao.InstanceArrayRAMGbytes = v
+	retNetwork, err := getNetworkFromCommand("network", c, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_processor_count"]); ok {
-		iao.InstanceArrayProcessorCount = v
+	if retNetwork.InfrastructureID != retIA.InfrastructureID {
+		return "", fmt.Errorf("network %d belongs to infrastructure %d while instance array %d belongs to infrastructure %d. Use 'network join' to connect networks from different infrastructures",
+			retNetwork.NetworkID, retNetwork.InfrastructureID, retIA.InstanceArrayID, retIA.InfrastructureID)
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_processor_core_mhz"]); ok {
-		iao.InstanceArrayProcessorCoreMHZ = v
+	if iaInterface.NetworkID == retNetwork.NetworkID {
+		return "", fmt.Errorf("interface #%d of instance array %d is already attached to network %d", iaInterface.InstanceArrayInterfaceIndex+1, retIA.InstanceArrayID, retNetwork.NetworkID)
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_processor_core_count"]); ok {
-		iao.InstanceArrayProcessorCoreCount = v
+	_, err = client.InstanceArrayInterfaceAttachNetwork(retIA.InstanceArrayID, iaInterface.InstanceArrayInterfaceIndex, retNetwork.NetworkID)
+
+	return "", err
+}
+
+func instanceArrayInterfaceDetachCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_disk_count"]); ok {
-		iao.InstanceArrayDiskCount = v
+	iaInterface, err := getInstanceArrayInterfaceFromCommand(*retIA, c)
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getIntParamOk(m["instance_array_disk_size_mbytes"]); ok {
-		iao.InstanceArrayDiskSizeMBytes = v
+	if iaInterface.NetworkID == 0 {
+		return "", fmt.Errorf("interface #%d of instance array %d is not attached to any network", iaInterface.InstanceArrayInterfaceIndex+1, retIA.InstanceArrayID)
 	}
 
-	if v, ok := getStringParamOk(m["instance_array_boot_method"]); ok {
-		iao.InstanceArrayBootMethod = v
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Detaching interface #%d of instance array %s (%d) from network %d.  Are you sure? Type \"yes\" to continue:",
+			iaInterface.InstanceArrayInterfaceIndex+1,
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID,
+			iaInterface.NetworkID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
 	}
 
-	if v, ok := getBoolParamOk(m["instance_array_firewall_not_managed"]); ok {
-		iao.InstanceArrayFirewallManaged = !v
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
 	}
 
-	if v, ok := getStringParamOk(c.Arguments["volume_template_id_or_label"]); ok {
-		vtID, err := getIDOrDo(v, func(label string) (int, error) {
-			vt, err := client.VolumeTemplateGetByLabel(label)
-			if err != nil {
-				return 0, err
-			}
-			return vt.VolumeTemplateID, nil
-		},
-		)
-		if err != nil {
-			iao.VolumeTemplateID = 0
+	_, err = client.InstanceArrayInterfaceDetach(retIA.InstanceArrayID, iaInterface.InstanceArrayInterfaceIndex)
+
+	return "", err
+}
+
+//getInstanceArrayInterfaceFromCommand returns the interface of the instance array designated by the 1-based interface_index argument
+func getInstanceArrayInterfaceFromCommand(ia metalcloud.InstanceArray, c *Command) (*metalcloud.InstanceArrayInterface, error) {
+
+	index, ok := getIntParamOk(c.Arguments["interface_index"])
+	if !ok {
+		return nil, fmt.Errorf("-interface-index is required")
+	}
+
+	for _, iaInterface := range ia.InstanceArrayInterfaces {
+		if iaInterface.InstanceArrayInterfaceIndex+1 == index {
+			return &iaInterface, nil
 		}
-		iao.VolumeTemplateID = vtID
 	}
 
-	if v, ok := getStringParamOk(c.Arguments["custom_variables"]); ok {
+	return nil, fmt.Errorf("instance array %d has no interface #%d", ia.InstanceArrayID, index)
+}
 
-		m, err := getKeyValueMapFromString(v)
-		if err != nil {
-			return err
-		}
+// serverTypeMatch is a server type compatible with a hardware configuration along with the number of servers available for it
+type serverTypeMatch struct {
+	ServerType metalcloud.ServerType
+	Available  int
+}
 
-		iao.InstanceArrayCustomVariables = m
-	}
-	return nil
+// getHardwareConfigurationFromInstanceArray returns the hardware configuration requested for an instance array and false if none was set
+func getHardwareConfigurationFromInstanceArray(ia metalcloud.InstanceArray) (metalcloud.HardwareConfiguration, bool) {
+	hw := metalcloud.HardwareConfiguration{
+		InstanceArrayRAMGbytes:          ia.InstanceArrayRAMGbytes,
+		InstanceArrayProcessorCount:     ia.InstanceArrayProcessorCount,
+		InstanceArrayProcessorCoreMHZ:   ia.InstanceArrayProcessorCoreMHZ,
+		InstanceArrayProcessorCoreCount: ia.InstanceArrayProcessorCoreCount,
+		InstanceArrayDiskCount:          ia.InstanceArrayDiskCount,
+		InstanceArrayDiskSizeMBytes:     ia.InstanceArrayDiskSizeMBytes,
+		InstanceArrayInstanceCount:      ia.InstanceArrayInstanceCount,
+	}
+
+	ok := hw.InstanceArrayRAMGbytes != 0 ||
+		hw.InstanceArrayProcessorCount != 0 ||
+		hw.InstanceArrayProcessorCoreMHZ != 0 ||
+		hw.InstanceArrayProcessorCoreCount != 0 ||
+		hw.InstanceArrayDiskCount != 0 ||
+		hw.InstanceArrayDiskSizeMBytes != 0
+
+	return hw, ok
 }
 
-func copyInstanceArrayToOperation(ia metalcloud.InstanceArray, iao *metalcloud.InstanceArrayOperation) {
+// getServerTypesMatchingHardwareConfiguration returns the server types of the infrastructure's datacenter that satisfy the hardware configuration
+// along with the number of servers currently available for each of them
+func getServerTypesMatchingHardwareConfiguration(infra metalcloud.Infrastructure, hw metalcloud.HardwareConfiguration, client metalcloud.MetalCloudClient) ([]serverTypeMatch, error) {
+	compatible, err := client.ServerTypesMatchHardwareConfiguration(infra.DatacenterName, hw)
+	if err != nil {
+		return nil, err
+	}
 
-	iao.InstanceArrayID = ia.InstanceArrayID
-	iao.InstanceArrayLabel = ia.InstanceArrayLabel
-	iao.InstanceArrayBootMethod = ia.InstanceArrayBootMethod
+	available, err := client.ServerTypesMatches(infra.InfrastructureID, hw, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []serverTypeMatch{}
+	for _, st := range *compatible {
+		m := serverTypeMatch{ServerType: st}
+		if a, ok := (*available)[fmt.Sprintf("%d", st.ServerTypeID)]; ok {
+			m.Available = a.ServerCount
+		}
+		matches = append(matches, m)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Available != matches[j].Available {
+			return matches[i].Available > matches[j].Available
+		}
+		return matches[i].ServerType.ServerTypeID < matches[j].ServerType.ServerTypeID
+	})
+
+	return matches, nil
+}
+
+func renderServerTypeMatches(matches []serverTypeMatch, datacenterName string, instanceCount int) (string, error) {
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "CPU",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RAM (GB)",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "DISKS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "AVAILABLE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	totalAvailable := 0
+	for _, m := range matches {
+		st := m.ServerType
+
+		available := fmt.Sprintf("%d", m.Available)
+		if m.Available >= instanceCount {
+			available = green(available)
+		} else if m.Available > 0 {
+			available = yellow(available)
+		} else {
+			available = red(available)
+		}
+		totalAvailable += m.Available
+
+		data = append(data, []interface{}{
+			st.ServerTypeID,
+			st.ServerTypeLabel,
+			getServerTypeProcessorsString(st),
+			st.ServerRAMGbytes,
+			getServerTypeDisksString(st),
+			available,
+		})
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	title := fmt.Sprintf("Server types in datacenter %s matching the hardware configuration", datacenterName)
+	ret, err := table.RenderTable(title, "", "")
+	if err != nil {
+		return "", err
+	}
+
+	if totalAvailable < instanceCount {
+		ret += fmt.Sprintf("%s only %d servers are available for the %d requested instances. The deploy will fail unless more servers become available.\n", yellow("WARNING:"), totalAvailable, instanceCount)
+	}
+
+	return ret, nil
+}
+
+func argsToInstanceArray(m map[string]interface{}, c *Command, client metalcloud.MetalCloudClient) (*metalcloud.InstanceArray, error) {
+	ia := metalcloud.InstanceArray{}
+
+	if v, ok := getIntParamOk(m["instance_array_instance_count"]); ok {
+		ia.InstanceArrayInstanceCount = v
+	}
+
+	if v, ok := getStringParamOk(m["instance_array_label"]); ok {
+		ia.InstanceArrayLabel = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_ram_gbytes"]); ok {
+		ia.InstanceArrayRAMGbytes = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_count"]); ok {
+		ia.InstanceArrayProcessorCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_core_mhz"]); ok {
+		ia.InstanceArrayProcessorCoreMHZ = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_core_count"]); ok {
+		ia.InstanceArrayProcessorCoreCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_disk_count"]); ok {
+		ia.InstanceArrayDiskCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_disk_size_mbytes"]); ok {
+		ia.InstanceArrayDiskSizeMBytes = v
+	}
+
+	if v, ok := getStringParamOk(m["instance_array_boot_method"]); ok {
+		ia.InstanceArrayBootMethod = v
+	}
+
+	if v, ok := getBoolParamOk(m["instance_array_firewall_not_managed"]); ok {
+		ia.InstanceArrayFirewallManaged = !v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["volume_template_id_or_label"]); ok {
+		vtID, err := getIDOrDo(v, func(label string) (int, error) {
+			vt, err := client.VolumeTemplateGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return vt.VolumeTemplateID, nil
+		},
+		)
+		if err != nil {
+			ia.VolumeTemplateID = 0
+		}
+		ia.VolumeTemplateID = vtID
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["custom_variables"]); ok {
+
+		m, err := getKeyValueMapFromString(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ia.InstanceArrayCustomVariables = m
+	}
+
+	return &ia, nil
+}
+
+func argsToInstanceArrayOperation(m map[string]interface{}, iao *metalcloud.InstanceArrayOperation, c *Command, client metalcloud.MetalCloudClient) error {
+	if v, ok := getIntParamOk(m["instance_array_instance_count"]); ok {
+		iao.InstanceArrayInstanceCount = v
+	}
+
+	if v, ok := getStringParamOk(m["instance_array_label"]); ok {
+		iao.InstanceArrayLabel = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_ram_gbytes"]); ok {
+		iao.InstanceArrayRAMGbytes = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_count"]); ok {
+		iao.InstanceArrayProcessorCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_core_mhz"]); ok {
+		iao.InstanceArrayProcessorCoreMHZ = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_processor_core_count"]); ok {
+		iao.InstanceArrayProcessorCoreCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_disk_count"]); ok {
+		iao.InstanceArrayDiskCount = v
+	}
+
+	if v, ok := getIntParamOk(m["instance_array_disk_size_mbytes"]); ok {
+		iao.InstanceArrayDiskSizeMBytes = v
+	}
+
+	if v, ok := getStringParamOk(m["instance_array_boot_method"]); ok {
+		iao.InstanceArrayBootMethod = v
+	}
+
+	if v, ok := getBoolParamOk(m["instance_array_firewall_not_managed"]); ok {
+		iao.InstanceArrayFirewallManaged = !v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["volume_template_id_or_label"]); ok {
+		vtID, err := getIDOrDo(v, func(label string) (int, error) {
+			vt, err := client.VolumeTemplateGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return vt.VolumeTemplateID, nil
+		},
+		)
+		if err != nil {
+			iao.VolumeTemplateID = 0
+		}
+		iao.VolumeTemplateID = vtID
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["custom_variables"]); ok {
+
+		m, err := getKeyValueMapFromString(v)
+		if err != nil {
+			return err
+		}
+
+		iao.InstanceArrayCustomVariables = m
+	}
+	return nil
+}
+
+func copyInstanceArrayToOperation(ia metalcloud.InstanceArray, iao *metalcloud.InstanceArrayOperation) {
+
+	iao.InstanceArrayID = ia.InstanceArrayID
+	iao.InstanceArrayLabel = ia.InstanceArrayLabel
+	iao.InstanceArrayBootMethod = ia.InstanceArrayBootMethod
 	iao.InstanceArrayInstanceCount = ia.InstanceArrayInstanceCount
 	iao.InstanceArrayRAMGbytes = ia.InstanceArrayRAMGbytes
 	iao.InstanceArrayProcessorCount = ia.InstanceArrayProcessorCount
@@ -896,3 +1460,704 @@ func getInstanceArrayFromCommand(paramName string, c *Command, client metalcloud
 	}
 	return client.InstanceArrayGetByLabel(label)
 }
+
+// instanceArrayCheckDeployWindow refuses a requested deploy that is not allowed by the maintenance window policy.
+// It is called before the instance array is changed so that nothing is left undeployed.
+func instanceArrayCheckDeployWindow(c *Command) error {
+
+	if !getBoolParam(c.Arguments["deploy"]) {
+		return nil
+	}
+
+	windowFile, err := getMaintenanceWindowFileFromCommand(c)
+	if err != nil {
+		return err
+	}
+
+	return checkMaintenanceWindow(windowFile, time.Now(), getShutdownOptionsFromCommand(c), getBoolParam(c.Arguments["allow_data_loss"]))
+}
+
+// instanceArrayDeployIfRequested deploys the infrastructure of the instance array if the deploy flag is set
+func instanceArrayDeployIfRequested(infraID int, c *Command, client metalcloud.MetalCloudClient) error {
+
+	if !getBoolParam(c.Arguments["deploy"]) {
+		return nil
+	}
+
+	return infrastructureDeployAndBlock(infraID, getShutdownOptionsFromCommand(c), getBoolParam(c.Arguments["allow_data_loss"]), getBoolParam(c.Arguments["skip_ansible"]), c, client)
+}
+
+func instanceArrayStartCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if err := instanceArrayCheckDeployWindow(c); err != nil {
+		return "", err
+	}
+
+	_, err = client.InstanceArrayStart(retIA.InstanceArrayID)
+	if err != nil {
+		return "", err
+	}
+
+	return "", instanceArrayDeployIfRequested(retIA.InfrastructureID, c, client)
+}
+
+func instanceArrayStopCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if err := instanceArrayCheckDeployWindow(c); err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Stopping instance array %s (%d). All its instances will be powered off. Are you sure? Type \"yes\" to continue:",
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	_, err = client.InstanceArrayStop(retIA.InstanceArrayID)
+	if err != nil {
+		return "", err
+	}
+
+	return "", instanceArrayDeployIfRequested(retIA.InfrastructureID, c, client)
+}
+
+// getNewInstanceCount computes the new instance count from an absolute count (N) or a relative one (+N, -N)
+func getNewInstanceCount(current int, count string) (int, error) {
+
+	relative := strings.HasPrefix(count, "+") || strings.HasPrefix(count, "-")
+
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, fmt.Errorf("-count must be a number such as 3, +2 or -1")
+	}
+
+	if relative {
+		n = current + n
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("cannot scale to %d instances", n)
+	}
+
+	return n, nil
+}
+
+// getInstancesToRemove returns the instances that will be removed when scaling down, most recently created first
+func getInstancesToRemove(ia *metalcloud.InstanceArray, removeCount int, client metalcloud.MetalCloudClient) ([]metalcloud.Instance, error) {
+
+	instances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []metalcloud.Instance{}
+	for _, i := range *instances {
+		if i.InstanceServiceStatus == "deleted" || i.InstanceOperation.InstanceDeployType == "delete" {
+			continue
+		}
+		list = append(list, i)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].InstanceID > list[j].InstanceID })
+
+	if removeCount > len(list) {
+		removeCount = len(list)
+	}
+
+	return list[:removeCount], nil
+}
+
+// getInstanceArrayDrivesByInstance returns the drives of the drive arrays attached to an instance array, grouped by instance id
+func getInstanceArrayDrivesByInstance(ia *metalcloud.InstanceArray, client metalcloud.MetalCloudClient) (map[int][]metalcloud.Drive, error) {
+
+	drivesByInstance := map[int][]metalcloud.Drive{}
+
+	daList, err := client.DriveArrays(ia.InfrastructureID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, da := range *daList {
+		if da.InstanceArrayID != ia.InstanceArrayID {
+			continue
+		}
+
+		drives, err := client.DriveArrayDrives(da.DriveArrayID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range *drives {
+			drivesByInstance[d.InstanceID] = append(drivesByInstance[d.InstanceID], d)
+		}
+	}
+
+	return drivesByInstance, nil
+}
+
+func instanceArrayScaleCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if err := instanceArrayCheckDeployWindow(c); err != nil {
+		return "", err
+	}
+
+	count, ok := getStringParamOk(c.Arguments["count"])
+	if !ok {
+		return "", fmt.Errorf("-count is required")
+	}
+
+	current := retIA.InstanceArrayOperation.InstanceArrayInstanceCount
+
+	newCount, err := getNewInstanceCount(current, count)
+	if err != nil {
+		return "", err
+	}
+
+	if newCount == current {
+		return "", fmt.Errorf("instance array %s (%d) already has %d instances", retIA.InstanceArrayLabel, retIA.InstanceArrayID, current)
+	}
+
+	keepDetachingDrives := !getBoolParam(c.Arguments["no_bKeepDetachingDrives"])
+
+	toRemove := []metalcloud.Instance{}
+	drivesByInstance := map[int][]metalcloud.Drive{}
+	if newCount < current {
+		toRemove, err = getInstancesToRemove(retIA, current-newCount, client)
+		if err != nil {
+			return "", err
+		}
+
+		drivesByInstance, err = getInstanceArrayDrivesByInstance(retIA, client)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := ""
+
+		if len(toRemove) > 0 {
+
+			drivesAction := "deleted"
+			if keepDetachingDrives {
+				drivesAction = "detached and kept"
+			}
+
+			schema := []tableformatter.SchemaField{
+				{
+					FieldName: "ID",
+					FieldType: tableformatter.TypeInt,
+					FieldSize: 6,
+				},
+				{
+					FieldName: "LABEL",
+					FieldType: tableformatter.TypeString,
+					FieldSize: 15,
+				},
+				{
+					FieldName: "SERVER",
+					FieldType: tableformatter.TypeString,
+					FieldSize: 10,
+				},
+				{
+					FieldName: "DRIVES",
+					FieldType: tableformatter.TypeString,
+					FieldSize: 20,
+				},
+			}
+
+			data := [][]interface{}{}
+			for _, i := range toRemove {
+				drives := []string{}
+				for _, d := range drivesByInstance[i.InstanceID] {
+					drives = append(drives, fmt.Sprintf("%s (#%d) %s", d.DriveLabel, d.DriveID, drivesAction))
+				}
+
+				server := ""
+				if i.ServerID != 0 {
+					server = fmt.Sprintf("#%d", i.ServerID)
+				}
+
+				data = append(data, []interface{}{
+					i.InstanceID,
+					i.InstanceLabel,
+					server,
+					strings.Join(drives, ", "),
+				})
+			}
+
+			table := tableformatter.Table{
+				Data:   data,
+				Schema: schema,
+			}
+			tableStr, _ := table.RenderTable("Instances to be removed", "", "")
+
+			confirmationMessage = tableStr + "\n"
+		}
+
+		confirmationMessage = confirmationMessage + fmt.Sprintf("Scaling instance array %s (%d) from %d to %d instances. Are you sure? Type \"yes\" to continue:",
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID,
+			current, newCount)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	iao := *retIA.InstanceArrayOperation
+	iao.InstanceArrayInstanceCount = newCount
+
+	var instancesToBeDeleted *[]int
+	if len(toRemove) > 0 {
+		ids := []int{}
+		for _, i := range toRemove {
+			ids = append(ids, i.InstanceID)
+		}
+		instancesToBeDeleted = &ids
+	}
+
+	bFalse := false
+
+	_, err = client.InstanceArrayEdit(
+		retIA.InstanceArrayID,
+		iao,
+		&bFalse,
+		&keepDetachingDrives,
+		nil,
+		instancesToBeDeleted)
+	if err != nil {
+		return "", err
+	}
+
+	return "", instanceArrayDeployIfRequested(retIA.InfrastructureID, c, client)
+}
+
+// serverReplaceState is the local state of a rolling server replacement. It allows a failed or interrupted
+// replacement to be resumed by running the same command again. Replacements that failed are started again on resume
+// with another server, the servers they were using are kept in FailedServerIDs and never chosen again.
+type serverReplaceState struct {
+	InstanceArrayID int                      `json:"instance_array_id"`
+	ServerTypeID    int                      `json:"server_type_id"`
+	Instances       []serverReplaceStateItem `json:"instances"`
+	FailedServerIDs []int                    `json:"failed_server_ids,omitempty"`
+}
+
+// serverReplaceStateItem is the replacement of the server of a single instance.
+// Status is one of pending, running, done or failed.
+type serverReplaceStateItem struct {
+	InstanceID    int    `json:"instance_id"`
+	InstanceLabel string `json:"instance_label"`
+	OldServerID   int    `json:"old_server_id"`
+	NewServerID   int    `json:"new_server_id,omitempty"`
+	AFCGroupID    int    `json:"afc_group_id,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+func getServerReplaceStateFileName(instanceArrayID int) string {
+	return fmt.Sprintf("server_replace_%d.json", instanceArrayID)
+}
+
+func instanceArrayServerReplaceCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retIA, err := getInstanceArrayFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	serverType, err := getServerTypeFromCommand("server-type", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(retIA.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	batchSize := getIntParam(c.Arguments["batch_size"])
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	stateFile := getServerReplaceStateFileName(retIA.InstanceArrayID)
+
+	state := serverReplaceState{}
+	if !getBoolParam(c.Arguments["restart"]) {
+		if err := readLocalState(stateFile, &state); err != nil {
+			return "", err
+		}
+	}
+
+	resumed := len(state.Instances) > 0
+
+	if resumed && state.ServerTypeID != serverType.ServerTypeID {
+		return "", fmt.Errorf("a replacement of the servers of instance array %s (#%d) with server type #%d is in progress. Use --restart to discard it",
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID, state.ServerTypeID)
+	}
+
+	if !resumed {
+		state, err = newServerReplaceState(*retIA, serverType.ServerTypeID, client)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	//failed replacements are issued again, as waiting for a failed job group would only return the same error
+	for i := range state.Instances {
+		item := &state.Instances[i]
+		if item.Status == "failed" {
+			if item.NewServerID != 0 {
+				state.FailedServerIDs = append(state.FailedServerIDs, item.NewServerID)
+			}
+			item.AFCGroupID = 0
+			item.NewServerID = 0
+		}
+	}
+
+	if len(state.Instances) == 0 {
+		return "", fmt.Errorf("all the instances of instance array %s (#%d) already have servers of type %s",
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID, serverType.ServerTypeName)
+	}
+
+	err = assignServerReplaceServers(&state, retInfra.DatacenterName, client)
+	if err != nil {
+		return "", err
+	}
+
+	title := fmt.Sprintf("Replacing the servers of instance array %s (#%d) with servers of type %s",
+		retIA.InstanceArrayLabel, retIA.InstanceArrayID, serverType.ServerTypeName)
+
+	confirm, err := confirmCommand(c, func() string {
+
+		table, _ := renderServerReplaceState(state, title)
+
+		action := "Starting"
+		if resumed {
+			action = "Resuming"
+		}
+
+		confirmationMessage := fmt.Sprintf("%s\n%s\n%s the replacement, %d server(s) at a time. Are you sure? Type \"yes\" to continue:",
+			red("WARNING: This feature is experimental."),
+			table,
+			action,
+			batchSize)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	if err := writeLocalState(stateFile, state); err != nil {
+		return "", err
+	}
+
+	remaining := []int{}
+	for i, item := range state.Instances {
+		if item.Status != "done" {
+			remaining = append(remaining, i)
+		}
+	}
+
+	batchCount := (len(remaining) + batchSize - 1) / batchSize
+
+	var failure error
+
+	for b := 0; b < batchCount && failure == nil; b++ {
+
+		end := (b + 1) * batchSize
+		if end > len(remaining) {
+			end = len(remaining)
+		}
+		batch := remaining[b*batchSize : end]
+
+		fmt.Fprintf(GetStdout(), "Batch %d/%d: replacing the servers of %d instance(s)\n", b+1, batchCount, len(batch))
+
+		for _, i := range batch {
+			item := &state.Instances[i]
+
+			//the replacement was already started by a previous run, only wait for it
+			if item.AFCGroupID != 0 {
+				continue
+			}
+
+			afcGroupID, err := client.InstanceServerReplace(item.InstanceID, item.NewServerID)
+			if err != nil {
+				item.Status = "failed"
+				item.Error = err.Error()
+				failure = fmt.Errorf("%s: %v", item.InstanceLabel, err)
+				break
+			}
+
+			item.AFCGroupID = afcGroupID
+			item.Status = "running"
+			item.Error = ""
+
+			if err := writeLocalState(stateFile, state); err != nil {
+				return "", err
+			}
+		}
+
+		for _, i := range batch {
+			item := &state.Instances[i]
+
+			if item.AFCGroupID == 0 {
+				continue
+			}
+
+			err := waitForJobGroup(item.AFCGroupID, getIntParam(c.Arguments["block_timeout"]), getIntParam(c.Arguments["block_check_interval"]), client)
+			if err != nil {
+				item.Status = "failed"
+				item.Error = err.Error()
+				if failure == nil {
+					failure = fmt.Errorf("%s: %v", item.InstanceLabel, err)
+				}
+			} else {
+				item.Status = "done"
+				item.Error = ""
+			}
+		}
+
+		if err := writeLocalState(stateFile, state); err != nil {
+			return "", err
+		}
+	}
+
+	table, err := renderServerReplaceState(state, title)
+	if err != nil {
+		return "", err
+	}
+
+	if failure != nil {
+		fmt.Fprint(GetStdout(), table)
+		return "", fmt.Errorf("server replacement stopped at the first failure: %v\nThe progress was saved in %s. Run the same command again to resume, the failed replacements are started again",
+			failure, getLocalStatePath(stateFile))
+	}
+
+	if err := removeLocalState(stateFile); err != nil {
+		return "", err
+	}
+
+	return table, nil
+}
+
+// newServerReplaceState returns the instances of an instance array whose servers are not of the requested type, ordered by id
+func newServerReplaceState(ia metalcloud.InstanceArray, serverTypeID int, client metalcloud.MetalCloudClient) (serverReplaceState, error) {
+
+	state := serverReplaceState{
+		InstanceArrayID: ia.InstanceArrayID,
+		ServerTypeID:    serverTypeID,
+		Instances:       []serverReplaceStateItem{},
+	}
+
+	instances, err := client.InstanceArrayInstances(ia.InstanceArrayID)
+	if err != nil {
+		return state, err
+	}
+
+	for _, i := range *instances {
+		if i.ServerID == 0 || i.InstanceServiceStatus == "deleted" {
+			continue
+		}
+
+		server, err := client.ServerGet(i.ServerID, false)
+		if err != nil {
+			return state, err
+		}
+
+		if server.ServerTypeID == serverTypeID {
+			continue
+		}
+
+		state.Instances = append(state.Instances, serverReplaceStateItem{
+			InstanceID:    i.InstanceID,
+			InstanceLabel: i.InstanceLabel,
+			OldServerID:   i.ServerID,
+			Status:        "pending",
+		})
+	}
+
+	sort.Slice(state.Instances, func(i, j int) bool { return state.Instances[i].InstanceID < state.Instances[j].InstanceID })
+
+	return state, nil
+}
+
+// assignServerReplaceServers picks an available server of the state's server type for every instance whose
+// replacement has not been started yet. Servers picked in a previous run are re-picked as they might have been taken.
+func assignServerReplaceServers(state *serverReplaceState, datacenterName string, client metalcloud.MetalCloudClient) error {
+
+	needed := 0
+	used := map[int]bool{}
+	for _, serverID := range state.FailedServerIDs {
+		used[serverID] = true
+	}
+	for _, item := range state.Instances {
+		if item.AFCGroupID != 0 {
+			used[item.NewServerID] = true
+		} else {
+			needed++
+		}
+	}
+
+	if needed == 0 {
+		return nil
+	}
+
+	list, err := client.ServersSearch(fmt.Sprintf("+server_type_id:%d +server_status:available +datacenter_name:%s", state.ServerTypeID, datacenterName))
+	if err != nil {
+		return err
+	}
+
+	available := []int{}
+	for _, s := range *list {
+		if s.ServerTypeID != state.ServerTypeID || s.ServerStatus != "available" || used[s.ServerID] {
+			continue
+		}
+		available = append(available, s.ServerID)
+	}
+
+	if len(available) < needed {
+		return fmt.Errorf("%d available servers of type #%d are needed in datacenter %s but only %d were found",
+			needed, state.ServerTypeID, datacenterName, len(available))
+	}
+
+	sort.Ints(available)
+
+	for i := range state.Instances {
+		item := &state.Instances[i]
+		if item.AFCGroupID != 0 {
+			continue
+		}
+		item.NewServerID = available[0]
+		available = available[1:]
+	}
+
+	return nil
+}
+
+func renderServerReplaceState(state serverReplaceState, title string) (string, error) {
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "OLD SERVER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "NEW SERVER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "JOB GROUP",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "ERROR",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, item := range state.Instances {
+
+		status := item.Status
+		switch status {
+		case "done":
+			status = green(status)
+		case "failed":
+			status = red(status)
+		case "running":
+			status = yellow(status)
+		}
+
+		afcGroup := ""
+		if item.AFCGroupID != 0 {
+			afcGroup = fmt.Sprintf("#%d", item.AFCGroupID)
+		}
+
+		data = append(data, []interface{}{
+			item.InstanceID,
+			item.InstanceLabel,
+			fmt.Sprintf("#%d", item.OldServerID),
+			fmt.Sprintf("#%d", item.NewServerID),
+			afcGroup,
+			status,
+			item.Error,
+		})
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTable(title, "", "")
+}
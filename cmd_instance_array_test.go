@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
@@ -52,19 +56,164 @@ func TestInstanceArrayCreateCmd(t *testing.T) {
 		Return(&retIA, nil).
 		AnyTimes()
 
+	serverTypes := map[int]metalcloud.ServerType{
+		1: {ServerTypeID: 1, ServerTypeLabel: "M.40.256", ServerProcessorCount: 10},
+	}
+
+	client.EXPECT().
+		ServerTypesMatchHardwareConfiguration(gomock.Any(), gomock.Any()).
+		Return(&serverTypes, nil).
+		AnyTimes()
+
+	availableServerTypes := map[string]metalcloud.ServerType{
+		"1": {ServerTypeID: 1, ServerCount: 10},
+	}
+
+	client.EXPECT().
+		ServerTypesMatches(infra.InfrastructureID, gomock.Any(), nil, false).
+		Return(&availableServerTypes, nil).
+		AnyTimes()
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
 	//check with no return_id
 	ret, err := instanceArrayCreateCmd(&cmd, client)
 	Expect(err).To(BeNil())
 	Expect(ret).To(Equal(""))
+	Expect(stdout.String()).To(ContainSubstring("M.40.256"))
 
 	bTrue := true
 	cmd.Arguments["return_id"] = &bTrue
 
-	//check with return_id
+	//check with return_id, the server type matches are not printed
+	stdout.Reset()
 	ret, err = instanceArrayCreateCmd(&cmd, client)
 
 	Expect(ret).To(Equal(fmt.Sprintf("%d", retIA.InstanceArrayID)))
 	Expect(err).To(BeNil())
+	Expect(stdout.String()).To(Equal(""))
+}
+
+func TestInstanceArrayCreateHardwareMatch(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "us-santaclara",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	hw := metalcloud.HardwareConfiguration{
+		InstanceArrayRAMGbytes:     64,
+		InstanceArrayInstanceCount: 3,
+	}
+
+	serverTypes := map[int]metalcloud.ServerType{
+		1: {ServerTypeID: 1, ServerTypeLabel: "M.16.64", ServerRAMGbytes: 64},
+		2: {ServerTypeID: 2, ServerTypeLabel: "M.40.256", ServerRAMGbytes: 256},
+	}
+
+	client.EXPECT().
+		ServerTypesMatchHardwareConfiguration("us-santaclara", hw).
+		Return(&serverTypes, nil).
+		Times(2)
+
+	availableServerTypes := map[string]metalcloud.ServerType{
+		"2": {ServerTypeID: 2, ServerCount: 1},
+	}
+
+	client.EXPECT().
+		ServerTypesMatches(infra.InfrastructureID, hw, nil, false).
+		Return(&availableServerTypes, nil).
+		Times(2)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label":    "10002",
+		"instance_array_label":          "testia",
+		"instance_array_instance_count": 3,
+		"instance_array_ram_gbytes":     64,
+		"dry_run":                       true,
+	})
+
+	//dry run only lists the matches
+	ret, err := instanceArrayCreateCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("M.16.64"))
+	Expect(ret).To(ContainSubstring("M.40.256"))
+	Expect(ret).To(ContainSubstring("only 1 servers are available"))
+
+	matches, err := getServerTypesMatchingHardwareConfiguration(infra, hw, client)
+	Expect(err).To(BeNil())
+	Expect(matches).To(HaveLen(2))
+	Expect(matches[0].ServerType.ServerTypeID).To(Equal(2))
+	Expect(matches[0].Available).To(Equal(1))
+	Expect(matches[1].Available).To(Equal(0))
+
+	//dry run without a hardware configuration
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"instance_array_label":       "testia",
+		"dry_run":                    true,
+	})
+
+	_, err = instanceArrayCreateCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInstanceArrayCreateNoHardwareMatch(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		DatacenterName:      "us-santaclara",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	serverTypes := map[int]metalcloud.ServerType{}
+
+	client.EXPECT().
+		ServerTypesMatchHardwareConfiguration("us-santaclara", gomock.Any()).
+		Return(&serverTypes, nil).
+		Times(1)
+
+	availableServerTypes := map[string]metalcloud.ServerType{}
+
+	client.EXPECT().
+		ServerTypesMatches(infra.InfrastructureID, gomock.Any(), nil, false).
+		Return(&availableServerTypes, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayCreate(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"instance_array_label":       "testia",
+		"instance_array_ram_gbytes":  2048,
+	})
+
+	_, err := instanceArrayCreateCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("no server type"))
 }
 
 func TestInstanceArrayEdit(t *testing.T) {
@@ -595,3 +744,514 @@ func TestInstanceArrayInstancesListCmd(t *testing.T) {
 	Expect(csv[1][2]).To(Equal(ips[0].IPHumanReadable))
 
 }
+
+func TestGetNewInstanceCount(t *testing.T) {
+	RegisterTestingT(t)
+
+	n, err := getNewInstanceCount(3, "5")
+	Expect(err).To(BeNil())
+	Expect(n).To(Equal(5))
+
+	n, err = getNewInstanceCount(3, "+2")
+	Expect(err).To(BeNil())
+	Expect(n).To(Equal(5))
+
+	n, err = getNewInstanceCount(3, "-1")
+	Expect(err).To(BeNil())
+	Expect(n).To(Equal(2))
+
+	_, err = getNewInstanceCount(3, "-4")
+	Expect(err).NotTo(BeNil())
+
+	_, err = getNewInstanceCount(3, "abc")
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInstanceArrayScaleCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	iao := metalcloud.InstanceArrayOperation{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InstanceArrayInstanceCount: 3,
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:            11,
+		InstanceArrayLabel:         "testia",
+		InfrastructureID:           100,
+		InstanceArrayInstanceCount: 3,
+		InstanceArrayOperation:     &iao,
+	}
+
+	da := metalcloud.DriveArray{
+		DriveArrayID:    12,
+		DriveArrayLabel: "testda",
+		InstanceArrayID: 11,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InstanceArrayGet(11).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(11).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 201, InstanceLabel: "i1"},
+			"i2": {InstanceID: 202, InstanceLabel: "i2"},
+			"i3": {InstanceID: 203, InstanceLabel: "i3"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrays(100).
+		Return(&map[string]metalcloud.DriveArray{da.DriveArrayLabel: da}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		DriveArrayDrives(12).
+		Return(&map[string]metalcloud.Drive{
+			"d3": {DriveID: 303, DriveLabel: "d3", InstanceID: 203},
+		}, nil).
+		AnyTimes()
+
+	bFalse := false
+	expectedOperation := iao
+	expectedOperation.InstanceArrayInstanceCount = 1
+
+	client.EXPECT().
+		InstanceArrayEdit(11, expectedOperation, &bFalse, &bFalse, nil, &[]int{203, 202}).
+		Return(&ia, nil).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureDeploy(100, gomock.Any(), true, false).
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "11",
+		"count":                      "-2",
+		"no_bKeepDetachingDrives":    true,
+		"deploy":                     true,
+		"allow_data_loss":            true,
+		"autoconfirm":                true,
+	})
+
+	_, err := instanceArrayScaleCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	removed, err := getInstancesToRemove(&ia, 2, client)
+	Expect(err).To(BeNil())
+	Expect(removed).To(HaveLen(2))
+	Expect(removed[0].InstanceID).To(Equal(203))
+
+	expectedOperation.InstanceArrayInstanceCount = 5
+	bTrue := true
+
+	client.EXPECT().
+		InstanceArrayEdit(11, expectedOperation, &bFalse, &bTrue, nil, nil).
+		Return(&ia, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "11",
+		"count":                      "+2",
+		"autoconfirm":                true,
+	})
+
+	_, err = instanceArrayScaleCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "11",
+		"count":                      "3",
+		"autoconfirm":                true,
+	})
+
+	_, err = instanceArrayScaleCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInstanceArrayStartStopCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    11,
+		InstanceArrayLabel: "testia",
+		InfrastructureID:   100,
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InstanceArrayGet(11).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayStart(11).
+		Return(&ia, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceArrayStop(11).
+		Return(&ia, nil).
+		Times(1)
+
+	client.EXPECT().
+		InfrastructureDeploy(100, metalcloud.ShutdownOptions{AttemptSoftShutdown: true, SoftShutdownTimeoutSeconds: 300}, false, false).
+		Return(nil).
+		Times(1)
+
+	//the only window is two days from now
+	day := time.Now().AddDate(0, 0, 2).Weekday().String()
+	windowFile := filepath.Join(t.TempDir(), "window.yaml")
+	Expect(os.WriteFile(windowFile, []byte(fmt.Sprintf("windows:\n  - days: [\"%s\"]\n    start: \"02:00\"\n    end: \"03:00\"\n", day)), 0600)).To(BeNil())
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "11",
+		"deploy":                     true,
+		"window":                     windowFile,
+	})
+
+	_, err := instanceArrayStartCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("refusing to deploy"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label":     "11",
+		"deploy":                         true,
+		"window":                         windowFile,
+		"no_hard_shutdown_after_timeout": true,
+		"soft_shutdown_timeout_seconds":  300,
+	})
+
+	_, err = instanceArrayStartCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "11",
+		"autoconfirm":                true,
+	})
+
+	_, err = instanceArrayStopCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
+
+func TestInstanceArrayInterfaceAttachDetachCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:  100,
+		InfrastructureID: 10,
+		InstanceArrayInterfaces: []metalcloud.InstanceArrayInterface{
+			{
+				InstanceArrayInterfaceIndex: 0,
+				NetworkID:                   1,
+			},
+			{
+				InstanceArrayInterfaceIndex: 1,
+			},
+		},
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(2).
+		Return(&metalcloud.Network{NetworkID: 2, InfrastructureID: 10}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(3).
+		Return(&metalcloud.Network{NetworkID: 3, InfrastructureID: 11}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInterfaceAttachNetwork(100, 1, 2).
+		Return(&ia, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"interface_index":            2,
+		"network_id_or_label":        "2",
+	})
+
+	_, err := instanceArrayInterfaceAttachCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//network from another infrastructure
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"interface_index":            2,
+		"network_id_or_label":        "3",
+	})
+
+	_, err = instanceArrayInterfaceAttachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//missing interface
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"interface_index":            5,
+		"network_id_or_label":        "2",
+	})
+
+	_, err = instanceArrayInterfaceAttachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	client.EXPECT().
+		InstanceArrayInterfaceDetach(100, 0).
+		Return(&ia, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"interface_index":            1,
+		"autoconfirm":                true,
+	})
+
+	_, err = instanceArrayInterfaceDetachCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//detaching an unattached interface
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"interface_index":            2,
+		"autoconfirm":                true,
+	})
+
+	_, err = instanceArrayInterfaceDetachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInstanceArrayPowerControlCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    100,
+		InstanceArrayLabel: "ia",
+		InfrastructureID:   10,
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(10).
+		Return(&metalcloud.Infrastructure{InfrastructureID: 10}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(100).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 201, ServerID: 1, InstanceServiceStatus: "active"},
+			"i2": {InstanceID: 202, ServerID: 2, InstanceServiceStatus: "active"},
+			"i3": {InstanceID: 203, InstanceServiceStatus: "ordered"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceServerPowerSet(201, "off").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerPowerSet(202, "off").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(10, []int{201, 202}).
+		Return(&map[string]string{"201": "off", "202": "off"}, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"operation":                  "off",
+		"autoconfirm":                true,
+	})
+
+	ret, err := instanceArrayPowerControlCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("2 succeeded 0 failed"))
+	Expect(ret).To(ContainSubstring("off: 2"))
+}
+
+func TestInstanceArrayServerReplaceCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	os.Setenv("METALCLOUD_STATE_DIR", dir)
+	defer os.Unsetenv("METALCLOUD_STATE_DIR")
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    100,
+		InstanceArrayLabel: "ia",
+		InfrastructureID:   10,
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&ia, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(10).
+		Return(&metalcloud.Infrastructure{InfrastructureID: 10, DatacenterName: "dc"}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypeGet(5).
+		Return(&metalcloud.ServerType{ServerTypeID: 5, ServerTypeName: "new-type"}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayInstances(100).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 201, InstanceLabel: "instance-201", ServerID: 1},
+			"i2": {InstanceID: 202, InstanceLabel: "instance-202", ServerID: 2},
+			"i3": {InstanceID: 203, InstanceLabel: "instance-203", ServerID: 3},
+			"i4": {InstanceID: 204, InstanceLabel: "instance-204"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerGet(1, false).
+		Return(&metalcloud.Server{ServerID: 1, ServerTypeID: 4}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerGet(2, false).
+		Return(&metalcloud.Server{ServerID: 2, ServerTypeID: 4}, nil).
+		AnyTimes()
+
+	//already replaced
+	client.EXPECT().
+		ServerGet(3, false).
+		Return(&metalcloud.Server{ServerID: 3, ServerTypeID: 5}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_type_id:5 +server_status:available +datacenter_name:dc").
+		Return(&[]metalcloud.ServerSearchResult{
+			{ServerID: 12, ServerTypeID: 5, ServerStatus: "available"},
+			{ServerID: 11, ServerTypeID: 5, ServerStatus: "available"},
+			{ServerID: 13, ServerTypeID: 6, ServerStatus: "available"},
+			{ServerID: 14, ServerTypeID: 5, ServerStatus: "available"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceServerReplace(201, 11).
+		Return(1001, nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerReplace(202, 12).
+		Return(1002, nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCSearch("+afc_group_id:1001", 0, 1000).
+		Return(&[]metalcloud.AFCSearchResult{
+			{AFCID: 1, AFCStatus: "returned_success"},
+		}, nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCSearch("+afc_group_id:1002", 0, 1000).
+		Return(&[]metalcloud.AFCSearchResult{
+			{AFCID: 2, AFCStatus: "returned_success"},
+			{AFCID: 3, AFCStatus: "thrown_error", AFCFunctionName: "server_deploy"},
+		}, nil).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	args := map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"server_type":                "5",
+		"batch_size":                 1,
+		"block_timeout":              10,
+		"block_check_interval":       0,
+		"autoconfirm":                true,
+	}
+
+	cmd := MakeCommand(args)
+
+	_, err := instanceArrayServerReplaceCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("instance-202: job #3 (server_deploy) failed"))
+	Expect(stdout.String()).To(ContainSubstring("Batch 2/2"))
+
+	stateFile := getServerReplaceStateFileName(100)
+
+	var state serverReplaceState
+	Expect(readLocalState(stateFile, &state)).To(BeNil())
+	Expect(state.ServerTypeID).To(Equal(5))
+	Expect(state.Instances).To(HaveLen(2))
+	Expect(state.Instances[0].NewServerID).To(Equal(11))
+	Expect(state.Instances[0].Status).To(Equal("done"))
+	Expect(state.Instances[1].NewServerID).To(Equal(12))
+	Expect(state.Instances[1].AFCGroupID).To(Equal(1002))
+	Expect(state.Instances[1].Status).To(Equal("failed"))
+
+	//a different server type is not resumed
+	client.EXPECT().
+		ServerTypeGet(6).
+		Return(&metalcloud.ServerType{ServerTypeID: 6, ServerTypeName: "other-type"}, nil).
+		AnyTimes()
+
+	args["server_type"] = "6"
+	cmd = MakeCommand(args)
+	_, err = instanceArrayServerReplaceCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("--restart"))
+
+	//resuming issues the failed replacement again with another server, the finished ones are not touched
+	client.EXPECT().
+		InstanceServerReplace(202, 14).
+		Return(1003, nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCSearch("+afc_group_id:1003", 0, 1000).
+		Return(&[]metalcloud.AFCSearchResult{
+			{AFCID: 4, AFCStatus: "returned_success"},
+		}, nil).
+		Times(1)
+
+	args["server_type"] = "5"
+	cmd = MakeCommand(args)
+	ret, err := instanceArrayServerReplaceCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("instance-202"))
+	Expect(ret).To(ContainSubstring("done"))
+
+	_, err = os.Stat(filepath.Join(dir, stateFile))
+	Expect(os.IsNotExist(err)).To(BeTrue())
+}
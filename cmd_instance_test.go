@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
@@ -144,3 +147,173 @@ func TestInstanceServerReplaceCmd(t *testing.T) {
 	Expect(ret).To(Equal("500"))
 
 }
+
+func TestPowerControlBatches(t *testing.T) {
+	RegisterTestingT(t)
+
+	targets := []powerControlTarget{}
+	for i := 0; i < 5; i++ {
+		targets = append(targets, powerControlTarget{Instance: metalcloud.Instance{InstanceID: i}})
+	}
+
+	Expect(powerControlBatches(targets, 0)).To(HaveLen(1))
+	Expect(powerControlBatches(targets, 10)).To(HaveLen(1))
+
+	batches := powerControlBatches(targets, 2)
+	Expect(batches).To(HaveLen(3))
+	Expect(batches[2]).To(HaveLen(1))
+	Expect(batches[2][0].Instance.InstanceID).To(Equal(4))
+}
+
+func TestBulkPowerControlRolling(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    100,
+		InfrastructureLabel: "test",
+	}
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    10,
+		InstanceArrayLabel: "ia",
+	}
+
+	targets := []powerControlTarget{}
+	for i := 1; i <= 5; i++ {
+		targets = append(targets, powerControlTarget{
+			Instance:      metalcloud.Instance{InstanceID: i, InstanceLabel: fmt.Sprintf("instance-%d", i)},
+			InstanceArray: ia,
+		})
+	}
+
+	client.EXPECT().
+		InstanceServerPowerSet(1, "reset").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerPowerSet(2, "reset").
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerPowerSet(3, "reset").
+		Return(fmt.Errorf("ipmi timeout")).
+		Times(1)
+
+	client.EXPECT().
+		InstanceServerPowerSet(4, "reset").
+		Return(nil).
+		Times(1)
+
+	//the last batch is skipped because the second one had failures
+	client.EXPECT().
+		InstanceServerPowerSet(5, gomock.Any()).
+		Times(0)
+
+	//a reset keeps the servers powered on so there is no power status to wait for
+	client.EXPECT().
+		InstanceServerPowerGetBatch(100, []int{1, 2}).
+		Times(0)
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(100, []int{1, 2, 3, 4, 5}).
+		Return(&map[string]string{"1": "on", "2": "on", "3": "unknown", "4": "on", "5": "off"}, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"operation":   "reset",
+		"batch_size":  2,
+		"autoconfirm": true,
+		"format":      "json",
+	})
+
+	ret, err := bulkPowerControl(infra, targets, "instance array ia (#10)", &cmd, client)
+	Expect(err).To(BeNil())
+	Expect(stdout.String()).To(ContainSubstring("Batch 2/3"))
+	Expect(stdout.String()).NotTo(ContainSubstring("Batch 3/3"))
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m).To(HaveLen(5))
+	Expect(m[2].(map[string]interface{})["RESULT"]).To(ContainSubstring("ipmi timeout"))
+	Expect(m[4].(map[string]interface{})["RESULT"]).To(ContainSubstring("skipped"))
+	Expect(m[4].(map[string]interface{})["POWER"]).To(ContainSubstring("off"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"operation":   "explode",
+		"autoconfirm": true,
+	})
+
+	_, err = bulkPowerControl(infra, targets, "instance array ia (#10)", &cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestBulkPowerControlWaitsForPowerStatus(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    100,
+		InfrastructureLabel: "test",
+	}
+
+	targets := []powerControlTarget{}
+	for i := 1; i <= 3; i++ {
+		targets = append(targets, powerControlTarget{
+			Instance:      metalcloud.Instance{InstanceID: i, InstanceLabel: fmt.Sprintf("instance-%d", i)},
+			InstanceArray: metalcloud.InstanceArray{InstanceArrayID: 10, InstanceArrayLabel: "ia"},
+		})
+	}
+
+	client.EXPECT().
+		InstanceServerPowerSet(1, "soft").
+		Return(nil).
+		Times(1)
+
+	//the first instance does not power off so the next batches are skipped
+	client.EXPECT().
+		InstanceServerPowerSet(gomock.Not(1), gomock.Any()).
+		Times(0)
+
+	client.EXPECT().
+		InstanceServerPowerGetBatch(100, []int{1}).
+		Return(&map[string]string{"1": "on"}, nil).
+		Times(1)
+
+	//the results are still shown when the final power status cannot be read
+	client.EXPECT().
+		InstanceServerPowerGetBatch(100, []int{1, 2, 3}).
+		Return(nil, fmt.Errorf("api unavailable")).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"operation":     "soft",
+		"batch_size":    1,
+		"batch_timeout": 0,
+		"autoconfirm":   true,
+	})
+
+	ret, err := bulkPowerControl(infra, targets, "instance array ia (#10)", &cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("api unavailable"))
+	Expect(ret).To(Equal(""))
+
+	Expect(stdout.String()).To(ContainSubstring("not powered off after 0 seconds"))
+	Expect(stdout.String()).To(ContainSubstring("skipped"))
+	Expect(stdout.String()).To(ContainSubstring("Power status could not be read"))
+}
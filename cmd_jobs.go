@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -43,9 +44,12 @@ var jobsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("get job", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"job_id": c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
-				"format": c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
-				"watch":  c.FlagSet.String("watch", _nilDefaultStr, "If set to a human readable interval such as '4s', '1m' will print the job status until interrupted."),
+				"job_id":          c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
+				"format":          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"watch":           c.FlagSet.String("watch", _nilDefaultStr, "If set to a human readable interval such as '4s', '1m' will print the job status until interrupted."),
+				"follow":          c.FlagSet.Bool("follow", false, green("(Flag)")+" If set prints every status change and retry of the job until it finishes."),
+				"follow_interval": c.FlagSet.Int("follow-interval", 5, "Check interval in seconds when following a job. Must be at least 1. Defaults to 5 seconds."),
+				"exception":       c.FlagSet.Bool("exception", false, green("(Flag)")+" If set prints the full exception of the job including the stack trace and the decoded request parameters."),
 			}
 		},
 		ExecuteFunc: jobGetCmdWithWatch,
@@ -60,8 +64,14 @@ var jobsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("retry job", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"job_id":      c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)") + " If set it will assume action is confirmed"),
+				"job_id":                     c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
+				"filter":                     c.FlagSet.String("filter", _nilDefaultStr, "Apply the operation to all the jobs matching the filter, same syntax as 'job list'. Example: 'afc_status:thrown_error'"),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Apply the operation to the jobs of this infrastructure. Can be combined with --filter."),
+				"server_id":                  c.FlagSet.Int("server", _nilDefaultInt, "Apply the operation to the jobs of this server. Can be combined with --filter."),
+				"limit":                      c.FlagSet.Int("limit", 100, "Maximum number of jobs to match when using --filter, --infra or --server. Defaults to 100."),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "How many jobs to process in parallel when using --filter, --infra or --server. Defaults to 5."),
+				"format":                     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: jobRetryCmd,
@@ -76,8 +86,14 @@ var jobsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("Skip job", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"job_id":      c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)") + " If set it will assume action is confirmed"),
+				"job_id":                     c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
+				"filter":                     c.FlagSet.String("filter", _nilDefaultStr, "Apply the operation to all the jobs matching the filter, same syntax as 'job list'. Example: 'afc_status:thrown_error'"),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Apply the operation to the jobs of this infrastructure. Can be combined with --filter."),
+				"server_id":                  c.FlagSet.Int("server", _nilDefaultInt, "Apply the operation to the jobs of this server. Can be combined with --filter."),
+				"limit":                      c.FlagSet.Int("limit", 100, "Maximum number of jobs to match when using --filter, --infra or --server. Defaults to 100."),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "How many jobs to process in parallel when using --filter, --infra or --server. Defaults to 5."),
+				"format":                     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: jobSkipCmd,
@@ -92,8 +108,14 @@ var jobsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("Delete job", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"job_id":      c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)") + " If set it will assume action is confirmed"),
+				"job_id":                     c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
+				"filter":                     c.FlagSet.String("filter", _nilDefaultStr, "Apply the operation to all the jobs matching the filter, same syntax as 'job list'. Example: 'afc_status:thrown_error'"),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Apply the operation to the jobs of this infrastructure. Can be combined with --filter."),
+				"server_id":                  c.FlagSet.Int("server", _nilDefaultInt, "Apply the operation to the jobs of this server. Can be combined with --filter."),
+				"limit":                      c.FlagSet.Int("limit", 100, "Maximum number of jobs to match when using --filter, --infra or --server. Defaults to 100."),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "How many jobs to process in parallel when using --filter, --infra or --server. Defaults to 5."),
+				"format":                     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: jobDeleteCmd,
@@ -108,9 +130,15 @@ var jobsCmds = []Command{
 		FlagSet:      flag.NewFlagSet("Kill job", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"job_id":      c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
-				"mark":        c.FlagSet.String("mark", "kill", "One of 'kill','stop_retrying','kill_and_stop_retrying','kill_and_stop_retrying','keep_alive'"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)") + " If set it will assume action is confirmed"),
+				"job_id":                     c.FlagSet.String("id", _nilDefaultStr, "JOB ID"),
+				"filter":                     c.FlagSet.String("filter", _nilDefaultStr, "Apply the operation to all the jobs matching the filter, same syntax as 'job list'. Example: 'afc_status:thrown_error'"),
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, "Apply the operation to the jobs of this infrastructure. Can be combined with --filter."),
+				"server_id":                  c.FlagSet.Int("server", _nilDefaultInt, "Apply the operation to the jobs of this server. Can be combined with --filter."),
+				"limit":                      c.FlagSet.Int("limit", 100, "Maximum number of jobs to match when using --filter, --infra or --server. Defaults to 100."),
+				"concurrency":                c.FlagSet.Int("concurrency", 5, "How many jobs to process in parallel when using --filter, --infra or --server. Defaults to 5."),
+				"format":                     c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"mark":                       c.FlagSet.String("mark", "kill", "One of 'kill','stop_retrying','kill_and_stop_retrying','kill_and_stop_retrying','keep_alive'"),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: jobKillCmd,
@@ -183,20 +211,7 @@ func jobListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 
 		statusCounts[s.AFCStatus] = statusCounts[s.AFCStatus] + 1
 
-		status := s.AFCStatus
-
-		switch status {
-		case "thrown_error":
-			status = red(status)
-		case "thrown_error_while_retrying":
-			status = magenta(status)
-		case "running":
-			status = yellow(status)
-		case "returned_success":
-			status = green(status)
-		default:
-			status = yellow(status)
-		}
+		status := colorizeJobStatus(s.AFCStatus)
 
 		durationObj, err := durationSinceZuluUTC(s.AFCCreatedTimestamp)
 		if err != nil {
@@ -219,34 +234,11 @@ func jobListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 			affects = affects + fmt.Sprintf("Group: #%d ", s.AFCGroupID)
 		}
 
-		retries := fmt.Sprintf("%d/%d", s.AFCRetryCount, s.AFCRetryMax)
-		if s.AFCRetryCount >= s.AFCRetryMax {
-			retries = red(retries)
-		} else if s.AFCRetryCount < s.AFCRetryMax && s.AFCRetryCount > 1 {
-			retries = yellow(retries)
-		} else {
-			retries = green(retries)
-		}
+		retries := colorizeJobRetries(s.AFCRetryCount, s.AFCRetryMax)
 
-		request := ""
-		switch s.AFCFunctionName {
-		case "infrastructure_provision":
-			var paramsArr []interface{}
-			err := json.Unmarshal([]byte(s.AFCParamsJSON), &paramsArr)
-			if err != nil {
-				return "", err
-			}
-			funcName := ""
-			if len(paramsArr) >= 2 {
-				funcName = paramsArr[1].(string)
-			}
-			var actualParams interface{}
-			if len(paramsArr) >= 3 {
-				actualParams = paramsArr[2]
-			}
-			request = fmt.Sprintf("%s(%+v)", funcName, actualParams)
-		default:
-			request = fmt.Sprintf("%s(%+v)", s.AFCFunctionName, s.AFCParamsJSON)
+		request, err := getJobRequestString(s.AFCFunctionName, s.AFCParamsJSON)
+		if err != nil {
+			return "", err
 		}
 
 		requestFieldWidth := 40
@@ -262,16 +254,9 @@ func jobListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 		if len(request) > requestFieldWidth {
 			request = truncateString(request, requestFieldWidth)
 		}
-		var respObj map[string]string
-
-		response := ""
-		if s.AFCExceptionJSON != "" {
-			err := json.Unmarshal([]byte(s.AFCExceptionJSON), &respObj)
-			if err != nil {
-				return "", err
-			}
-
-			response = fmt.Sprintf("%+v", respObj["message"])
+		response, err := getJobExceptionMessage(s.AFCExceptionJSON)
+		if err != nil {
+			return "", err
 		}
 
 		if len(response) > responseFieldWidth {
@@ -324,11 +309,27 @@ func jobGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 		return "", err
 	}
 
+	if getBoolParam(c.Arguments["follow"]) {
+		followInterval := getIntParam(c.Arguments["follow_interval"])
+		if followInterval < 1 {
+			return "", fmt.Errorf("-follow-interval must be at least 1 second")
+		}
+
+		err := jobFollow(afc_id, followInterval, client)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	s, err := client.AFCGet(afc_id)
 	if err != nil {
 		return "", err
 	}
 
+	if getBoolParam(c.Arguments["exception"]) {
+		return jobExceptionDetails(s, getStringParam(c.Arguments["format"]))
+	}
+
 	schema := []tableformatter.SchemaField{
 		{
 			FieldName: "ID",
@@ -379,20 +380,7 @@ func jobGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
 	data := [][]interface{}{}
 
-	status := s.AFCStatus
-
-	switch status {
-	case "thrown_error":
-		status = red(status)
-	case "thrown_error_while_retrying":
-		status = magenta(status)
-	case "running":
-		status = yellow(status)
-	case "returned_success":
-		status = green(status)
-	default:
-		status = yellow(status)
-	}
+	status := colorizeJobStatus(s.AFCStatus)
 
 	durationObj, err := durationSinceZuluUTC(s.AFCCreatedTimestamp)
 	if err != nil {
@@ -415,49 +403,19 @@ func jobGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 		affects = affects + fmt.Sprintf("Infrastructure: #%d ", s.InfrastructureID)
 	}
 
-	retries := fmt.Sprintf("%d/%d", s.AFCRetryCount, s.AFCRetryMax)
-	if s.AFCRetryCount >= s.AFCRetryMax {
-		retries = red(retries)
-	} else if s.AFCRetryCount < s.AFCRetryMax && s.AFCRetryCount > 1 {
-		retries = yellow(retries)
-	} else {
-		retries = green(retries)
-	}
+	retries := colorizeJobRetries(s.AFCRetryCount, s.AFCRetryMax)
 
-	request := ""
-	switch s.AFCFunctionName {
-	case "infrastructure_provision":
-		var paramsArr []interface{}
-		err := json.Unmarshal([]byte(s.AFCParamsJSON), &paramsArr)
-		if err != nil {
-			return "", err
-		}
-		funcName := ""
-		if len(paramsArr) >= 2 {
-			funcName = paramsArr[1].(string)
-		}
-		var actualParams interface{}
-		if len(paramsArr) >= 3 {
-			actualParams = paramsArr[2]
-		}
-		request = fmt.Sprintf("%s(%+v)", funcName, actualParams)
-	default:
-		request = fmt.Sprintf("%s(%+v)", s.AFCFunctionName, s.AFCParamsJSON)
+	request, err := getJobRequestString(s.AFCFunctionName, s.AFCParamsJSON)
+	if err != nil {
+		return "", err
 	}
 
 	if len(request) > 100 {
 		request = truncateString(request, 100)
 	}
-	var respObj map[string]string
-
-	response := ""
-	if s.AFCExceptionJSON != "" {
-		err := json.Unmarshal([]byte(s.AFCExceptionJSON), &respObj)
-		if err != nil {
-			return "", err
-		}
-
-		response = fmt.Sprintf("%+v", respObj["message"])
+	response, err := getJobExceptionMessage(s.AFCExceptionJSON)
+	if err != nil {
+		return "", err
 	}
 
 	if len(response) > 100 {
@@ -500,6 +458,12 @@ func jobGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
 func jobRetryCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
+	if isJobBulkCommand(c) {
+		return jobBulkCmd("Retrying", c, client, func(afcID int) error {
+			return client.AFCRetryCall(afcID)
+		})
+	}
+
 	afc_id_s, ok := getStringParamOk(c.Arguments["job_id"])
 	if !ok {
 		return "", fmt.Errorf("-id required")
@@ -536,6 +500,12 @@ func jobRetryCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 
 func jobSkipCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
+	if isJobBulkCommand(c) {
+		return jobBulkCmd("Skipping", c, client, func(afcID int) error {
+			return client.AFCSkip(afcID)
+		})
+	}
+
 	afc_id_s, ok := getStringParamOk(c.Arguments["job_id"])
 	if !ok {
 		return "", fmt.Errorf("-id required")
@@ -572,6 +542,12 @@ func jobSkipCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 
 func jobDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
+	if isJobBulkCommand(c) {
+		return jobBulkCmd("Deleting", c, client, func(afcID int) error {
+			return client.AFCDelete(afcID)
+		})
+	}
+
 	afc_id_s, ok := getStringParamOk(c.Arguments["job_id"])
 	if !ok {
 		return "", fmt.Errorf("-id required")
@@ -608,6 +584,12 @@ func jobDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error
 
 func jobKillCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
+	if isJobBulkCommand(c) {
+		return jobBulkCmd("Killing", c, client, func(afcID int) error {
+			return client.AFCMarkForDeath(afcID, getStringParam(c.Arguments["mark"]))
+		})
+	}
+
 	afc_id_s, ok := getStringParamOk(c.Arguments["job_id"])
 	if !ok {
 		return "", fmt.Errorf("-id required")
@@ -644,6 +626,437 @@ func jobKillCmd(c *Command, client metalcloud.MetalCloudClient) (string, error)
 	return jobGetCmd(c, client)
 }
 
+// isJobBulkCommand returns true if the command targets multiple jobs instead of a single job id
+func isJobBulkCommand(c *Command) bool {
+	if _, ok := getStringParamOk(c.Arguments["job_id"]); ok {
+		return false
+	}
+	_, filterOk := getStringParamOk(c.Arguments["filter"])
+	_, infraOk := getStringParamOk(c.Arguments["infrastructure_id_or_label"])
+	_, serverOk := getIntParamOk(c.Arguments["server_id"])
+	return filterOk || infraOk || serverOk
+}
+
+// getJobsFromCommand returns the jobs matching the filter, infra and server arguments
+func getJobsFromCommand(c *Command, client metalcloud.MetalCloudClient) ([]metalcloud.AFCSearchResult, error) {
+
+	conditions := []string{}
+
+	if filter, ok := getStringParamOk(c.Arguments["filter"]); ok {
+		conditions = append(conditions, filter)
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["infrastructure_id_or_label"]); ok {
+		infraID, err := getIDOrDo(v, func(label string) (int, error) {
+			infra, err := client.InfrastructureGetByLabel(label)
+			if err != nil {
+				return 0, err
+			}
+			return infra.InfrastructureID, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("infrastructure_id:%d", infraID))
+	}
+
+	if serverID, ok := getIntParamOk(c.Arguments["server_id"]); ok {
+		conditions = append(conditions, fmt.Sprintf("server_id:%d", serverID))
+	}
+
+	limit := 100
+	if v, ok := getIntParamOk(c.Arguments["limit"]); ok {
+		limit = v
+	}
+
+	list, err := client.AFCSearch(convertToSearchFieldFormat(strings.Join(conditions, " ")), 0, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return *list, nil
+}
+
+// jobBulkCmd applies an operation to all the jobs matched by the command's arguments after a single confirmation
+func jobBulkCmd(operation string, c *Command, client metalcloud.MetalCloudClient, f func(afcID int) error) (string, error) {
+
+	jobs, err := getJobsFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if len(jobs) == 0 {
+		return "", fmt.Errorf("no jobs matched")
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		schema := []tableformatter.SchemaField{
+			{
+				FieldName: "ID",
+				FieldType: tableformatter.TypeInt,
+				FieldSize: 6,
+			},
+			{
+				FieldName: "STATUS",
+				FieldType: tableformatter.TypeString,
+				FieldSize: 5,
+			},
+			{
+				FieldName: "REQUEST",
+				FieldType: tableformatter.TypeString,
+				FieldSize: 5,
+			},
+		}
+
+		data := [][]interface{}{}
+		for _, s := range jobs {
+			request, _ := getJobRequestString(s.AFCFunctionName, s.AFCParamsJSON)
+			data = append(data, []interface{}{
+				s.AFCID,
+				colorizeJobStatus(s.AFCStatus),
+				truncateString(request, 60),
+			})
+		}
+
+		table := tableformatter.Table{
+			Data:   data,
+			Schema: schema,
+		}
+		tableStr, _ := table.RenderTable("Matched jobs", "", "")
+
+		confirmationMessage := fmt.Sprintf("%s\n%s %d jobs.  Are you sure? Type \"yes\" to continue:",
+			tableStr,
+			operation,
+			len(jobs),
+		)
+
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	concurrency := 5
+	if v, ok := getIntParamOk(c.Arguments["concurrency"]); ok {
+		concurrency = v
+	}
+
+	errs := runConcurrently(len(jobs), concurrency, func(i int) error {
+		return f(jobs[i].AFCID)
+	})
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	data := [][]interface{}{}
+	failed := 0
+	for i, s := range jobs {
+		result := green("ok")
+		if errs[i] != nil {
+			result = red(errs[i].Error())
+			failed++
+		}
+		data = append(data, []interface{}{
+			s.AFCID,
+			colorizeJobStatus(s.AFCStatus),
+			result,
+		})
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	title := fmt.Sprintf("%s %d jobs: %d succeeded %d failed", operation, len(jobs), len(jobs)-failed, failed)
+
+	return table.RenderTable(title, "", getStringParam(c.Arguments["format"]))
+}
+
+// jobFinalStatuses are the statuses after which a job will no longer change by itself
+var jobFinalStatuses = map[string]bool{
+	"returned_success": true,
+	"thrown_error":     true,
+	"skipped":          true,
+	"killed":           true,
+}
+
+// jobFollow prints every status change and retry of a job until it reaches a final status
+func jobFollow(afcID int, checkIntervalSeconds int, client metalcloud.MetalCloudClient) error {
+
+	lastStatus := ""
+	lastRetryCount := -1
+
+	for {
+		s, err := client.AFCGet(afcID)
+		if err != nil {
+			return err
+		}
+
+		if s.AFCStatus != lastStatus || s.AFCRetryCount != lastRetryCount {
+
+			line := fmt.Sprintf("%s Job #%d %s retries %s",
+				time.Now().Format("01-02-2006 15:04:05"),
+				s.AFCID,
+				colorizeJobStatus(s.AFCStatus),
+				colorizeJobRetries(s.AFCRetryCount, s.AFCRetryMax),
+			)
+
+			message, err := getJobExceptionMessage(s.AFCExceptionJSON)
+			if err != nil {
+				return err
+			}
+			if message != "" && s.AFCStatus != "returned_success" {
+				line = line + " " + message
+			}
+
+			fmt.Fprintln(GetStdout(), line)
+
+			lastStatus = s.AFCStatus
+			lastRetryCount = s.AFCRetryCount
+		}
+
+		if jobFinalStatuses[s.AFCStatus] {
+			return nil
+		}
+
+		time.Sleep(time.Duration(checkIntervalSeconds) * time.Second)
+	}
+}
+
+// colorizeJobStatus returns the status of a job colored by severity
+func colorizeJobStatus(status string) string {
+	switch status {
+	case "thrown_error":
+		return red(status)
+	case "thrown_error_while_retrying":
+		return magenta(status)
+	case "running":
+		return yellow(status)
+	case "returned_success":
+		return green(status)
+	}
+	return yellow(status)
+}
+
+// colorizeJobRetries returns the retries of a job as count/max colored by how close to max they are
+func colorizeJobRetries(retryCount int, retryMax int) string {
+	retries := fmt.Sprintf("%d/%d", retryCount, retryMax)
+	if retryCount >= retryMax {
+		return red(retries)
+	} else if retryCount < retryMax && retryCount > 1 {
+		return yellow(retries)
+	}
+	return green(retries)
+}
+
+// decodeJobRequest returns the function name and parameters of a job. For infrastructure_provision jobs
+// the parameters are decoded and the actual provisioning function and its parameters are returned.
+func decodeJobRequest(functionName string, paramsJSON string) (string, interface{}, error) {
+
+	if functionName != "infrastructure_provision" {
+		return functionName, paramsJSON, nil
+	}
+
+	var paramsArr []interface{}
+	err := json.Unmarshal([]byte(paramsJSON), &paramsArr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	funcName := ""
+	if len(paramsArr) >= 2 {
+		funcName, _ = paramsArr[1].(string)
+	}
+
+	var actualParams interface{}
+	if len(paramsArr) >= 3 {
+		actualParams = paramsArr[2]
+	}
+
+	return funcName, actualParams, nil
+}
+
+// getJobRequestString returns the request of a job as func(params)
+func getJobRequestString(functionName string, paramsJSON string) (string, error) {
+	funcName, params, err := decodeJobRequest(functionName, paramsJSON)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%+v)", funcName, params), nil
+}
+
+// parseJobException parses the exception of a job. Returns nil if the job has no exception.
+func parseJobException(exceptionJSON string) (map[string]interface{}, error) {
+	if exceptionJSON == "" {
+		return nil, nil
+	}
+
+	var exception map[string]interface{}
+	err := json.Unmarshal([]byte(exceptionJSON), &exception)
+	if err != nil {
+		return nil, err
+	}
+
+	return exception, nil
+}
+
+// getJobExceptionMessage returns the message of the exception of a job
+func getJobExceptionMessage(exceptionJSON string) (string, error) {
+	exception, err := parseJobException(exceptionJSON)
+	if err != nil || exception == nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%+v", exception["message"]), nil
+}
+
+// jobExceptionFirstKeys are printed before the other exception keys
+var jobExceptionFirstKeys = []string{"type", "message", "code", "file", "line"}
+
+// jobExceptionStackKeys hold stack traces which are printed one frame per line
+var jobExceptionStackKeys = map[string]bool{
+	"stack":       true,
+	"trace":       true,
+	"stack_trace": true,
+	"stackTrace":  true,
+}
+
+// renderJobException writes the exception with the known keys first, stack traces one frame per line and nested
+// exceptions (causes) indented below their parent
+func renderJobException(sb *strings.Builder, exception map[string]interface{}, indent string) {
+
+	keys := []string{}
+	for _, k := range jobExceptionFirstKeys {
+		if _, ok := exception[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+
+	otherKeys := []string{}
+	for k := range exception {
+		isFirst := false
+		for _, f := range jobExceptionFirstKeys {
+			if k == f {
+				isFirst = true
+			}
+		}
+		if !isFirst {
+			otherKeys = append(otherKeys, k)
+		}
+	}
+	sort.Strings(otherKeys)
+	keys = append(keys, otherKeys...)
+
+	for _, k := range keys {
+		v := exception[k]
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			sb.WriteString(fmt.Sprintf("%s%s:\n", indent, bold(k)))
+			renderJobException(sb, val, indent+"    ")
+		case []interface{}:
+			sb.WriteString(fmt.Sprintf("%s%s:\n", indent, bold(k)))
+			for _, frame := range val {
+				if m, ok := frame.(map[string]interface{}); ok {
+					renderJobException(sb, m, indent+"    ")
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("%s    %v\n", indent, frame))
+			}
+		case string:
+			if jobExceptionStackKeys[k] {
+				sb.WriteString(fmt.Sprintf("%s%s:\n", indent, bold(k)))
+				for _, line := range strings.Split(strings.TrimRight(val, "\n"), "\n") {
+					sb.WriteString(fmt.Sprintf("%s    %s\n", indent, line))
+				}
+				continue
+			}
+			if k == "message" {
+				val = red(val)
+			}
+			sb.WriteString(fmt.Sprintf("%s%s: %s\n", indent, bold(k), val))
+		default:
+			sb.WriteString(fmt.Sprintf("%s%s: %v\n", indent, bold(k), val))
+		}
+	}
+}
+
+// jobExceptionDetails returns the full exception of a job along with the decoded request
+func jobExceptionDetails(s *metalcloud.AFC, format string) (string, error) {
+
+	funcName, params, err := decodeJobRequest(s.AFCFunctionName, s.AFCParamsJSON)
+	if err != nil {
+		return "", err
+	}
+
+	exception, err := parseJobException(s.AFCExceptionJSON)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "json", "JSON", "yaml", "YAML":
+		obj := map[string]interface{}{
+			"id":        s.AFCID,
+			"status":    s.AFCStatus,
+			"function":  funcName,
+			"params":    params,
+			"exception": exception,
+		}
+		return tableformatter.RenderRawObject(obj, format, "")
+	}
+
+	sb := strings.Builder{}
+
+	sb.WriteString(fmt.Sprintf("Job #%d %s retries %s\n", s.AFCID, colorizeJobStatus(s.AFCStatus), colorizeJobRetries(s.AFCRetryCount, s.AFCRetryMax)))
+	sb.WriteString(fmt.Sprintf("%s: %s\n", bold("Function"), funcName))
+
+	paramsStr, err := json.MarshalIndent(params, "    ", "  ")
+	if err != nil {
+		return "", err
+	}
+	if str, ok := params.(string); ok {
+		paramsStr = []byte(str)
+	}
+	sb.WriteString(fmt.Sprintf("%s:\n    %s\n", bold("Parameters"), paramsStr))
+
+	if exception == nil {
+		sb.WriteString("No exception thrown\n")
+		return sb.String(), nil
+	}
+
+	sb.WriteString(fmt.Sprintf("%s:\n", bold("Exception")))
+	renderJobException(&sb, exception, "    ")
+
+	return sb.String(), nil
+}
+
 func durationSinceZuluUTC(t string) (time.Duration, error) {
 	startTime, err := time.Parse(time.RFC3339, t)
 	if err != nil {
@@ -652,3 +1065,44 @@ func durationSinceZuluUTC(t string) (time.Duration, error) {
 
 	return time.Now().Sub(startTime), nil
 }
+
+// waitForJobGroup blocks until all the jobs of a job group have finished. Returns an error if any of the jobs
+// has thrown an error or if the jobs have not finished within the timeout.
+func waitForJobGroup(afcGroupID int, timeoutSeconds int, checkIntervalSeconds int, client metalcloud.MetalCloudClient) error {
+
+	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+
+	for {
+		list, err := client.AFCSearch(fmt.Sprintf("+afc_group_id:%d", afcGroupID), 0, 1000)
+		if err != nil {
+			return err
+		}
+
+		//an empty group means the jobs were not picked up yet
+		finished := len(*list) > 0
+
+		for _, j := range *list {
+			switch j.AFCStatus {
+			case "returned_success":
+			case "thrown_error":
+				message, _ := getJobExceptionMessage(j.AFCExceptionJSON)
+				if message == "" {
+					message = "no exception message"
+				}
+				return fmt.Errorf("job #%d (%s) failed: %s", j.AFCID, j.AFCFunctionName, message)
+			default:
+				finished = false
+			}
+		}
+
+		if finished {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %d seconds while waiting for job group #%d to finish", timeoutSeconds, afcGroupID)
+		}
+
+		time.Sleep(time.Duration(checkIntervalSeconds) * time.Second)
+	}
+}
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
@@ -135,3 +140,198 @@ func TestJobsGetCmd(t *testing.T) {
 	Expect(ret).To(ContainSubstring("ID,STATUS,DURATION,AFFECTS,RETRIES,REQUEST,RESPONSE"))
 
 }
+
+func TestJobsGetFollow(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	running := metalcloud.AFC{
+		AFCID:               13,
+		AFCCreatedTimestamp: "2006-01-02T15:04:05Z",
+		AFCFunctionName:     "test_func",
+		AFCStatus:           "running",
+		AFCRetryMax:         3,
+	}
+
+	retrying := running
+	retrying.AFCStatus = "thrown_error_while_retrying"
+	retrying.AFCRetryCount = 1
+	retrying.AFCExceptionJSON = "{\"message\":\"first failure\"}"
+
+	success := running
+	success.AFCStatus = "returned_success"
+	success.AFCRetryCount = 1
+
+	gomock.InOrder(
+		client.EXPECT().AFCGet(13).Return(&running, nil).Times(2),
+		client.EXPECT().AFCGet(13).Return(&retrying, nil).Times(1),
+		client.EXPECT().AFCGet(13).Return(&success, nil).Times(1),
+	)
+
+	var stdin bytes.Buffer
+	var stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	//an interval below 1 second would flood the API
+	cmd := MakeCommand(map[string]interface{}{
+		"job_id":          "13",
+		"follow":          true,
+		"follow_interval": 0,
+	})
+
+	_, err := jobGetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("-follow-interval"))
+
+	//follow the job without waiting between checks
+	err = jobFollow(13, 0, client)
+	Expect(err).To(BeNil())
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	Expect(lines).To(HaveLen(3))
+	Expect(lines[0]).To(ContainSubstring("running"))
+	Expect(lines[1]).To(ContainSubstring("first failure"))
+	Expect(lines[2]).To(ContainSubstring("returned_success"))
+}
+
+func TestJobsGetException(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	afc := metalcloud.AFC{
+		AFCID:               13,
+		AFCCreatedTimestamp: "2006-01-02T15:04:05Z",
+		AFCFunctionName:     "infrastructure_provision",
+		AFCParamsJSON:       "[ \"param1\",\"real_func\",[\"real_param1\",10] ]",
+		AFCStatus:           "thrown_error",
+		AFCExceptionJSON:    "{\"type\":\"Exception\",\"message\":\"top failure\",\"code\":500,\"stack\":\"frame1\\nframe2\",\"previous\":{\"type\":\"IOError\",\"message\":\"root cause\"}}",
+	}
+
+	client.EXPECT().
+		AFCGet(13).
+		Return(&afc, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"job_id":    "13",
+		"exception": true,
+	})
+
+	ret, err := jobGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("real_func"))
+	Expect(ret).To(ContainSubstring("real_param1"))
+	Expect(ret).To(ContainSubstring("top failure"))
+	Expect(ret).To(ContainSubstring("    frame2"))
+	Expect(ret).To(ContainSubstring("root cause"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"job_id":    "13",
+		"exception": true,
+		"format":    "json",
+	})
+
+	ret, err = jobGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m map[string]interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m["function"]).To(Equal("real_func"))
+	Expect(m["exception"].(map[string]interface{})["previous"].(map[string]interface{})["message"]).To(Equal("root cause"))
+}
+
+func TestJobsBulkRetryCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	list := []metalcloud.AFCSearchResult{
+		{
+			AFCID:           10,
+			AFCFunctionName: "test_func",
+			AFCStatus:       "thrown_error",
+		},
+		{
+			AFCID:           11,
+			AFCFunctionName: "test_func2",
+			AFCStatus:       "thrown_error",
+		},
+	}
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    100,
+		InfrastructureLabel: "test",
+	}
+
+	client.EXPECT().
+		InfrastructureGetByLabel("test").
+		Return(&infra, nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCSearch("+afc_status:thrown_error +infrastructure_id:100 +server_id:20", 0, 100).
+		Return(&list, nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCRetryCall(10).
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		AFCRetryCall(11).
+		Return(fmt.Errorf("cannot retry")).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"filter":                     "afc_status:thrown_error",
+		"infrastructure_id_or_label": "test",
+		"server_id":                  20,
+		"limit":                      100,
+		"concurrency":                2,
+		"autoconfirm":                true,
+		"format":                     "json",
+	})
+
+	ret, err := jobRetryCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []map[string]interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+	Expect(m).To(HaveLen(2))
+
+	results := map[int]string{}
+	for _, r := range m {
+		results[int(r["ID"].(float64))] = r["RESULT"].(string)
+	}
+	Expect(results[10]).To(ContainSubstring("ok"))
+	Expect(results[11]).To(ContainSubstring("cannot retry"))
+
+	//without confirmation nothing is executed
+	client.EXPECT().
+		AFCSearch("+afc_status:thrown_error", 0, 100).
+		Return(&list, nil).
+		Times(1)
+
+	var stdin bytes.Buffer
+	var stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+	stdin.Write([]byte("no\n"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter": "afc_status:thrown_error",
+		"limit":  100,
+	})
+
+	_, err = jobSkipCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
@@ -25,6 +27,81 @@ var networkCmds = []Command{
 		},
 		ExecuteFunc: networkListCmd,
 	},
+	{
+		Description:  "Create a network.",
+		Subject:      "network",
+		AltSubject:   "nw",
+		Predicate:    "create",
+		AltPredicate: "new",
+		FlagSet:      flag.NewFlagSet("create network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label":   c.FlagSet.String("infra", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that the label can be ambigous."),
+				"network_type":                 c.FlagSet.String("type", _nilDefaultStr, red("(Required)")+" Network's type. Supported values are 'wan','lan','san'."),
+				"network_label":                c.FlagSet.String("label", _nilDefaultStr, "Network's label."),
+				"network_subdomain":            c.FlagSet.String("subdomain", _nilDefaultStr, "Network's subdomain."),
+				"network_lan_autoallocate_ips": c.FlagSet.Bool("lan-autoallocate-ips", false, green("(Flag)")+" If set, IPs will be automatically allocated on this LAN network."),
+				"return_id":                    c.FlagSet.Bool("return-id", false, green("(Flag)")+" If set will print the ID of the created network. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: networkCreateCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Edit a network.",
+		Subject:      "network",
+		AltSubject:   "nw",
+		Predicate:    "edit",
+		AltPredicate: "update",
+		FlagSet:      flag.NewFlagSet("edit network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label":             c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Network's id or label. Note that the label can be ambigous."),
+				"network_label":                   c.FlagSet.String("label", _nilDefaultStr, "Network's new label."),
+				"network_subdomain":               c.FlagSet.String("subdomain", _nilDefaultStr, "Network's new subdomain."),
+				"network_lan_autoallocate_ips":    c.FlagSet.Bool("lan-autoallocate-ips", false, green("(Flag)")+" If set, IPs will be automatically allocated on this LAN network."),
+				"no_network_lan_autoallocate_ips": c.FlagSet.Bool("no-lan-autoallocate-ips", false, green("(Flag)")+" If set, IPs will no longer be automatically allocated on this LAN network."),
+			}
+		},
+		ExecuteFunc: networkEditCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Delete a network.",
+		Subject:      "network",
+		AltSubject:   "nw",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Network's id or label. Note that the label can be ambigous."),
+				"autoconfirm":         c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: networkDeleteCmd,
+		Endpoint:    UserEndpoint,
+	},
+	{
+		Description:  "Join a network into another network, possibly from a different infrastructure.",
+		Subject:      "network",
+		AltSubject:   "nw",
+		Predicate:    "join",
+		AltPredicate: "merge",
+		FlagSet:      flag.NewFlagSet("join network", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"network_id_or_label":         c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" The id or label of the network that will be kept. Note that the label can be ambigous."),
+				"network_to_join_id_or_label": c.FlagSet.String("join", _nilDefaultStr, red("(Required)")+" The id or label of the network that will be joined and then deleted. Note that the label can be ambigous."),
+				"autoconfirm":                 c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: networkJoinCmd,
+		Endpoint:    UserEndpoint,
+		Example: `
+metalcloud-cli network join --id 100 --join 200 #the interfaces attached to network 200 will be moved to network 100 and network 200 will be deleted
+		`,
+	},
 }
 
 func networkListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -99,3 +176,181 @@ func networkListCmd(c *Command, client metalcloud.MetalCloudClient) (string, err
 
 	return tableNetworkAttachments.RenderTable("", subtitleNetworkAttachmentsRender, getStringParam(c.Arguments["format"]))
 }
+
+func networkCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("infra", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	networkType, ok := getStringParamOk(c.Arguments["network_type"])
+	if !ok {
+		return "", fmt.Errorf("-type is required")
+	}
+
+	switch networkType {
+	case "wan", "lan", "san":
+	default:
+		return "", fmt.Errorf("invalid network type %s. Supported values are 'wan','lan','san'", networkType)
+	}
+
+	network := metalcloud.Network{
+		NetworkType:               networkType,
+		NetworkLabel:              getStringParam(c.Arguments["network_label"]),
+		NetworkSubdomain:          getStringParam(c.Arguments["network_subdomain"]),
+		NetworkLANAutoAllocateIPs: getBoolParam(c.Arguments["network_lan_autoallocate_ips"]),
+	}
+
+	retNetwork, err := client.NetworkCreate(infra.InfrastructureID, network)
+	if err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", retNetwork.NetworkID), nil
+	}
+
+	return "", nil
+}
+
+func networkEditCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if retNetwork.NetworkOperation == nil {
+		return "", fmt.Errorf("network %d has no operation object", retNetwork.NetworkID)
+	}
+
+	op := *retNetwork.NetworkOperation
+
+	if v, ok := getStringParamOk(c.Arguments["network_label"]); ok {
+		op.NetworkLabel = v
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["network_subdomain"]); ok {
+		op.NetworkSubdomain = v
+	}
+
+	enable := getBoolParam(c.Arguments["network_lan_autoallocate_ips"])
+	disable := getBoolParam(c.Arguments["no_network_lan_autoallocate_ips"])
+
+	if enable && disable {
+		return "", fmt.Errorf("-lan-autoallocate-ips and -no-lan-autoallocate-ips cannot be used together")
+	}
+
+	if enable {
+		op.NetworkLANAutoAllocateIPs = true
+	}
+
+	if disable {
+		op.NetworkLANAutoAllocateIPs = false
+	}
+
+	_, err = client.NetworkEdit(retNetwork.NetworkID, op)
+
+	return "", err
+}
+
+func networkDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deleting %s network %s (%d) from infrastructure %d.  Are you sure? Type \"yes\" to continue:",
+			retNetwork.NetworkType,
+			retNetwork.NetworkLabel, retNetwork.NetworkID,
+			retNetwork.InfrastructureID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	err = client.NetworkDelete(retNetwork.NetworkID)
+
+	return "", err
+}
+
+func networkJoinCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retNetwork, err := getNetworkFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retNetworkToJoin, err := getNetworkFromCommandParam("network_to_join_id_or_label", "join", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	if retNetwork.NetworkID == retNetworkToJoin.NetworkID {
+		return "", fmt.Errorf("cannot join network %d with itself", retNetwork.NetworkID)
+	}
+
+	if retNetwork.NetworkType != retNetworkToJoin.NetworkType {
+		return "", fmt.Errorf("cannot join %s network %d with %s network %d", retNetwork.NetworkType, retNetwork.NetworkID, retNetworkToJoin.NetworkType, retNetworkToJoin.NetworkID)
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Joining %s network %s (%d) of infrastructure %d into network %s (%d) of infrastructure %d. Network %s (%d) will be deleted.  Are you sure? Type \"yes\" to continue:",
+			retNetworkToJoin.NetworkType,
+			retNetworkToJoin.NetworkLabel, retNetworkToJoin.NetworkID, retNetworkToJoin.InfrastructureID,
+			retNetwork.NetworkLabel, retNetwork.NetworkID, retNetwork.InfrastructureID,
+			retNetworkToJoin.NetworkLabel, retNetworkToJoin.NetworkID)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	err = client.NetworkJoin(retNetwork.NetworkID, retNetworkToJoin.NetworkID)
+
+	return "", err
+}
+
+func getNetworkFromCommand(paramName string, c *Command, client metalcloud.MetalCloudClient) (*metalcloud.Network, error) {
+	return getNetworkFromCommandParam("network_id_or_label", paramName, c, client)
+}
+
+func getNetworkFromCommandParam(internalParamName string, paramName string, c *Command, client metalcloud.MetalCloudClient) (*metalcloud.Network, error) {
+
+	m, err := getParam(c, internalParamName, paramName)
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+	if isID {
+		return client.NetworkGet(id)
+	}
+	return client.NetworkGetByLabel(label)
+}
@@ -141,3 +141,182 @@ func TestNetworkListCmd(t *testing.T) {
 	_, err = networkListCmd(&cmd, client)
 	Expect(err).NotTo(BeNil())
 }
+
+func TestNetworkCreateCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	nw := metalcloud.Network{
+		NetworkType:               "lan",
+		NetworkLabel:              "lan01",
+		NetworkLANAutoAllocateIPs: true,
+	}
+
+	retNw := nw
+	retNw.NetworkID = 100
+
+	client.EXPECT().
+		NetworkCreate(infra.InfrastructureID, nw).
+		Return(&retNw, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label":   "10002",
+		"network_type":                 "lan",
+		"network_label":                "lan01",
+		"network_lan_autoallocate_ips": true,
+		"return_id":                    true,
+	})
+
+	ret, err := networkCreateCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("100"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"network_type":               "wrong",
+	})
+
+	_, err = networkCreateCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestNetworkEditCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	nw := metalcloud.Network{
+		NetworkID:    100,
+		NetworkType:  "lan",
+		NetworkLabel: "lan01",
+		NetworkOperation: &metalcloud.NetworkOperation{
+			NetworkID:                 100,
+			NetworkType:               "lan",
+			NetworkLabel:              "lan01",
+			NetworkLANAutoAllocateIPs: true,
+		},
+	}
+
+	client.EXPECT().
+		NetworkGet(100).
+		Return(&nw, nil).
+		AnyTimes()
+
+	op := *nw.NetworkOperation
+	op.NetworkLabel = "lan02"
+	op.NetworkLANAutoAllocateIPs = false
+
+	client.EXPECT().
+		NetworkEdit(100, op).
+		Return(&nw, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"network_id_or_label":             "100",
+		"network_label":                   "lan02",
+		"no_network_lan_autoallocate_ips": true,
+	})
+
+	_, err := networkEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":             "100",
+		"network_lan_autoallocate_ips":    true,
+		"no_network_lan_autoallocate_ips": true,
+	})
+
+	_, err = networkEditCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestNetworkDeleteAndJoinCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	nw1 := metalcloud.Network{
+		NetworkID:        100,
+		NetworkType:      "lan",
+		InfrastructureID: 1,
+	}
+
+	nw2 := metalcloud.Network{
+		NetworkID:        200,
+		NetworkType:      "lan",
+		InfrastructureID: 2,
+	}
+
+	nw3 := metalcloud.Network{
+		NetworkID:        300,
+		NetworkType:      "wan",
+		InfrastructureID: 2,
+	}
+
+	client.EXPECT().
+		NetworkGet(100).
+		Return(&nw1, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(200).
+		Return(&nw2, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkGet(300).
+		Return(&nw3, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		NetworkDelete(100).
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"network_id_or_label": "100",
+		"autoconfirm":         true,
+	})
+
+	_, err := networkDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	client.EXPECT().
+		NetworkJoin(100, 200).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":         "100",
+		"network_to_join_id_or_label": "200",
+		"autoconfirm":                 true,
+	})
+
+	_, err = networkJoinCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//different network types cannot be joined
+	cmd = MakeCommand(map[string]interface{}{
+		"network_id_or_label":         "100",
+		"network_to_join_id_or_label": "300",
+		"autoconfirm":                 true,
+	})
+
+	_, err = networkJoinCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+)
+
+var rackCmds = []Command{
+
+	{
+		Description:  "Lists the racks of a datacenter and their utilization.",
+		Subject:      "rack",
+		AltSubject:   "racks",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list racks", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter_name": c.FlagSet.String("datacenter", _nilDefaultStr, red("(Required)")+" Label of the datacenter."),
+				"rack_height":     c.FlagSet.Int("height", 42, "The height of the racks in U."),
+				"format":          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: rackListCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Shows the elevation of a rack.",
+		Subject:      "rack",
+		AltSubject:   "racks",
+		Predicate:    "show",
+		AltPredicate: "get",
+		FlagSet:      flag.NewFlagSet("show rack", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter_name": c.FlagSet.String("datacenter", _nilDefaultStr, red("(Required)")+" Label of the datacenter."),
+				"rack_name":       c.FlagSet.String("rack", _nilDefaultStr, red("(Required)")+" The name of the rack, as set with 'server rack-info-set'."),
+				"rack_height":     c.FlagSet.Int("height", 42, "The height of the rack in U. Extended automatically if equipment is placed higher."),
+			}
+		},
+		ExecuteFunc: rackShowCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli rack show --datacenter dc-1 --rack R01
+`,
+	},
+}
+
+// rackEquipment is a server or a switch with its rack position.
+// LowerU and UpperU are 0 if the position is not set or is invalid.
+type rackEquipment struct {
+	Kind   string
+	ID     int
+	Label  string
+	Status string
+	Rack   string
+	LowerU int
+	UpperU int
+}
+
+// Placed returns true if the equipment has a valid U range
+func (e rackEquipment) Placed() bool {
+	return e.LowerU > 0 && e.UpperU >= e.LowerU
+}
+
+// Name returns a short description of the equipment such as "server #100"
+func (e rackEquipment) Name() string {
+	return fmt.Sprintf("%s #%d", e.Kind, e.ID)
+}
+
+// rackOverlap is a U occupied by more than one piece of equipment
+type rackOverlap struct {
+	Rack      string
+	U         int
+	Equipment []rackEquipment
+}
+
+// getRackEquipment returns the servers and switches of a datacenter with their rack positions
+func getRackEquipment(datacenterName string, client metalcloud.MetalCloudClient) ([]rackEquipment, error) {
+
+	servers, err := client.ServersSearch(fmt.Sprintf("+datacenter_name:%s", datacenterName))
+	if err != nil {
+		return nil, err
+	}
+
+	switches, err := client.SwitchDevices(datacenterName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	list := []rackEquipment{}
+
+	for _, s := range *servers {
+		if s.ServerStatus == "decommissioned" {
+			continue
+		}
+
+		e := rackEquipment{
+			Kind:   "server",
+			ID:     s.ServerID,
+			Label:  s.ServerSerialNumber,
+			Status: s.ServerStatus,
+			Rack:   strings.TrimSpace(s.ServerRackName),
+		}
+		e.LowerU, e.UpperU = parseRackUnits(s.ServerRackPositionLowerUnit, s.ServerRackPositionUpperUnit)
+
+		list = append(list, e)
+	}
+
+	for _, sw := range *switches {
+		e := rackEquipment{
+			Kind:  "switch",
+			ID:    sw.NetworkEquipmentID,
+			Label: sw.NetworkEquipmentIdentifierString,
+			Rack:  strings.TrimSpace(sw.NetworkEquipmentDatacenterRack),
+		}
+		e.LowerU, e.UpperU = parseRackUnits(strconv.Itoa(sw.NetworkEquipmentRackPositionLowerUnit), strconv.Itoa(sw.NetworkEquipmentRackPositionUpperUnit))
+
+		list = append(list, e)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+// parseRackUnits parses the lower and upper U of a rack position. If only one of them is set the equipment occupies a single U.
+// Returns 0, 0 if the position is not set or is invalid.
+func parseRackUnits(lower string, upper string) (int, int) {
+
+	l, lErr := strconv.Atoi(strings.TrimSpace(lower))
+	u, uErr := strconv.Atoi(strings.TrimSpace(upper))
+
+	if lErr != nil || l <= 0 {
+		l = 0
+	}
+	if uErr != nil || u <= 0 {
+		u = 0
+	}
+
+	if l == 0 {
+		l = u
+	}
+	if u == 0 {
+		u = l
+	}
+
+	if l == 0 || u < l {
+		return 0, 0
+	}
+
+	return l, u
+}
+
+// getRackOccupancy returns the equipment occupying each U of a rack and the U positions occupied by more than one piece of equipment
+func getRackOccupancy(rack string, equipment []rackEquipment) (map[int][]rackEquipment, []rackOverlap) {
+
+	units := map[int][]rackEquipment{}
+
+	for _, e := range equipment {
+		if e.Rack != rack || !e.Placed() {
+			continue
+		}
+		for u := e.LowerU; u <= e.UpperU; u++ {
+			units[u] = append(units[u], e)
+		}
+	}
+
+	overlaps := []rackOverlap{}
+	for u, list := range units {
+		if len(list) > 1 {
+			overlaps = append(overlaps, rackOverlap{Rack: rack, U: u, Equipment: list})
+		}
+	}
+
+	sort.Slice(overlaps, func(i, j int) bool {
+		return overlaps[i].U > overlaps[j].U
+	})
+
+	return units, overlaps
+}
+
+func rackListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	datacenterName, ok := getStringParamOk(c.Arguments["datacenter_name"])
+	if !ok {
+		return "", fmt.Errorf("-datacenter is required")
+	}
+
+	equipment, err := getRackEquipment(datacenterName, client)
+	if err != nil {
+		return "", err
+	}
+
+	height := getIntParam(c.Arguments["rack_height"])
+	if height < 1 {
+		return "", fmt.Errorf("-height must be positive")
+	}
+
+	racks := map[string][]rackEquipment{}
+	unracked := 0
+
+	for _, e := range equipment {
+		if e.Rack == "" {
+			unracked++
+			continue
+		}
+		racks[e.Rack] = append(racks[e.Rack], e)
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "RACK",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SERVERS",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "SWITCHES",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "USED_U",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "FREE_U",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "UTILIZATION",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "OVERLAPS",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "UNPLACED",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+	}
+
+	data := [][]interface{}{}
+
+	for rack, list := range racks {
+
+		counts := map[string]int{}
+		unplaced := 0
+		for _, e := range list {
+			counts[e.Kind]++
+			if !e.Placed() {
+				unplaced++
+			}
+		}
+
+		units, overlaps := getRackOccupancy(rack, list)
+
+		rackHeight := height
+		for u := range units {
+			if u > rackHeight {
+				rackHeight = u
+			}
+		}
+
+		data = append(data, []interface{}{
+			rack,
+			counts["server"],
+			counts["switch"],
+			len(units),
+			rackHeight - len(units),
+			fmt.Sprintf("%.0f%%", float64(len(units))*100/float64(rackHeight)),
+			len(overlaps),
+			unplaced,
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := fmt.Sprintf("Datacenter %s. %d servers and switches have no rack set.", datacenterName, unracked)
+
+	return table.RenderTable("Racks", subtitle, getStringParam(c.Arguments["format"]))
+}
+
+func rackShowCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	datacenterName, ok := getStringParamOk(c.Arguments["datacenter_name"])
+	if !ok {
+		return "", fmt.Errorf("-datacenter is required")
+	}
+
+	rack, ok := getStringParamOk(c.Arguments["rack_name"])
+	if !ok {
+		return "", fmt.Errorf("-rack is required")
+	}
+
+	equipment, err := getRackEquipment(datacenterName, client)
+	if err != nil {
+		return "", err
+	}
+
+	inRack := []rackEquipment{}
+	unracked := 0
+	for _, e := range equipment {
+		if e.Rack == rack {
+			inRack = append(inRack, e)
+		}
+		if e.Rack == "" {
+			unracked++
+		}
+	}
+
+	if len(inRack) == 0 {
+		return "", fmt.Errorf("no servers or switches found in rack %s of datacenter %s", rack, datacenterName)
+	}
+
+	ret := renderRackElevation(rack, inRack, getIntParam(c.Arguments["rack_height"]))
+
+	if unracked > 0 {
+		ret += fmt.Sprintf("\n%d servers and switches of datacenter %s have no rack set.\n", unracked, datacenterName)
+	}
+
+	return ret, nil
+}
+
+// renderRackElevation draws the rack from the top U down. Equipment spanning several U is drawn
+// with its description on the top U and a continuation mark on the others.
+func renderRackElevation(rack string, equipment []rackEquipment, height int) string {
+
+	const width = 50
+
+	units, overlaps := getRackOccupancy(rack, equipment)
+
+	for u := range units {
+		if u > height {
+			height = u
+		}
+	}
+
+	var sb strings.Builder
+
+	border := "    +" + strings.Repeat("-", width+2) + "+\n"
+
+	sb.WriteString(fmt.Sprintf("Rack %s\n", rack))
+	sb.WriteString(border)
+
+	for u := height; u >= 1; u-- {
+
+		list := units[u]
+		text := ""
+		status := ""
+
+		switch {
+		case len(list) > 1:
+			names := []string{}
+			for _, e := range list {
+				names = append(names, e.Name())
+			}
+			text = "!! overlap: " + strings.Join(names, ", ")
+		case len(list) == 1:
+			e := list[0]
+			if u == e.UpperU {
+				text = strings.TrimSpace(fmt.Sprintf("%s %s %s", e.Name(), e.Label, e.Status))
+				status = e.Status
+			} else {
+				text = "  |"
+			}
+		}
+
+		if len(text) > width {
+			text = text[:width]
+		}
+
+		line := fmt.Sprintf("%-*s", width, text)
+
+		switch {
+		case len(list) > 1:
+			line = red(line)
+		case status != "" && strings.HasSuffix(text, status):
+			line = strings.TrimSuffix(text, status) + colorizeServerStatus(status) + strings.Repeat(" ", width-len(text))
+		}
+
+		sb.WriteString(fmt.Sprintf("U%-3d| %s |\n", u, line))
+	}
+
+	sb.WriteString(border)
+
+	if len(overlaps) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%s\n", red("Overlapping equipment:")))
+		for _, o := range overlaps {
+			names := []string{}
+			for _, e := range o.Equipment {
+				names = append(names, fmt.Sprintf("%s (U%d-U%d)", e.Name(), e.LowerU, e.UpperU))
+			}
+			sb.WriteString(fmt.Sprintf("  U%d: %s\n", o.U, strings.Join(names, ", ")))
+		}
+	}
+
+	unplaced := []string{}
+	for _, e := range equipment {
+		if !e.Placed() {
+			unplaced = append(unplaced, fmt.Sprintf("  %s %s", e.Name(), e.Label))
+		}
+	}
+
+	if len(unplaced) > 0 {
+		sb.WriteString(fmt.Sprintf("\n%s\n", yellow("Equipment in this rack without a valid U position:")))
+		sb.WriteString(strings.Join(unplaced, "\n") + "\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+func setupRackTestClient(t *testing.T) *mock_metalcloud.MockMetalCloudClient {
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	servers := []metalcloud.ServerSearchResult{
+		{ServerID: 1, ServerSerialNumber: "SN1", ServerStatus: "available", ServerRackName: "R01", ServerRackPositionLowerUnit: "1", ServerRackPositionUpperUnit: "2"},
+		{ServerID: 2, ServerSerialNumber: "SN2", ServerStatus: "used", ServerRackName: "R01", ServerRackPositionLowerUnit: "2", ServerRackPositionUpperUnit: "3"},
+		{ServerID: 3, ServerSerialNumber: "SN3", ServerStatus: "available", ServerRackName: "R01"},
+		{ServerID: 4, ServerSerialNumber: "SN4", ServerStatus: "available", ServerRackName: "R02", ServerRackPositionLowerUnit: "10", ServerRackPositionUpperUnit: "10"},
+		{ServerID: 5, ServerSerialNumber: "SN5", ServerStatus: "available"},
+		{ServerID: 6, ServerSerialNumber: "SN6", ServerStatus: "decommissioned"},
+	}
+
+	switches := map[string]metalcloud.SwitchDevice{
+		"leaf-1": {
+			NetworkEquipmentID:                    10,
+			NetworkEquipmentIdentifierString:      "leaf-1",
+			NetworkEquipmentDatacenterRack:        "R01",
+			NetworkEquipmentRackPositionLowerUnit: 42,
+			NetworkEquipmentRackPositionUpperUnit: 42,
+		},
+	}
+
+	client.EXPECT().
+		ServersSearch("+datacenter_name:dc-1").
+		Return(&servers, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SwitchDevices("dc-1", "").
+		Return(&switches, nil).
+		AnyTimes()
+
+	return client
+}
+
+func TestParseRackUnits(t *testing.T) {
+	RegisterTestingT(t)
+
+	cases := []struct {
+		lower string
+		upper string
+		l     int
+		u     int
+	}{
+		{"1", "2", 1, 2},
+		{"5", "", 5, 5},
+		{"", "7", 7, 7},
+		{"", "", 0, 0},
+		{"0", "0", 0, 0},
+		{"4", "2", 0, 0},
+		{"x", "2", 2, 2},
+	}
+
+	for _, c := range cases {
+		l, u := parseRackUnits(c.lower, c.upper)
+		Expect(l).To(Equal(c.l), "%s-%s", c.lower, c.upper)
+		Expect(u).To(Equal(c.u), "%s-%s", c.lower, c.upper)
+	}
+}
+
+func TestRackListCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	client := setupRackTestClient(t)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"rack_height":     42,
+		"format":          "json",
+	})
+
+	ret, err := rackListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(2))
+
+	Expect(rows[0]["RACK"]).To(Equal("R01"))
+	Expect(rows[0]["SERVERS"]).To(Equal(3.0))
+	Expect(rows[0]["SWITCHES"]).To(Equal(1.0))
+	Expect(rows[0]["USED_U"]).To(Equal(4.0))
+	Expect(rows[0]["FREE_U"]).To(Equal(38.0))
+	Expect(rows[0]["OVERLAPS"]).To(Equal(1.0))
+	Expect(rows[0]["UNPLACED"]).To(Equal(1.0))
+
+	Expect(rows[1]["RACK"]).To(Equal("R02"))
+	Expect(rows[1]["USED_U"]).To(Equal(1.0))
+
+	cmd = MakeCommand(map[string]interface{}{})
+	_, err = rackListCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestRackShowCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	client := setupRackTestClient(t)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"rack_name":       "R01",
+		"rack_height":     42,
+	})
+
+	ret, err := rackShowCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	Expect(ret).To(ContainSubstring("U42 | switch #10 leaf-1"))
+	Expect(ret).To(ContainSubstring("U3  | server #2 SN2"))
+	Expect(ret).To(ContainSubstring("overlap: server #1, server #2"))
+	Expect(ret).To(ContainSubstring("U2: server #1 (U1-U2), server #2 (U2-U3)"))
+	Expect(ret).To(ContainSubstring("server #3 SN3"))
+	Expect(ret).To(ContainSubstring("1 servers and switches of datacenter dc-1 have no rack set"))
+	Expect(ret).NotTo(ContainSubstring("SN4"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"rack_name":       "R99",
+		"rack_height":     42,
+	})
+
+	_, err = rackShowCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
@@ -25,6 +31,49 @@ var reportsCmds = []Command{
 		ExecuteFunc: devicesListCmd,
 		Endpoint:    DeveloperEndpoint,
 	},
+	{
+		Description:  "Cabling report of a datacenter.",
+		Subject:      "report",
+		AltSubject:   "report",
+		Predicate:    "cabling",
+		AltPredicate: "cables",
+		FlagSet:      flag.NewFlagSet("cabling report", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter_name":     c.FlagSet.String("datacenter", _nilDefaultStr, red("(Required)")+" Label of the datacenter."),
+				"expected_interfaces": c.FlagSet.Int("expected-interfaces", _nilDefaultInt, "The number of interfaces every server should have connected. Defaults to the most common number of connected interfaces of the servers of the same server type."),
+				"issues_only":         c.FlagSet.Bool("issues-only", false, green("(Flag)")+" If set only the interfaces and servers with issues are returned."),
+				"format":              c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: cablingReportCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Validates the cabling of a datacenter against a cabling plan.",
+		Subject:      "validate",
+		AltSubject:   "check",
+		Predicate:    "cabling",
+		AltPredicate: "cables",
+		FlagSet:      flag.NewFlagSet("validate cabling", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter_name": c.FlagSet.String("datacenter", _nilDefaultStr, red("(Required)")+" Label of the datacenter."),
+				"cabling_file":    c.FlagSet.String("f", _nilDefaultStr, red("(Required)")+" The cabling plan, a csv file with the columns: "+strings.Join(cablingPlanColumns, ",")+"."),
+				"format":          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: validateCablingCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli validate cabling --datacenter dc-1 -f expected.csv
+
+#expected.csv. The server is the id or the serial number, the interface is the index or the mac address of the server interface.
+server,interface,switch,switch_port
+SN0001,0,leaf-1,Ethernet1
+SN0001,1,leaf-2,Ethernet1
+`,
+	},
 }
 
 func getActiveServers(datacenter string, client metalcloud.MetalCloudClient) (*[]metalcloud.ServerSearchResult, error) {
@@ -194,3 +243,487 @@ func devicesListCmd(c *Command, client metalcloud.MetalCloudClient) (string, err
 	return table.RenderTable(fmt.Sprintf("Records (%d active devices across all datacenters)", totalDevices), title, getStringParam(c.Arguments["format"]))
 
 }
+
+// cablingMap holds the servers of a datacenter and the switch interfaces they are connected to
+type cablingMap struct {
+	servers        []metalcloud.ServerSearchResult
+	interfaces     map[int][]metalcloud.SwitchInterfaceSearchResult
+	pairedSwitches map[int]bool
+}
+
+func getCablingMap(datacenter string, client metalcloud.MetalCloudClient) (*cablingMap, error) {
+
+	servers, err := client.ServersSearch(fmt.Sprintf("+datacenter_name:%s", datacenter))
+	if err != nil {
+		return nil, err
+	}
+
+	switches, err := getAllActiveSwitches(datacenter, client)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := client.SwitchDeviceLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	m := cablingMap{
+		interfaces:     map[int][]metalcloud.SwitchInterfaceSearchResult{},
+		pairedSwitches: map[int]bool{},
+	}
+
+	for _, l := range *links {
+		m.pairedSwitches[l.NetworkEquipmentID1] = true
+		m.pairedSwitches[l.NetworkEquipmentID2] = true
+	}
+
+	for _, s := range *servers {
+		if s.ServerStatus != "decommissioned" {
+			m.servers = append(m.servers, s)
+		}
+	}
+
+	sort.Slice(m.servers, func(i, j int) bool {
+		return m.servers[i].ServerID < m.servers[j].ServerID
+	})
+
+	for _, sw := range *switches {
+		list, err := client.SwitchInterfaceSearch(fmt.Sprintf("network_equipment_id:%d", sw.NetworkEquipmentID))
+		if err != nil {
+			return nil, err
+		}
+		for _, intf := range *list {
+			m.interfaces[intf.ServerID] = append(m.interfaces[intf.ServerID], intf)
+		}
+	}
+
+	for id := range m.interfaces {
+		list := m.interfaces[id]
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].ServerInterfaceIndex < list[j].ServerInterfaceIndex
+		})
+	}
+
+	return &m, nil
+}
+
+// getExpectedInterfaceCounts returns the most common number of connected interfaces of the servers of each server type
+func (m cablingMap) getExpectedInterfaceCounts() map[string]int {
+
+	counts := map[string]map[int]int{}
+
+	for _, s := range m.servers {
+		n := len(m.interfaces[s.ServerID])
+		if s.ServerTypeName == "" || n == 0 {
+			continue
+		}
+		if counts[s.ServerTypeName] == nil {
+			counts[s.ServerTypeName] = map[int]int{}
+		}
+		counts[s.ServerTypeName][n]++
+	}
+
+	expected := map[string]int{}
+	for serverType, c := range counts {
+		best := 0
+		for n, count := range c {
+			//on a tie the higher number of interfaces is expected
+			if count > c[best] || (count == c[best] && n > best) {
+				best = n
+			}
+		}
+		expected[serverType] = best
+	}
+
+	return expected
+}
+
+func cablingReportCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	datacenter, ok := getStringParamOk(c.Arguments["datacenter_name"])
+	if !ok {
+		return "", fmt.Errorf("-datacenter is required")
+	}
+
+	m, err := getCablingMap(datacenter, client)
+	if err != nil {
+		return "", err
+	}
+
+	expectedCounts := m.getExpectedInterfaceCounts()
+	expectedOverride, expectedOverrideOk := getIntParamOk(c.Arguments["expected_interfaces"])
+	issuesOnly := getBoolParam(c.Arguments["issues_only"])
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "SERVER_ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "SERIAL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SERVER_TYPE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "INTERFACE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "MAC",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 17,
+		},
+		{
+			FieldName: "SWITCH",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SWITCH_PORT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SPEED",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 8,
+		},
+		{
+			FieldName: "ISSUES",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+	}
+
+	data := [][]interface{}{}
+	serversWithIssues := 0
+
+	for _, s := range m.servers {
+
+		interfaces := m.interfaces[s.ServerID]
+
+		serverIssues := []string{}
+
+		expected := expectedCounts[s.ServerTypeName]
+		if expectedOverrideOk {
+			expected = expectedOverride
+		}
+
+		if len(interfaces) == 0 {
+			serverIssues = append(serverIssues, "no connected interfaces")
+		} else if len(interfaces) < expected {
+			serverIssues = append(serverIssues, fmt.Sprintf("%d of %d interfaces connected", len(interfaces), expected))
+		}
+
+		speeds := map[int]bool{}
+		for _, intf := range interfaces {
+			speeds[intf.ServerInterfaceCapacityMBPs] = true
+		}
+		if len(speeds) > 1 {
+			list := []int{}
+			for speed := range speeds {
+				list = append(list, speed)
+			}
+			sort.Ints(list)
+			strs := []string{}
+			for _, speed := range list {
+				strs = append(strs, formatLinkSpeed(speed))
+			}
+			serverIssues = append(serverIssues, "mismatched link speeds: "+strings.Join(strs, ", "))
+		}
+
+		if len(interfaces) == 0 {
+			data = append(data, []interface{}{s.ServerID, s.ServerSerialNumber, s.ServerTypeName, "", "", "", "", "", strings.Join(serverIssues, "; ")})
+			serversWithIssues++
+			continue
+		}
+
+		hasIssues := len(serverIssues) > 0
+
+		for i, intf := range interfaces {
+
+			issues := []string{}
+			if i == 0 {
+				issues = append(issues, serverIssues...)
+			}
+
+			if !m.pairedSwitches[intf.NetworkEquipmentID] {
+				issues = append(issues, "switch not in a switch pair")
+			}
+
+			if len(issues) > 0 {
+				hasIssues = true
+			}
+
+			//the other interfaces of a server with issues are kept for context
+			if issuesOnly && len(issues) == 0 && len(serverIssues) == 0 {
+				continue
+			}
+
+			data = append(data, []interface{}{
+				s.ServerID,
+				s.ServerSerialNumber,
+				s.ServerTypeName,
+				strconv.Itoa(intf.ServerInterfaceIndex),
+				intf.ServerInterfaceMACAddress,
+				intf.NetworkEquipmentIdentifierString,
+				intf.NetworkEquipmentInterfaceIdentifierString,
+				formatLinkSpeed(intf.ServerInterfaceCapacityMBPs),
+				strings.Join(issues, "; "),
+			})
+		}
+
+		if hasIssues {
+			serversWithIssues++
+		}
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := fmt.Sprintf("%d servers, %d with issues", len(m.servers), serversWithIssues)
+
+	return table.RenderTable(fmt.Sprintf("Cabling of datacenter %s", datacenter), subtitle, getStringParam(c.Arguments["format"]))
+}
+
+func formatLinkSpeed(mbps int) string {
+	if mbps >= 1000 && mbps%1000 == 0 {
+		return fmt.Sprintf("%d Gbps", mbps/1000)
+	}
+	return fmt.Sprintf("%d Mbps", mbps)
+}
+
+// cablingPlanRow is a connection of a cabling plan
+type cablingPlanRow struct {
+	Row        int
+	Server     string
+	Interface  string
+	Switch     string
+	SwitchPort string
+}
+
+var cablingPlanColumns = []string{"server", "interface", "switch", "switch_port"}
+
+func readCablingPlan(path string) ([]cablingPlanRow, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("cabling plan %s is empty", path)
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range cablingPlanColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("cabling plan %s has no %s column. The columns are: %s", path, name, strings.Join(cablingPlanColumns, ","))
+		}
+	}
+
+	get := func(record []string, name string) string {
+		if i := columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := []cablingPlanRow{}
+	for i, record := range records[1:] {
+		rows = append(rows, cablingPlanRow{
+			Row:        i + 1,
+			Server:     get(record, "server"),
+			Interface:  get(record, "interface"),
+			Switch:     get(record, "switch"),
+			SwitchPort: get(record, "switch_port"),
+		})
+	}
+
+	return rows, nil
+}
+
+func validateCablingCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	datacenter, ok := getStringParamOk(c.Arguments["datacenter_name"])
+	if !ok {
+		return "", fmt.Errorf("-datacenter is required")
+	}
+
+	path, ok := getStringParamOk(c.Arguments["cabling_file"])
+	if !ok {
+		return "", fmt.Errorf("-f is required")
+	}
+
+	plan, err := readCablingPlan(path)
+	if err != nil {
+		return "", err
+	}
+
+	m, err := getCablingMap(datacenter, client)
+	if err != nil {
+		return "", err
+	}
+
+	findServer := func(idOrSerial string) (metalcloud.ServerSearchResult, bool) {
+		for _, s := range m.servers {
+			if strconv.Itoa(s.ServerID) == idOrSerial || strings.EqualFold(s.ServerSerialNumber, idOrSerial) {
+				return s, true
+			}
+		}
+		return metalcloud.ServerSearchResult{}, false
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ROW",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 4,
+		},
+		{
+			FieldName: "SERVER",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "INTERFACE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 17,
+		},
+		{
+			FieldName: "EXPECTED",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 25,
+		},
+		{
+			FieldName: "ACTUAL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 25,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	data := [][]interface{}{}
+	failed := 0
+
+	//interfaces of the servers in the plan that were matched by a row
+	matched := map[int]map[int]bool{}
+	planned := map[int]metalcloud.ServerSearchResult{}
+
+	for _, row := range plan {
+
+		expected := fmt.Sprintf("%s %s", row.Switch, row.SwitchPort)
+		actual := ""
+		result := "ok"
+
+		server, ok := findServer(row.Server)
+
+		if !ok {
+			result = "unknown server"
+		} else {
+			planned[server.ServerID] = server
+			if matched[server.ServerID] == nil {
+				matched[server.ServerID] = map[int]bool{}
+			}
+
+			var intf *metalcloud.SwitchInterfaceSearchResult
+			for i, candidate := range m.interfaces[server.ServerID] {
+				if strconv.Itoa(candidate.ServerInterfaceIndex) == row.Interface || strings.EqualFold(candidate.ServerInterfaceMACAddress, row.Interface) {
+					intf = &m.interfaces[server.ServerID][i]
+					break
+				}
+			}
+
+			if intf == nil {
+				result = "missing"
+			} else {
+				matched[server.ServerID][intf.ServerInterfaceIndex] = true
+				actual = fmt.Sprintf("%s %s", intf.NetworkEquipmentIdentifierString, intf.NetworkEquipmentInterfaceIdentifierString)
+
+				if !strings.EqualFold(intf.NetworkEquipmentIdentifierString, row.Switch) || !strings.EqualFold(intf.NetworkEquipmentInterfaceIdentifierString, row.SwitchPort) {
+					result = "mismatch"
+				}
+			}
+		}
+
+		if result != "ok" {
+			failed++
+			result = red(result)
+		} else {
+			result = green(result)
+		}
+
+		data = append(data, []interface{}{
+			strconv.Itoa(row.Row),
+			row.Server,
+			row.Interface,
+			expected,
+			actual,
+			result,
+		})
+	}
+
+	//connected interfaces of the planned servers that are not in the plan
+	ids := []int{}
+	for id := range planned {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		for _, intf := range m.interfaces[id] {
+			if matched[id][intf.ServerInterfaceIndex] {
+				continue
+			}
+			failed++
+			data = append(data, []interface{}{
+				"",
+				planned[id].ServerSerialNumber,
+				strconv.Itoa(intf.ServerInterfaceIndex),
+				"",
+				fmt.Sprintf("%s %s", intf.NetworkEquipmentIdentifierString, intf.NetworkEquipmentInterfaceIdentifierString),
+				red("unexpected"),
+			})
+		}
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	ret, err := table.RenderTable("Cabling validation", fmt.Sprintf("%d connections in the plan, %d issues", len(plan), failed), getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("the cabling of datacenter %s does not match %s: %d issues", datacenter, path, failed)
+	}
+
+	return ret, nil
+}
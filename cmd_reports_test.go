@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
@@ -57,3 +61,180 @@ func TestDevicesListCmdWithWatch(t *testing.T) {
 
 const _storageListFixture = "[\r\n                {\r\n                    \"storage_pool_id\": 1,\r\n                    \"storage_pool_name\": \"UnityVSA\",\r\n                    \"storage_pool_status\": \"active\",\r\n                    \"storage_pool_in_maintenance\": false,\r\n                    \"datacenter_name\": \"us02-chi-qts01-dc\",\r\n                    \"storage_type\": \"iscsi_ssd\",\r\n                    \"user_id\": null,\r\n                    \"storage_pool_iscsi_host\": \"100.96.0.2\",\r\n                    \"storage_pool_iscsi_port\": 3260,\r\n                    \"storage_pool_capacity_total_cached_real_mbytes\": 505344,\r\n                    \"storage_pool_capacity_usable_cached_real_mbytes\": 505344,\r\n                    \"storage_pool_capacity_free_cached_real_mbytes\": 496128,\r\n                    \"storage_pool_capacity_used_cached_virtual_mbytes\": 122880\r\n                }\r\n            ]"
 const _datacenterList = "{\"test\":{\"datacenter_id\":6,\"datacenter_name\":\"test\",\"datacenter_name_parent\":null,\"user_id\":null,\"datacenter_is_master\":false,\"datacenter_is_maintenance\":false,\"datacenter_type\":\"metal_cloud\",\"datacenter_display_name\":\"US02 Chi QTS01 DC\",\"datacenter_hidden\":false,\"datacenter_created_timestamp\":\"2022-02-11T11:14:08Z\",\"datacenter_updated_timestamp\":\"2022-06-09T13:32:56Z\",\"type\":\"Datacenter\",\"datacenter_tags\":[]}}"
+
+func setupCablingTestClient(t *testing.T) *mock_metalcloud.MockMetalCloudClient {
+	ctrl := gomock.NewController(t)
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	servers := []metalcloud.ServerSearchResult{
+		{ServerID: 1, ServerSerialNumber: "SN1", ServerTypeName: "M.8", ServerStatus: "available"},
+		{ServerID: 2, ServerSerialNumber: "SN2", ServerTypeName: "M.8", ServerStatus: "used"},
+		{ServerID: 3, ServerSerialNumber: "SN3", ServerTypeName: "M.8", ServerStatus: "available"},
+		{ServerID: 4, ServerSerialNumber: "SN4", ServerTypeName: "M.8", ServerStatus: "available"},
+		{ServerID: 5, ServerSerialNumber: "SN5", ServerTypeName: "M.8", ServerStatus: "registering"},
+		{ServerID: 6, ServerSerialNumber: "SN6", ServerStatus: "decommissioned"},
+	}
+
+	client.EXPECT().
+		ServersSearch("+datacenter_name:dc-1").
+		Return(&servers, nil).
+		AnyTimes()
+
+	switches := map[string]metalcloud.SwitchDevice{
+		"leaf-1": {NetworkEquipmentID: 10, NetworkEquipmentIdentifierString: "leaf-1"},
+		"leaf-2": {NetworkEquipmentID: 11, NetworkEquipmentIdentifierString: "leaf-2"},
+		"leaf-3": {NetworkEquipmentID: 12, NetworkEquipmentIdentifierString: "leaf-3"},
+	}
+
+	client.EXPECT().
+		SwitchDevices("dc-1", "").
+		Return(&switches, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SwitchDeviceLinks().
+		Return(&map[int]metalcloud.SwitchDeviceLink{
+			1: {NetworkEquipmentLinkID: 1, NetworkEquipmentID1: 10, NetworkEquipmentID2: 11},
+		}, nil).
+		AnyTimes()
+
+	intf := func(serverID int, index int, switchID int, port string, speed int) metalcloud.SwitchInterfaceSearchResult {
+		return metalcloud.SwitchInterfaceSearchResult{
+			ServerID:                                  serverID,
+			ServerSerialNumber:                        fmt.Sprintf("SN%d", serverID),
+			ServerInterfaceIndex:                      index,
+			ServerInterfaceMACAddress:                 fmt.Sprintf("00:00:00:00:%02d:%02d", serverID, index),
+			ServerInterfaceCapacityMBPs:               speed,
+			NetworkEquipmentID:                        switchID,
+			NetworkEquipmentIdentifierString:          fmt.Sprintf("leaf-%d", switchID-9),
+			NetworkEquipmentInterfaceIdentifierString: port,
+		}
+	}
+
+	client.EXPECT().
+		SwitchInterfaceSearch("network_equipment_id:10").
+		Return(&[]metalcloud.SwitchInterfaceSearchResult{
+			intf(1, 0, 10, "Ethernet1", 10000),
+			intf(2, 0, 10, "Ethernet2", 10000),
+			intf(3, 0, 10, "Ethernet3", 10000),
+			intf(4, 0, 10, "Ethernet4", 10000),
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SwitchInterfaceSearch("network_equipment_id:11").
+		Return(&[]metalcloud.SwitchInterfaceSearchResult{
+			intf(1, 1, 11, "Ethernet1", 10000),
+			intf(2, 1, 11, "Ethernet2", 10000),
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SwitchInterfaceSearch("network_equipment_id:12").
+		Return(&[]metalcloud.SwitchInterfaceSearchResult{
+			intf(4, 1, 12, "Ethernet4", 25000),
+		}, nil).
+		AnyTimes()
+
+	return client
+}
+
+func TestCablingReportCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	client := setupCablingTestClient(t)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"format":          "json",
+	})
+
+	ret, err := cablingReportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(8))
+
+	issues := map[string]string{}
+	for _, r := range rows {
+		key := fmt.Sprintf("%v/%v", r["SERIAL"], r["INTERFACE"])
+		issues[key] = r["ISSUES"].(string)
+	}
+
+	Expect(issues["SN1/0"]).To(Equal(""))
+	Expect(issues["SN3/0"]).To(Equal("1 of 2 interfaces connected"))
+	Expect(issues["SN4/0"]).To(Equal("mismatched link speeds: 10 Gbps, 25 Gbps"))
+	Expect(issues["SN4/1"]).To(Equal("switch not in a switch pair"))
+	Expect(issues["SN5/"]).To(Equal("no connected interfaces"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"issues_only":     true,
+		"format":          "json",
+	})
+
+	ret, err = cablingReportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(4))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"datacenter_name":     "dc-1",
+		"expected_interfaces": 3,
+		"issues_only":         true,
+		"format":              "json",
+	})
+
+	ret, err = cablingReportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(8))
+}
+
+func TestValidateCablingCmd(t *testing.T) {
+	RegisterTestingT(t)
+
+	client := setupCablingTestClient(t)
+
+	f := filepath.Join(t.TempDir(), "expected.csv")
+
+	plan := "server,interface,switch,switch_port\n" +
+		"SN1,0,leaf-1,Ethernet1\n" +
+		"1,00:00:00:00:01:01,leaf-2,Ethernet1\n"
+	Expect(os.WriteFile(f, []byte(plan), 0600)).To(BeNil())
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"cabling_file":    f,
+	})
+
+	ret, err := validateCablingCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("0 issues"))
+
+	plan = "server,interface,switch,switch_port\n" +
+		"SN1,0,leaf-1,Ethernet1\n" +
+		"SN1,1,leaf-2,Ethernet9\n" +
+		"SN3,1,leaf-2,Ethernet3\n" +
+		"SN4,0,leaf-1,Ethernet4\n" +
+		"SN99,0,leaf-1,Ethernet9\n"
+	Expect(os.WriteFile(f, []byte(plan), 0600)).To(BeNil())
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	_, err = validateCablingCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	//mismatch on SN1/1, missing SN3/1, unknown SN99 and the unplanned SN3/0 and SN4/1
+	Expect(err.Error()).To(ContainSubstring("5 issues"))
+	Expect(stdout.String()).To(ContainSubstring("mismatch"))
+	Expect(stdout.String()).To(ContainSubstring("unexpected"))
+
+	Expect(os.WriteFile(f, []byte("server,switch\nSN1,leaf-1\n"), 0600)).To(BeNil())
+
+	_, err = validateCablingCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("no interface column"))
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+	"gopkg.in/yaml.v3"
+)
+
+// scheduleCmds commands managing locally scheduled deploys
+var scheduleCmds = []Command{
+
+	{
+		Description:  "Lists scheduled deploys.",
+		Subject:      "schedule",
+		AltSubject:   "sched",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list scheduled deploys", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"format": c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc:   scheduleListCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+	},
+	{
+		Description:  "Cancel a scheduled deploy.",
+		Subject:      "schedule",
+		AltSubject:   "sched",
+		Predicate:    "cancel",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("cancel scheduled deploy", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"schedule_id": c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Scheduled deploy's id."),
+				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc:   scheduleCancelCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+	},
+	{
+		Description:  "Run scheduled deploys that are due.",
+		Subject:      "schedule",
+		AltSubject:   "sched",
+		Predicate:    "run",
+		AltPredicate: "exec",
+		FlagSet:      flag.NewFlagSet("run scheduled deploys", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"format":      c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc:   scheduleRunCmd,
+		Endpoint:      UserEndpoint,
+		AdminEndpoint: DeveloperEndpoint,
+		Example: `
+# Add the following to crontab to run due deploys every 5 minutes:
+*/5 * * * * metalcloud-cli schedule run --autoconfirm
+`,
+	},
+}
+
+// scheduledDeploysFile is the local state file holding the pending deploys
+const scheduledDeploysFile = "scheduled_deploys.json"
+
+// scheduledDeploy is a deploy that will be executed by 'schedule run' once its time has come.
+// A deploy that fails is kept as failed and never retried, it needs to be cancelled and scheduled again.
+type scheduledDeploy struct {
+	ID                  int                        `json:"id"`
+	InfrastructureID    int                        `json:"infrastructure_id"`
+	InfrastructureLabel string                     `json:"infrastructure_label"`
+	At                  time.Time                  `json:"at"`
+	ShutdownOptions     metalcloud.ShutdownOptions `json:"shutdown_options"`
+	AllowDataLoss       bool                       `json:"allow_data_loss"`
+	SkipAnsible         bool                       `json:"skip_ansible"`
+	WindowFile          string                     `json:"window_file,omitempty"`
+	Failed              bool                       `json:"failed,omitempty"`
+	LastError           string                     `json:"last_error,omitempty"`
+}
+
+// maintenanceWindow is a recurring time interval in which disruptive deploys are allowed.
+// Start and End are in HH:MM format. If End is before Start the window ends on the next day.
+type maintenanceWindow struct {
+	Days     []string `json:"days" yaml:"days"`
+	Start    string   `json:"start" yaml:"start"`
+	End      string   `json:"end" yaml:"end"`
+	Timezone string   `json:"timezone" yaml:"timezone"`
+}
+
+// maintenanceWindowPolicy is the content of the file passed with --window
+type maintenanceWindowPolicy struct {
+	Windows []maintenanceWindow `json:"windows" yaml:"windows"`
+}
+
+func readScheduledDeploys() ([]scheduledDeploy, error) {
+	list := []scheduledDeploy{}
+	err := readLocalState(scheduledDeploysFile, &list)
+	return list, err
+}
+
+func writeScheduledDeploys(list []scheduledDeploy) error {
+	return writeLocalState(scheduledDeploysFile, list)
+}
+
+// addScheduledDeploy persists a new scheduled deploy and returns it with the id set
+func addScheduledDeploy(d scheduledDeploy) (*scheduledDeploy, error) {
+	unlock, err := lockLocalState(scheduledDeploysFile)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	list, err := readScheduledDeploys()
+	if err != nil {
+		return nil, err
+	}
+
+	maxID := 0
+	for _, s := range list {
+		if s.ID > maxID {
+			maxID = s.ID
+		}
+	}
+	d.ID = maxID + 1
+
+	list = append(list, d)
+
+	return &d, writeScheduledDeploys(list)
+}
+
+// readMaintenanceWindowPolicy reads a maintenance window policy from a json or yaml file
+func readMaintenanceWindowPolicy(path string) (*maintenanceWindowPolicy, error) {
+	content, err := readInputFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy maintenanceWindowPolicy
+	//yaml is a superset of json so this handles both formats
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return nil, err
+	}
+
+	if len(policy.Windows) == 0 {
+		return nil, fmt.Errorf("maintenance window policy %s does not define any windows", path)
+	}
+
+	return &policy, nil
+}
+
+// parseClock parses a HH:MM string into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %s, expecting HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// dayMatches returns true if the weekday is in the list. An empty list matches every day.
+func dayMatches(days []string, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if strings.HasPrefix(strings.ToLower(day.String()), strings.ToLower(d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if the given moment falls inside the window
+func (w maintenanceWindow) contains(t time.Time) (bool, error) {
+	loc := time.UTC
+	if w.Timezone != "" {
+		l, err := time.LoadLocation(w.Timezone)
+		if err != nil {
+			return false, err
+		}
+		loc = l
+	}
+
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return dayMatches(w.Days, t.Weekday()) && minute >= start && minute < end, nil
+	}
+
+	//the window crosses midnight, the days refer to the day the window starts
+	if minute >= start {
+		return dayMatches(w.Days, t.Weekday()), nil
+	}
+	if minute < end {
+		return dayMatches(w.Days, t.AddDate(0, 0, -1).Weekday()), nil
+	}
+	return false, nil
+}
+
+// allows returns true if the given moment falls inside any of the windows
+func (p maintenanceWindowPolicy) allows(t time.Time) (bool, error) {
+	for _, w := range p.Windows {
+		ok, err := w.contains(t)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkMaintenanceWindow returns an error if a disruptive deploy (allow data loss or hard shutdown)
+// would run at the given moment outside the windows defined in windowFile. An empty windowFile allows everything.
+func checkMaintenanceWindow(windowFile string, at time.Time, shutdownOptions metalcloud.ShutdownOptions, allowDataLoss bool) error {
+	if windowFile == "" {
+		return nil
+	}
+
+	if !allowDataLoss && !shutdownOptions.HardShutdownAfterTimeout {
+		return nil
+	}
+
+	policy, err := readMaintenanceWindowPolicy(windowFile)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := policy.allows(at)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return fmt.Errorf("refusing to deploy at %s: deploys with allow-data-loss or hard shutdown are only allowed inside the maintenance windows defined in %s. Use --no-hard-shutdown-after-timeout and no --allow-data-loss or schedule the deploy with --at",
+			at.Format(time.RFC3339),
+			windowFile)
+	}
+
+	return nil
+}
+
+func describeScheduledDeploy(s scheduledDeploy) string {
+	options := []string{}
+	if s.AllowDataLoss {
+		options = append(options, "allow data loss")
+	}
+	if s.ShutdownOptions.HardShutdownAfterTimeout {
+		options = append(options, "hard shutdown")
+	}
+	if !s.ShutdownOptions.AttemptSoftShutdown {
+		options = append(options, "no soft shutdown")
+	}
+	if s.SkipAnsible {
+		options = append(options, "skip ansible")
+	}
+	if s.WindowFile != "" {
+		options = append(options, fmt.Sprintf("window %s", s.WindowFile))
+	}
+	return strings.Join(options, ", ")
+}
+
+func scheduleListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	list, err := readScheduledDeploys()
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "INFRASTRUCTURE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "AT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "OPTIONS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+	}
+
+	now := time.Now()
+	data := [][]interface{}{}
+	for _, s := range list {
+
+		status := blue("pending")
+		if !s.At.After(now) {
+			status = yellow("due")
+		}
+		if s.Failed || s.LastError != "" {
+			status = red(fmt.Sprintf("failed: %s", s.LastError))
+		}
+
+		data = append(data, []interface{}{
+			s.ID,
+			fmt.Sprintf("%s (#%d)", s.InfrastructureLabel, s.InfrastructureID),
+			s.At.Format(time.RFC3339),
+			describeScheduledDeploy(s),
+			status,
+		})
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+	return table.RenderTable("Scheduled deploys", "", getStringParam(c.Arguments["format"]))
+}
+
+func scheduleCancelCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	id, ok := getIntParamOk(c.Arguments["schedule_id"])
+	if !ok {
+		return "", fmt.Errorf("-id is required")
+	}
+
+	unlock, err := lockLocalState(scheduledDeploysFile)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	list, err := readScheduledDeploys()
+	if err != nil {
+		return "", err
+	}
+
+	newList := []scheduledDeploy{}
+	var found *scheduledDeploy
+	for i, s := range list {
+		if s.ID == id {
+			found = &list[i]
+			continue
+		}
+		newList = append(newList, s)
+	}
+
+	if found == nil {
+		return "", fmt.Errorf("scheduled deploy %d not found", id)
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Cancelling deploy of infrastructure %s (#%d) scheduled at %s. Are you sure? Type \"yes\" to continue:",
+			found.InfrastructureLabel,
+			found.InfrastructureID,
+			found.At.Format(time.RFC3339))
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	return "", writeScheduledDeploys(newList)
+}
+
+func scheduleRunCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	//overlapping runs would deploy the same entries twice
+	unlock, err := lockLocalState(scheduledDeploysFile)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	list, err := readScheduledDeploys()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	due := []string{}
+	for _, s := range list {
+		if !s.Failed && !s.At.After(now) {
+			due = append(due, fmt.Sprintf("%s (#%d)", s.InfrastructureLabel, s.InfrastructureID))
+		}
+	}
+
+	if len(due) == 0 {
+		return "", nil
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Deploying infrastructures %s. Are you sure? Type \"yes\" to continue:", strings.Join(due, ", "))
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "INFRASTRUCTURE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+	}
+
+	data := [][]interface{}{}
+	remaining := []scheduledDeploy{}
+	failed := 0
+	for _, s := range list {
+
+		if s.Failed || s.At.After(now) {
+			remaining = append(remaining, s)
+			continue
+		}
+
+		//the window is checked again as the deploy might have been delayed past the window
+		err := checkMaintenanceWindow(s.WindowFile, now, s.ShutdownOptions, s.AllowDataLoss)
+		if err == nil {
+			err = client.InfrastructureDeploy(s.InfrastructureID, s.ShutdownOptions, s.AllowDataLoss, s.SkipAnsible)
+		}
+
+		//a failed deploy is not retried as a later run could fall in a different maintenance window
+		result := green("deploy started")
+		if err != nil {
+			failed++
+			s.Failed = true
+			s.LastError = err.Error()
+			remaining = append(remaining, s)
+			result = red(fmt.Sprintf("failed, not retried: %v", err))
+		}
+
+		data = append(data, []interface{}{
+			s.ID,
+			fmt.Sprintf("%s (#%d)", s.InfrastructureLabel, s.InfrastructureID),
+			result,
+		})
+	}
+
+	if err := writeScheduledDeploys(remaining); err != nil {
+		return "", err
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	ret, err := table.RenderTable("Scheduled deploys", "", getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("%d scheduled deploys failed and will not be retried. Remove them with 'schedule cancel' and schedule them again", failed)
+	}
+
+	return ret, nil
+}
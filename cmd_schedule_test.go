@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+const _maintenanceWindowFixture = `
+windows:
+  - days: ["sat", "sun"]
+    start: "02:00"
+    end: "05:00"
+  - days: ["wed"]
+    start: "23:00"
+    end: "01:00"
+`
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir := t.TempDir()
+	windowFile := filepath.Join(dir, "window.yaml")
+	Expect(os.WriteFile(windowFile, []byte(_maintenanceWindowFixture), 0600)).To(BeNil())
+
+	policy, err := readMaintenanceWindowPolicy(windowFile)
+	Expect(err).To(BeNil())
+
+	cases := map[string]bool{
+		"2026-10-24T03:00:00Z": true,  //saturday
+		"2026-10-24T05:00:00Z": false, //saturday, window ended
+		"2026-10-23T03:00:00Z": false, //friday
+		"2026-10-21T23:30:00Z": true,  //wednesday
+		"2026-10-22T00:30:00Z": true,  //thursday, window started on wednesday
+		"2026-10-23T00:30:00Z": false, //friday, window started on thursday
+	}
+
+	for at, expected := range cases {
+		tm, err := time.Parse(time.RFC3339, at)
+		Expect(err).To(BeNil())
+		allowed, err := policy.allows(tm)
+		Expect(err).To(BeNil())
+		Expect(allowed).To(Equal(expected), at)
+	}
+
+	outside, _ := time.Parse(time.RFC3339, "2026-10-23T03:00:00Z")
+
+	err = checkMaintenanceWindow(windowFile, outside, metalcloud.ShutdownOptions{HardShutdownAfterTimeout: true}, false)
+	Expect(err).NotTo(BeNil())
+
+	err = checkMaintenanceWindow(windowFile, outside, metalcloud.ShutdownOptions{}, true)
+	Expect(err).NotTo(BeNil())
+
+	err = checkMaintenanceWindow(windowFile, outside, metalcloud.ShutdownOptions{AttemptSoftShutdown: true}, false)
+	Expect(err).To(BeNil())
+}
+
+func TestInfrastructureDeployScheduled(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	dir := t.TempDir()
+	os.Setenv("METALCLOUD_STATE_DIR", dir)
+	defer os.Unsetenv("METALCLOUD_STATE_DIR")
+
+	windowFile := filepath.Join(dir, "window.yaml")
+	Expect(os.WriteFile(windowFile, []byte(_maintenanceWindowFixture), 0600)).To(BeNil())
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		InfrastructureUserLimits(10002).
+		Return(&map[string]interface{}{}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&infra, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label":    "10002",
+		"autoconfirm":                   true,
+		"soft_shutdown_timeout_seconds": 180,
+		"at":                            "2026-10-23T03:00:00Z",
+		"window":                        windowFile,
+	})
+
+	//friday is outside the window and hard shutdown is on by default
+	_, err := infrastructureDeployCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label":    "10002",
+		"autoconfirm":                   true,
+		"soft_shutdown_timeout_seconds": 180,
+		"at":                            "2026-10-24T03:00:00Z",
+		"window":                        windowFile,
+	})
+
+	ret, err := infrastructureDeployCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("scheduled at 2026-10-24T03:00:00Z with id 1"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label":     "10002",
+		"autoconfirm":                    true,
+		"soft_shutdown_timeout_seconds":  180,
+		"no_hard_shutdown_after_timeout": true,
+		"at":                             time.Now().Add(-time.Minute).Format(time.RFC3339),
+	})
+
+	ret, err = infrastructureDeployCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("with id 2"))
+
+	list, err := readScheduledDeploys()
+	Expect(err).To(BeNil())
+	Expect(list).To(HaveLen(2))
+	Expect(list[1].ShutdownOptions.HardShutdownAfterTimeout).To(BeFalse())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"format": "json",
+	})
+
+	ret, err = scheduleListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("testinfra"))
+
+	//only the second one is due
+	client.EXPECT().
+		InfrastructureDeploy(10002, metalcloud.ShutdownOptions{HardShutdownAfterTimeout: false, AttemptSoftShutdown: true, SoftShutdownTimeoutSeconds: 180}, false, false).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"autoconfirm": true,
+	})
+
+	//a run does not start while another one is in progress
+	unlock, err := lockLocalState(scheduledDeploysFile)
+	Expect(err).To(BeNil())
+
+	_, err = scheduleRunCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("in use by another run"))
+
+	unlock()
+
+	ret, err = scheduleRunCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("deploy started"))
+
+	list, err = readScheduledDeploys()
+	Expect(err).To(BeNil())
+	Expect(list).To(HaveLen(1))
+	Expect(list[0].ID).To(Equal(1))
+
+	//a failed deploy is kept as failed and not retried
+	failingOptions := metalcloud.ShutdownOptions{AttemptSoftShutdown: true, SoftShutdownTimeoutSeconds: 60}
+
+	failing, err := addScheduledDeploy(scheduledDeploy{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+		At:                  time.Now().Add(-time.Minute),
+		ShutdownOptions:     failingOptions,
+	})
+	Expect(err).To(BeNil())
+
+	client.EXPECT().
+		InfrastructureDeploy(10002, failingOptions, false, false).
+		Return(fmt.Errorf("deploy error")).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	_, err = scheduleRunCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(stdout.String()).To(ContainSubstring("not retried"))
+
+	ret, err = scheduleRunCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal(""))
+
+	list, err = readScheduledDeploys()
+	Expect(err).To(BeNil())
+	Expect(list).To(HaveLen(2))
+	Expect(list[1].Failed).To(BeTrue())
+	Expect(list[1].LastError).To(Equal("deploy error"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"schedule_id": failing.ID,
+		"autoconfirm": true,
+	})
+
+	_, err = scheduleCancelCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"schedule_id": 1,
+		"autoconfirm": true,
+	})
+
+	_, err = scheduleCancelCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	list, err = readScheduledDeploys()
+	Expect(err).To(BeNil())
+	Expect(list).To(HaveLen(0))
+}
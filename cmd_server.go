@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
 	"github.com/metalsoft-io/tableformatter"
+	"gopkg.in/yaml.v3"
 )
 
 var serversCmds = []Command{
@@ -90,10 +100,26 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 				"mgmt_user":     c.FlagSet.String("mgmt-user", _nilDefaultStr, red("(Required)")+" Server' BMC username."),
 				"mgmt_pass":     c.FlagSet.String("mgmt-pass", _nilDefaultStr, red("(Required)")+" Server' BMC password."),
 				"return_id":     c.FlagSet.Bool("return-id", false, "Will print the ID of the created object. Useful for automating tasks."),
+				"inventory_file": c.FlagSet.String("from-file", _nilDefaultStr, "Register all the servers of an inventory file instead. The columns are: "+
+					"datacenter, vendor, mgmt_address, user, password, rack, u_range, inventory_id. The password is a reference such as env:VARIABLE or file:/path/to/file."),
+				"format":       c.FlagSet.String("format", _nilDefaultStr, "The format of the inventory file. Supported values are 'csv','yaml'. Defaults to yaml for .yaml and .yml files and to csv otherwise."),
+				"results_file": c.FlagSet.String("results-file", _nilDefaultStr, "The csv file in which the id or error of each inventory row is written. Defaults to <inventory file>.results.csv."),
+				"concurrency":  c.FlagSet.Int("concurrency", 10, "Maximum number of servers registered at the same time."),
+				"autoconfirm":  c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: serverRegisterCmd,
 		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli server register --datacenter dc1 --server-vendor dell --mgmt-address 10.0.0.1 --mgmt-user root --mgmt-pass secret
+
+#inventory.csv:
+datacenter,vendor,mgmt_address,user,password,rack,u_range,inventory_id
+dc1,dell,10.0.0.1,root,env:BMC_PASS,R01,10-11,INV0001
+dc1,dell,10.0.0.2,root,file:/secrets/bmc2,R01,12-13,INV0002
+
+metalcloud-cli server register --from-file inventory.csv
+`,
 	},
 
 	{
@@ -165,9 +191,13 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 		FlagSet:      flag.NewFlagSet("", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"server_id":   c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Server's id."),
-				"status":      c.FlagSet.String("status", _nilDefaultStr, red("(Required)")+" New server status. One of: 'available','decommissioned','removed_from_rack'"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"status":          c.FlagSet.String("status", _nilDefaultStr, red("(Required)")+" New server status. One of: 'available','decommissioned','removed_from_rack'"),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to change in parallel."),
+				"autoconfirm":     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: serverStatusSetCmd,
@@ -199,9 +229,13 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 		FlagSet:      flag.NewFlagSet("", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"server_id":   c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Server's id."),
-				"server_type": c.FlagSet.String("server-type", _nilDefaultStr, red("(Required)")+" New server type. Can be an ID or label"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"server_type":     c.FlagSet.String("server-type", _nilDefaultStr, red("(Required)")+" New server type. Can be an ID or label"),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to change in parallel."),
+				"autoconfirm":     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: serverServerTypeSetCmd,
@@ -216,11 +250,15 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 		FlagSet:      flag.NewFlagSet("", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"server_id":   c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Server's id."),
-				"rack_name":   c.FlagSet.String("rack-name", _nilDefaultStr, red("(Required)")+" New rack name."),
-				"lower_u":     c.FlagSet.Int("lower-u", _nilDefaultInt, red("(Required)")+" Lower U of the equipment"),
-				"upper_u":     c.FlagSet.Int("upper-u", _nilDefaultInt, red("(Required)")+" Upper U of the equipment"),
-				"autoconfirm": c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"rack_name":       c.FlagSet.String("rack-name", _nilDefaultStr, red("(Required)")+" New rack name."),
+				"lower_u":         c.FlagSet.Int("lower-u", _nilDefaultInt, red("(Required)")+" Lower U of the equipment"),
+				"upper_u":         c.FlagSet.Int("upper-u", _nilDefaultInt, red("(Required)")+" Upper U of the equipment"),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to change in parallel."),
+				"autoconfirm":     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: serverRackInfoSetCmd,
@@ -235,9 +273,13 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 		FlagSet:      flag.NewFlagSet("", flag.ExitOnError),
 		InitFunc: func(c *Command) {
 			c.Arguments = map[string]interface{}{
-				"server_id":    c.FlagSet.Int("id", _nilDefaultInt, red("(Required)")+" Server's id."),
-				"inventory_id": c.FlagSet.String("inventory-id", _nilDefaultStr, red("(Required)")+" New inventory id"),
-				"autoconfirm":  c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"inventory_id":    c.FlagSet.String("inventory-id", _nilDefaultStr, red("(Required)")+" New inventory id"),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to change in parallel."),
+				"autoconfirm":     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
 			}
 		},
 		ExecuteFunc: serverInventoryInfoSetCmd,
@@ -260,6 +302,162 @@ metalcloud-cli server list --show-credentials # to retrieve a list of credential
 		ExecuteFunc: serverInterfacesListCmd,
 		Endpoint:    DeveloperEndpoint,
 	},
+	{
+		Description:  "Lists the hardware components of one or more servers.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "components",
+		AltPredicate: "comp",
+		FlagSet:      flag.NewFlagSet("list server components", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid": c.FlagSet.String("id", _nilDefaultStr, "Server's ID or UUID. Either this or --filter is required."),
+				"filter":            c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. Either this or --id is required."),
+				"component_type":    c.FlagSet.String("type", _nilDefaultStr, "Comma separated list of component types to return such as 'disk,cpu,nic'."),
+				"component_model":   c.FlagSet.String("model", _nilDefaultStr, "Only return components whose name or model contains this text. Case insensitive."),
+				"concurrency":       c.FlagSet.Int("concurrency", 10, "The number of servers to read in parallel when using --filter."),
+				"format":            c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverComponentsCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli server components --id 100
+metalcloud-cli server components --filter "datacenter_name:dc-1" --type disk --model PM883 --format csv > disks.csv
+`,
+	},
+	{
+		Description:  "Decommissions one or more servers that are not allocated.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "decommission",
+		AltPredicate: "decomission",
+		FlagSet:      flag.NewFlagSet("decommission server", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":            c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":           c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file":      c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":               c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"wipe":                 c.FlagSet.Bool("wipe", false, green("(Flag)")+" If set the servers are cleaned first and the command waits for them to become available again."),
+				"skip_ipmi":            c.FlagSet.Bool("skip-ipmi", false, green("(Flag)")+" If set the BMC of the servers is not contacted."),
+				"concurrency":          c.FlagSet.Int("concurrency", 10, "The number of servers to process in parallel."),
+				"block_timeout":        c.FlagSet.Int("block-timeout", 180*60, "Timeout in seconds when waiting for the servers to be cleaned. Defaults to 180 minutes."),
+				"block_check_interval": c.FlagSet.Int("block-check-interval", 10, "Check interval when waiting for the servers to be cleaned. Defaults to 10 seconds."),
+				"autoconfirm":          c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: serverDecommissionCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli server decommission --id 100
+metalcloud-cli server decommission --filter "server_rack_name:rack-01" --wipe # retires a whole rack
+`,
+	},
+	{
+		Description:  "Deletes one or more servers that are not allocated.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete server", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":            c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":           c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file":      c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":               c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_rack_name:rack-01'."),
+				"wipe":                 c.FlagSet.Bool("wipe", false, green("(Flag)")+" If set the servers are cleaned first and the command waits for them to become available again."),
+				"skip_ipmi":            c.FlagSet.Bool("skip-ipmi", false, green("(Flag)")+" If set the BMC of the servers is not contacted."),
+				"concurrency":          c.FlagSet.Int("concurrency", 10, "The number of servers to process in parallel."),
+				"block_timeout":        c.FlagSet.Int("block-timeout", 180*60, "Timeout in seconds when waiting for the servers to be cleaned. Defaults to 180 minutes."),
+				"block_check_interval": c.FlagSet.Int("block-check-interval", 10, "Check interval when waiting for the servers to be cleaned. Defaults to 10 seconds."),
+				"autoconfirm":          c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: serverDeleteCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli server delete --ids 100,101,102
+`,
+	},
+	{
+		Description:  "Rotates the IPMI credentials of one or more servers.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "rotate-ipmi",
+		AltPredicate: "ipmi-rotate",
+		FlagSet:      flag.NewFlagSet("rotate server IPMI credentials", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file, --filter or --retry-from is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'datacenter_name:dc-1'."),
+				"retry_from":      c.FlagSet.String("retry-from", _nilDefaultStr, "Report of a previous rotation. The servers that were not rotated are retried with the same new passwords."),
+				"passwords_file":  c.FlagSet.String("passwords-from-file", _nilDefaultStr, "CSV file with server_id,password rows to use instead of generated passwords."),
+				"password_length": c.FlagSet.Int("password-length", 16, "Length of the generated passwords. IPMI 2.0 supports at most 20 characters."),
+				"report_file":     c.FlagSet.String("report", _nilDefaultStr, red("(Required)")+" File to write the encrypted report with the old and new credentials to."),
+				"report_key":      c.FlagSet.String("report-key", _nilDefaultStr, red("(Required)")+" Key used to encrypt the report, as env:VARIABLE or file:/path/to/file."),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to process in parallel."),
+				"autoconfirm":     c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: serverRotateIPMICmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+export ROTATION_KEY=...
+metalcloud-cli server rotate-ipmi --filter "datacenter_name:dc-1" --report rotation.enc --report-key env:ROTATION_KEY
+metalcloud-cli server rotate-ipmi --retry-from rotation.enc --report rotation-retry.enc --report-key env:ROTATION_KEY
+metalcloud-cli server rotate-ipmi-report --report rotation.enc --report-key env:ROTATION_KEY --show-passwords
+`,
+	},
+	{
+		Description:  "Shows the report of an IPMI credentials rotation.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "rotate-ipmi-report",
+		AltPredicate: "ipmi-rotate-report",
+		FlagSet:      flag.NewFlagSet("show server IPMI rotation report", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"report_file":    c.FlagSet.String("report", _nilDefaultStr, red("(Required)")+" The encrypted report written by 'server rotate-ipmi'."),
+				"report_key":     c.FlagSet.String("report-key", _nilDefaultStr, red("(Required)")+" Key used to encrypt the report, as env:VARIABLE or file:/path/to/file."),
+				"show_passwords": c.FlagSet.Bool("show-passwords", false, green("(Flag)")+" If set the old and new passwords are also shown."),
+				"format":         c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverRotateIPMIReportCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Checks from this machine if the BMC of one or more servers is reachable.",
+		Subject:      "server",
+		AltSubject:   "srv",
+		Predicate:    "probe",
+		AltPredicate: "bmc-probe",
+		FlagSet:      flag.NewFlagSet("probe server BMC", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id":       c.FlagSet.Int("id", _nilDefaultInt, "Server's id. One of --id, --ids, --ids-from-file or --filter is required."),
+				"server_ids":      c.FlagSet.String("ids", _nilDefaultStr, "Comma separated list of server ids."),
+				"server_ids_file": c.FlagSet.String("ids-from-file", _nilDefaultStr, "File with the ids of the servers, separated by commas or new lines."),
+				"filter":          c.FlagSet.String("filter", _nilDefaultStr, "Filter to use when searching for servers, same as for 'server list'. For example 'server_status:registering'."),
+				"redfish":         c.FlagSet.Bool("redfish", false, green("(Flag)")+" If set a Redfish GET /redfish/v1 request is made with the stored IPMI credentials of the server."),
+				"ipmi_port":       c.FlagSet.Int("ipmi-port", 623, "UDP port of the IPMI service, probed with an RMCP presence ping."),
+				"redfish_port":    c.FlagSet.Int("redfish-port", 443, "TCP port of the Redfish service."),
+				"timeout":         c.FlagSet.Int("timeout", 5, "Timeout in seconds of each check."),
+				"concurrency":     c.FlagSet.Int("concurrency", 10, "The number of servers to probe in parallel."),
+				"format":          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverProbeCmd,
+		Endpoint:    DeveloperEndpoint,
+		Example: `
+metalcloud-cli server probe --id 100 --redfish
+metalcloud-cli server probe --filter "server_status:registering"
+`,
+	},
 }
 
 func serverPowerControlCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -315,65 +513,26 @@ func serverPowerControlCmd(c *Command, client metalcloud.MetalCloudClient) (stri
 }
 
 func serverStatusSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
-	serverID, ok := getIntParamOk(c.Arguments["server_id"])
-	if !ok {
-		return "", fmt.Errorf("-id is required")
-	}
-
 	newStatus, ok := getStringParamOk(c.Arguments["status"])
 	if !ok {
-		return "", fmt.Errorf("-status is required (one of: on, off, reset, soft)")
-	}
-
-	var server metalcloud.Server
-
-	if !getBoolParam(c.Arguments["autoconfirm"]) {
-		serverPtr, err := client.ServerGet(serverID, false)
-		if err != nil {
-			return "", err
-		}
-		server = *serverPtr
+		return "", fmt.Errorf("-status is required (one of: available, decommissioned, removed_from_rack)")
 	}
 
-	confirm, err := confirmCommand(c, func() string {
-
-		confirmationMessage := ""
-
-		if !getBoolParam(c.Arguments["autoconfirm"]) {
-
-			confirmationMessage = fmt.Sprintf("Server #%s (%s) of datacenter %s. Current status: %s new status: %s  Are you sure? Type \"yes\" to continue:",
-				blue(fmt.Sprintf("%d", server.ServerID)),
-				yellow(server.ServerSerialNumber),
-				server.DatacenterName,
-				colorizeServerStatus(server.ServerStatus),
-				colorizeServerStatus(newStatus),
-			)
-		}
-
-		if strings.HasSuffix(os.Args[0], ".test") {
-			confirmationMessage = ""
-		}
-
-		return confirmationMessage
-	})
-
-	if err != nil {
-		return "", err
+	describe := func(server metalcloud.ServerSearchResult) string {
+		return fmt.Sprintf("Current status: %s new status: %s",
+			colorizeServerStatus(server.ServerStatus),
+			colorizeServerStatus(newStatus),
+		)
 	}
 
-	if confirm {
-		err = client.ServerStatusUpdate(serverID, newStatus)
+	apply := func(serverID int) error {
+		return client.ServerStatusUpdate(serverID, newStatus)
 	}
 
-	return "", err
+	return serverBatchEditCmd(c, client, "change the status", describe, apply)
 }
 
 func serverServerTypeSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
-	serverID, ok := getIntParamOk(c.Arguments["server_id"])
-	if !ok {
-		return "", fmt.Errorf("-id is required")
-	}
-
 	serverTypeStr, ok := getStringParamOk(c.Arguments["server_type"])
 	if !ok {
 		return "", fmt.Errorf("-server-type is required")
@@ -395,70 +554,29 @@ func serverServerTypeSetCmd(c *Command, client metalcloud.MetalCloudClient) (str
 		newServerType = *st
 	}
 
-	var server metalcloud.Server
-
-	if !getBoolParam(c.Arguments["autoconfirm"]) {
-		serverPtr, err := client.ServerGet(serverID, false)
-		if err != nil {
-			return "", err
-		}
-		server = *serverPtr
-	}
-
-	confirm, err := confirmCommand(c, func() string {
-
-		confirmationMessage := ""
-
-		if !getBoolParam(c.Arguments["autoconfirm"]) {
-
-			oldServerType := metalcloud.ServerType{
-				ServerTypeName: "none",
-				ServerTypeID:   0,
-			}
-
-			if server.ServerTypeID != 0 {
-				st, err := client.ServerTypeGet(server.ServerTypeID)
-				if err != nil {
-					return err.Error()
-				}
-				oldServerType = *st
-			}
-
-			confirmationMessage = fmt.Sprintf("Server #%s (%s) of datacenter %s. Current server type: %s (#%s) new server type: %s (#%s) Are you sure? Type \"yes\" to continue:",
-				blue(fmt.Sprintf("%d", server.ServerID)),
-				yellow(server.ServerSerialNumber),
-				server.DatacenterName,
-				red(oldServerType.ServerTypeName),
-				red(oldServerType.ServerTypeID),
-				green(newServerType.ServerTypeName),
-				green(newServerType.ServerTypeID),
-			)
-		}
+	describe := func(server metalcloud.ServerSearchResult) string {
 
-		if strings.HasSuffix(os.Args[0], ".test") {
-			confirmationMessage = ""
+		oldServerTypeName := "none"
+		if server.ServerTypeID != 0 {
+			oldServerTypeName = server.ServerTypeName
 		}
 
-		return confirmationMessage
-	})
-
-	if err != nil {
-		return "", err
+		return fmt.Sprintf("Current server type: %s (#%s) new server type: %s (#%s)",
+			red(oldServerTypeName),
+			red(server.ServerTypeID),
+			green(newServerType.ServerTypeName),
+			green(newServerType.ServerTypeID),
+		)
 	}
 
-	if confirm {
-		err = client.ServerEditProperty(serverID, "server_type_id", newServerType.ServerTypeID)
+	apply := func(serverID int) error {
+		return client.ServerEditProperty(serverID, "server_type_id", newServerType.ServerTypeID)
 	}
 
-	return "", err
+	return serverBatchEditCmd(c, client, "change the server type", describe, apply)
 }
 
 func serverRackInfoSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
-	serverID, ok := getIntParamOk(c.Arguments["server_id"])
-	if !ok {
-		return "", fmt.Errorf("-id is required")
-	}
-
 	serverRackName, ok := getStringParamOk(c.Arguments["rack_name"])
 	if !ok {
 		return "", fmt.Errorf("-rack-name is required")
@@ -474,47 +592,19 @@ func serverRackInfoSetCmd(c *Command, client metalcloud.MetalCloudClient) (strin
 		return "", fmt.Errorf("-upper-u is required")
 	}
 
-	var server metalcloud.Server
-
-	serverPtr, err := client.ServerGet(serverID, false)
-	if err != nil {
-		return "", err
-	}
-	server = *serverPtr
-
-	confirm, err := confirmCommand(c, func() string {
-
-		confirmationMessage := ""
-
-		if !getBoolParam(c.Arguments["autoconfirm"]) {
-
-			oldRackInfo := getRackInfoSafe(server)
-
-			oldServerRackInfo := fmt.Sprintf("Rack:%s U:%s-%s", oldRackInfo.RackName, oldRackInfo.LowerU, oldRackInfo.UpperU)
-
-			newServerRackInfo := fmt.Sprintf("Rack:%s U:%d-%d", serverRackName, serverRackLowerU, serverRackUpperU)
-
-			confirmationMessage = fmt.Sprintf("Server #%s (%s) of datacenter %s. Current server rack info %s new rack info: %s. Are you sure? Type \"yes\" to continue:",
-				blue(fmt.Sprintf("%d", server.ServerID)),
-				yellow(server.ServerSerialNumber),
-				server.DatacenterName,
-				red(oldServerRackInfo),
-				green(newServerRackInfo),
-			)
-		}
+	describe := func(server metalcloud.ServerSearchResult) string {
 
-		if strings.HasSuffix(os.Args[0], ".test") {
-			confirmationMessage = ""
-		}
+		oldServerRackInfo := fmt.Sprintf("Rack:%s U:%s-%s", server.ServerRackName, server.ServerRackPositionLowerUnit, server.ServerRackPositionUpperUnit)
 
-		return confirmationMessage
-	})
+		newServerRackInfo := fmt.Sprintf("Rack:%s U:%d-%d", serverRackName, serverRackLowerU, serverRackUpperU)
 
-	if err != nil {
-		return "", err
+		return fmt.Sprintf("Current server rack info %s new rack info: %s.",
+			red(oldServerRackInfo),
+			green(newServerRackInfo),
+		)
 	}
 
-	if confirm {
+	apply := func(serverID int) error {
 
 		lowerUStr := fmt.Sprintf("%d", serverRackLowerU)
 		upperUStr := fmt.Sprintf("%d", serverRackUpperU)
@@ -525,48 +615,71 @@ func serverRackInfoSetCmd(c *Command, client metalcloud.MetalCloudClient) (strin
 			ServerRackPositionUpperUnit: &upperUStr,
 		}
 
-		_, err = client.ServerEditRack(serverID, serverRackEdit)
+		_, err := client.ServerEditRack(serverID, serverRackEdit)
+		return err
 	}
 
-	return "", err
+	return serverBatchEditCmd(c, client, "change the rack info", describe, apply)
 }
 
 func serverInventoryInfoSetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
-	serverID, ok := getIntParamOk(c.Arguments["server_id"])
-	if !ok {
-		return "", fmt.Errorf("-id is required")
-	}
-
 	inventoryID, ok := getStringParamOk(c.Arguments["inventory_id"])
 	if !ok {
 		return "", fmt.Errorf("inventory-id is required")
 	}
 
-	var server metalcloud.Server
+	describe := func(server metalcloud.ServerSearchResult) string {
+		return fmt.Sprintf("Current inventory id: %s new inventory id: %s.",
+			red(server.ServerInventoryId),
+			green(inventoryID),
+		)
+	}
+
+	apply := func(serverID int) error {
+
+		serverEditInventory := metalcloud.ServerEditInventory{
+			ServerInventoryId: &inventoryID,
+		}
 
-	serverPtr, err := client.ServerGet(serverID, false)
+		_, err := client.ServerEditInventory(serverID, serverEditInventory)
+		return err
+	}
+
+	return serverBatchEditCmd(c, client, "change the inventory id", describe, apply)
+}
+
+// serverBatchEditCmd applies a change to the servers selected with -id, -ids, -ids-from-file or -filter.
+// describe returns the current and new values of a server, which are all shown in a single confirmation.
+func serverBatchEditCmd(c *Command, client metalcloud.MetalCloudClient, action string, describe func(server metalcloud.ServerSearchResult) string, apply func(serverID int) error) (string, error) {
+
+	servers, err := getServerSelectionFromCommand(c, client)
 	if err != nil {
 		return "", err
 	}
-	server = *serverPtr
 
 	confirm, err := confirmCommand(c, func() string {
 
-		confirmationMessage := ""
-
-		if !getBoolParam(c.Arguments["autoconfirm"]) {
+		lines := []string{}
+		for _, s := range servers {
+			lines = append(lines, fmt.Sprintf("Server #%s (%s) of datacenter %s. %s",
+				blue(fmt.Sprintf("%d", s.ServerID)),
+				yellow(s.ServerSerialNumber),
+				s.DatacenterName,
+				describe(s),
+			))
+		}
 
-			oldInventoryID := getStringFromStringOrEmpty(server.ServerInventoryId)
+		confirmationMessage := fmt.Sprintf("%s Are you sure? Type \"yes\" to continue:", lines[0])
 
-			confirmationMessage = fmt.Sprintf("Server #%s (%s) of datacenter %s. Current inventory id: %s new inventory id: %s. Are you sure? Type \"yes\" to continue:",
-				blue(fmt.Sprintf("%d", server.ServerID)),
-				yellow(server.ServerSerialNumber),
-				server.DatacenterName,
-				red(oldInventoryID),
-				green(inventoryID),
+		if len(servers) > 1 {
+			confirmationMessage = fmt.Sprintf("%s\nAbout to %s of %d servers. Are you sure? Type \"yes\" to continue:",
+				strings.Join(lines, "\n"),
+				action,
+				len(servers),
 			)
 		}
 
+		//this is simply so that we don't output a text on the command line under go test
 		if strings.HasSuffix(os.Args[0], ".test") {
 			confirmationMessage = ""
 		}
@@ -578,16 +691,60 @@ func serverInventoryInfoSetCmd(c *Command, client metalcloud.MetalCloudClient) (
 		return "", err
 	}
 
-	if confirm {
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
 
-		serverEditInventory := metalcloud.ServerEditInventory{
-			ServerInventoryId: &inventoryID,
+	errs := runConcurrently(len(servers), getIntParam(c.Arguments["concurrency"]), func(i int) error {
+		return apply(servers[i].ServerID)
+	})
+
+	if len(servers) == 1 {
+		return "", errs[0]
+	}
+
+	data := [][]interface{}{}
+	failed := 0
+
+	for i, s := range servers {
+		status := "done"
+		message := ""
+		if errs[i] != nil {
+			status = "failed"
+			message = errs[i].Error()
+			failed++
 		}
+		data = append(data, []interface{}{
+			s.ServerID,
+			s.ServerSerialNumber,
+			s.DatacenterName,
+			status,
+			message,
+		})
+	}
 
-		_, err = client.ServerEditInventory(serverID, serverEditInventory)
+	table := tableformatter.Table{
+		Data: data,
+		Schema: []tableformatter.SchemaField{
+			{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+			{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+			{FieldName: "DATACENTER", FieldType: tableformatter.TypeString, FieldSize: 10},
+			{FieldName: "STATUS", FieldType: tableformatter.TypeString, FieldSize: 8},
+			{FieldName: "ERROR", FieldType: tableformatter.TypeString, FieldSize: 30},
+		},
 	}
 
-	return "", err
+	ret, err := table.RenderTable("Servers", fmt.Sprintf("%s succeeded for %d of %d servers", action, len(servers)-failed, len(servers)), "")
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("could not %s of %d of %d servers", action, failed, len(servers))
+	}
+
+	return ret, nil
 }
 
 func serverReregisterCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -1209,6 +1366,10 @@ func serverCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, er
 
 func serverRegisterCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
 
+	if _, ok := getStringParamOk(c.Arguments["inventory_file"]); ok {
+		return serverRegisterFromFileCmd(c, client)
+	}
+
 	datacenter, ok := getStringParamOk(c.Arguments["datacenter"])
 	if !ok {
 		return "", fmt.Errorf("-datacenter is required")
@@ -1524,3 +1685,1459 @@ func getStringFromStringOrEmpty(str *string) string {
 	}
 	return *str
 }
+
+// serverInventoryRow is a server to be registered, as read from an inventory file
+type serverInventoryRow struct {
+	Row             int    `json:"-" yaml:"-"`
+	Datacenter      string `json:"datacenter" yaml:"datacenter"`
+	Vendor          string `json:"vendor" yaml:"vendor"`
+	MgmtAddress     string `json:"mgmt_address" yaml:"mgmt_address"`
+	User            string `json:"user" yaml:"user"`
+	PasswordRef     string `json:"password" yaml:"password"`
+	Rack            string `json:"rack" yaml:"rack"`
+	URange          string `json:"u_range" yaml:"u_range"`
+	InventoryID     string `json:"inventory_id" yaml:"inventory_id"`
+	password        string
+	lowerU          string
+	upperU          string
+	validationError error
+}
+
+// serverInventoryColumns are the columns of a csv inventory file, in the order used by the examples
+var serverInventoryColumns = []string{"datacenter", "vendor", "mgmt_address", "user", "password", "rack", "u_range", "inventory_id"}
+
+// readServerInventory reads the rows of a csv or yaml inventory file. The csv file must have a header row.
+func readServerInventory(path string, format string) ([]serverInventoryRow, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = "csv"
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+			format = "yaml"
+		}
+	}
+
+	rows := []serverInventoryRow{}
+
+	switch format {
+	case "yaml":
+		if err := yaml.Unmarshal(content, &rows); err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			rows[i].Row = i + 1
+		}
+
+	case "csv":
+		records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(records) == 0 {
+			return nil, fmt.Errorf("inventory file %s is empty", path)
+		}
+
+		columns := map[string]int{}
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+
+		for _, name := range []string{"datacenter", "vendor", "mgmt_address", "user", "password"} {
+			if _, ok := columns[name]; !ok {
+				return nil, fmt.Errorf("inventory file %s has no %s column. The columns are: %s", path, name, strings.Join(serverInventoryColumns, ","))
+			}
+		}
+
+		get := func(record []string, name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		for i, record := range records[1:] {
+			rows = append(rows, serverInventoryRow{
+				Row:         i + 1,
+				Datacenter:  get(record, "datacenter"),
+				Vendor:      get(record, "vendor"),
+				MgmtAddress: get(record, "mgmt_address"),
+				User:        get(record, "user"),
+				PasswordRef: get(record, "password"),
+				Rack:        get(record, "rack"),
+				URange:      get(record, "u_range"),
+				InventoryID: get(record, "inventory_id"),
+			})
+		}
+
+	default:
+		return nil, fmt.Errorf("inventory format \"%s\" not supported", format)
+	}
+
+	return rows, nil
+}
+
+// validateServerInventoryRow checks the required fields, resolves the password reference and parses the U range
+func validateServerInventoryRow(row *serverInventoryRow) error {
+
+	missing := []string{}
+	for name, v := range map[string]string{
+		"datacenter":   row.Datacenter,
+		"vendor":       row.Vendor,
+		"mgmt_address": row.MgmtAddress,
+		"user":         row.User,
+		"password":     row.PasswordRef,
+	} {
+		if v == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	password, err := resolvePasswordReference(row.PasswordRef)
+	if err != nil {
+		return err
+	}
+	row.password = password
+
+	if row.URange != "" {
+		bounds := strings.Split(row.URange, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid U range %s, expecting lower-upper", row.URange)
+		}
+
+		lower, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return fmt.Errorf("invalid U range %s, expecting lower-upper", row.URange)
+		}
+
+		upper := lower
+		if len(bounds) == 2 {
+			upper, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || upper < lower {
+				return fmt.Errorf("invalid U range %s, expecting lower-upper", row.URange)
+			}
+		}
+
+		row.lowerU = strconv.Itoa(lower)
+		row.upperU = strconv.Itoa(upper)
+
+		if row.Rack == "" {
+			return fmt.Errorf("a rack is required when the U range is set")
+		}
+	}
+
+	return nil
+}
+
+// resolvePasswordReference returns the password designated by a reference such as env:VARIABLE or file:/path/to/file.
+// Plain text passwords are not accepted so that inventory files can be shared safely.
+func resolvePasswordReference(ref string) (string, error) {
+
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+
+	case strings.HasPrefix(ref, "file:"):
+		path := strings.TrimPrefix(ref, "file:")
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		v := strings.TrimRight(string(content), "\r\n")
+		if v == "" {
+			return "", fmt.Errorf("password file %s is empty", path)
+		}
+		return v, nil
+	}
+
+	return "", fmt.Errorf("the password must be a reference such as env:VARIABLE or file:/path/to/file")
+}
+
+func serverRegisterFromFileCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	inventoryFile := getStringParam(c.Arguments["inventory_file"])
+
+	rows, err := readServerInventory(inventoryFile, getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if len(rows) == 0 {
+		return "", fmt.Errorf("inventory file %s has no servers", inventoryFile)
+	}
+
+	resultsFile := inventoryFile + ".results.csv"
+	if v, ok := getStringParamOk(c.Arguments["results_file"]); ok {
+		resultsFile = v
+	}
+
+	valid := []int{}
+	for i := range rows {
+		rows[i].validationError = validateServerInventoryRow(&rows[i])
+		if rows[i].validationError == nil {
+			valid = append(valid, i)
+		}
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Registering %d servers from %s", len(valid), inventoryFile)
+		if invalid := len(rows) - len(valid); invalid > 0 {
+			confirmationMessage += fmt.Sprintf(", %s", red(fmt.Sprintf("%d invalid rows are skipped", invalid)))
+		}
+		confirmationMessage += ". Are you sure? Type \"yes\" to continue:"
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	serverIDs := make([]int, len(rows))
+	errs := make([]error, len(rows))
+
+	for i := range rows {
+		errs[i] = rows[i].validationError
+	}
+
+	concurrency := 10
+	if v, ok := getIntParamOk(c.Arguments["concurrency"]); ok {
+		concurrency = v
+	}
+
+	//errors that happen after the server was registered are kept apart so that the row is not registered again
+	postEditErrs := make([]error, len(rows))
+
+	registerErrs := runConcurrently(len(valid), concurrency, func(i int) error {
+		row := rows[valid[i]]
+
+		serverID, err := client.ServerCreateAndRegister(metalcloud.ServerCreateAndRegister{
+			DatacenterName:           row.Datacenter,
+			ServerVendor:             row.Vendor,
+			ServerManagementAddress:  row.MgmtAddress,
+			ServerManagementUser:     row.User,
+			ServerManagementPassword: row.password,
+		})
+		if err != nil {
+			return err
+		}
+
+		serverIDs[valid[i]] = serverID
+
+		if row.Rack != "" {
+			rack := metalcloud.ServerEditRack{
+				ServerRackName: &row.Rack,
+			}
+			if row.lowerU != "" {
+				rack.ServerRackPositionLowerUnit = &row.lowerU
+				rack.ServerRackPositionUpperUnit = &row.upperU
+			}
+
+			if _, err := client.ServerEditRack(serverID, rack); err != nil {
+				postEditErrs[valid[i]] = fmt.Errorf("setting the rack info failed: %v", err)
+				return nil
+			}
+		}
+
+		if row.InventoryID != "" {
+			inventory := metalcloud.ServerEditInventory{
+				ServerInventoryId: &row.InventoryID,
+			}
+
+			if _, err := client.ServerEditInventory(serverID, inventory); err != nil {
+				postEditErrs[valid[i]] = fmt.Errorf("setting the inventory id failed: %v", err)
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	for i, err := range registerErrs {
+		errs[valid[i]] = err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ROW",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 4,
+		},
+		{
+			FieldName: "MGMT ADDRESS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "SERVER ID",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "RESULT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+	}
+
+	data := [][]interface{}{}
+	results := [][]string{{"row", "mgmt_address", "server_id", "status", "error"}}
+	failed := 0
+	postEditFailed := 0
+
+	for i, row := range rows {
+
+		serverID := ""
+		if serverIDs[i] != 0 {
+			serverID = strconv.Itoa(serverIDs[i])
+		}
+
+		status := "registered"
+		message := ""
+		result := green(status)
+		if errs[i] != nil {
+			failed++
+			status = "failed"
+			message = errs[i].Error()
+			result = red(message)
+		} else if postEditErrs[i] != nil {
+			postEditFailed++
+			status = "registered, post-edit failed"
+			message = postEditErrs[i].Error()
+			result = yellow(fmt.Sprintf("%s: %s", status, message))
+		}
+
+		data = append(data, []interface{}{row.Row, row.MgmtAddress, serverID, result})
+		results = append(results, []string{strconv.Itoa(row.Row), row.MgmtAddress, serverID, status, message})
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(results); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(resultsFile, buf.Bytes(), 0600); err != nil {
+		return "", err
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	ret, err := table.RenderTable(fmt.Sprintf("Registered %d of %d servers", len(rows)-failed, len(rows)), fmt.Sprintf("Results written to %s", resultsFile), "")
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 || postEditFailed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("%d of %d servers could not be registered. See %s", failed, len(rows), resultsFile)
+	}
+
+	if postEditFailed > 0 {
+		return "", fmt.Errorf("%d servers were registered but their rack or inventory info could not be set. Do not register them again, use 'server rack-info-set' or 'server inventory-info-set'. See %s", postEditFailed, resultsFile)
+	}
+
+	return ret, nil
+}
+
+// serverComponentRow is a hardware component of a server. Components that are not tracked individually
+// by the platform, such as the cpus or the memory, have no id.
+type serverComponentRow struct {
+	ServerID     int
+	ServerSerial string
+	ComponentID  int
+	Type         string
+	Name         string
+	Serial       string
+	Firmware     string
+	Status       string
+	Details      string
+}
+
+func serverComponentsCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	serverIDs := []int{}
+
+	if _, ok := getStringParamOk(c.Arguments["server_id_or_uuid"]); ok {
+		server, err := getServerFromCommand("id", c, client, false)
+		if err != nil {
+			return "", err
+		}
+		serverIDs = append(serverIDs, server.ServerID)
+	} else if filter, ok := getStringParamOk(c.Arguments["filter"]); ok {
+		list, err := client.ServersSearch(convertToSearchFieldFormat(filter))
+		if err != nil {
+			return "", err
+		}
+		for _, s := range *list {
+			serverIDs = append(serverIDs, s.ServerID)
+		}
+	} else {
+		return "", fmt.Errorf("-id or -filter is required")
+	}
+
+	types := map[string]bool{}
+	if v, ok := getStringParamOk(c.Arguments["component_type"]); ok {
+		for _, t := range strings.Split(v, ",") {
+			types[strings.ToLower(strings.TrimSpace(t))] = true
+		}
+	}
+
+	model := strings.ToLower(getStringParam(c.Arguments["component_model"]))
+
+	rows := make([][]serverComponentRow, len(serverIDs))
+
+	errs := runConcurrently(len(serverIDs), getIntParam(c.Arguments["concurrency"]), func(i int) error {
+		list, err := getServerComponentRows(serverIDs[i], client)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range list {
+			if len(types) > 0 && !types[strings.ToLower(r.Type)] {
+				continue
+			}
+			if model != "" && !strings.Contains(strings.ToLower(r.Name), model) {
+				continue
+			}
+			rows[i] = append(rows[i], r)
+		}
+
+		return nil
+	})
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "SERVER_ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "SERVER_SERIAL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "COMPONENT_ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "MODEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "SERIAL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "FIRMWARE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "DETAILS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	data := [][]interface{}{}
+	failures := []string{}
+
+	for i, list := range rows {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("server #%d: %v", serverIDs[i], errs[i]))
+			continue
+		}
+		for _, r := range list {
+			data = append(data, []interface{}{
+				r.ServerID,
+				r.ServerSerial,
+				r.ComponentID,
+				r.Type,
+				r.Name,
+				r.Serial,
+				r.Firmware,
+				r.Status,
+				r.Details,
+			})
+		}
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	ret, err := table.RenderTable("Components", fmt.Sprintf("%d components of %d servers", len(data), len(serverIDs)), getStringParam(c.Arguments["format"]))
+	if err != nil {
+		return "", err
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("could not read the components of %d servers:\n%s", len(failures), strings.Join(failures, "\n"))
+	}
+
+	return ret, nil
+}
+
+// getServerComponentRows returns the cpus, memory, disks, gpus and nics recorded on the server
+// followed by the components tracked for firmware management.
+func getServerComponentRows(serverID int, client metalcloud.MetalCloudClient) ([]serverComponentRow, error) {
+
+	server, err := client.ServerGet(serverID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	components, err := client.ServerComponents(serverID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	row := func(componentType string, name string) serverComponentRow {
+		return serverComponentRow{
+			ServerID:     server.ServerID,
+			ServerSerial: server.ServerSerialNumber,
+			Type:         componentType,
+			Name:         name,
+		}
+	}
+
+	rows := []serverComponentRow{}
+
+	if server.ServerProcessorCount > 0 {
+		r := row("cpu", server.ServerProcessorName)
+		r.Details = fmt.Sprintf("%dx %d cores @ %d MHz", server.ServerProcessorCount, server.ServerProcessorCoreCount, server.ServerProcessorCoreMhz)
+		rows = append(rows, r)
+	}
+
+	if server.ServerRAMGbytes > 0 {
+		r := row("memory", "")
+		r.Details = fmt.Sprintf("%d GB", server.ServerRAMGbytes)
+		rows = append(rows, r)
+	}
+
+	for _, d := range server.ServerDisks {
+		r := row("disk", strings.TrimSpace(d.ServerDiskVendor+" "+d.ServerDiskModel))
+		r.ComponentID = d.ServerDiskID
+		r.Serial = d.ServerDiskSerial
+		r.Status = d.ServerDiskStatus
+		r.Details = strings.TrimSpace(fmt.Sprintf("%d GB %s", d.ServerDiskSizeGB, d.ServerDiskType))
+		rows = append(rows, r)
+	}
+
+	if server.ServerGPUModel != "" {
+		r := row("gpu", strings.TrimSpace(server.ServerGPUVendor+" "+server.ServerGPUModel))
+		r.Details = fmt.Sprintf("%d GPUs", server.ServerGPUCount)
+		rows = append(rows, r)
+	}
+
+	for _, intf := range server.ServerInterfaces {
+		r := row("nic", "")
+		r.Details = intf.ServerInterfaceMACAddress
+		rows = append(rows, r)
+	}
+
+	for _, sc := range *components {
+		r := row(sc.ServerComponentType, sc.ServerComponentName)
+		r.ComponentID = sc.ServerComponentID
+		r.Firmware = sc.ServerComponentFirmwareVersion
+		r.Status = sc.ServerComponentFirmwareStatus
+		rows = append(rows, r)
+	}
+
+	return rows, nil
+}
+
+// getServerSelectionFromCommand returns the servers selected with one of the server_id, server_ids, server_ids_file or filter arguments
+func getServerSelectionFromCommand(c *Command, client metalcloud.MetalCloudClient) ([]metalcloud.ServerSearchResult, error) {
+
+	ids := []int{}
+
+	if id, ok := getIntParamOk(c.Arguments["server_id"]); ok {
+		ids = append(ids, id)
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["server_ids"]); ok {
+		list, err := parseServerIDs(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, list...)
+	}
+
+	if path, ok := getStringParamOk(c.Arguments["server_ids_file"]); ok {
+		content, err := readInputFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		list, err := parseServerIDs(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no server ids found in %s", path)
+		}
+		ids = append(ids, list...)
+	}
+
+	filter, filterOk := getStringParamOk(c.Arguments["filter"])
+
+	if len(ids) == 0 && !filterOk {
+		return nil, fmt.Errorf("one of -id, -ids, -ids-from-file or -filter is required")
+	}
+
+	if len(ids) > 0 && filterOk {
+		return nil, fmt.Errorf("-filter cannot be used together with -id, -ids or -ids-from-file")
+	}
+
+	if filterOk {
+		list, err := client.ServersSearch(convertToSearchFieldFormat(filter))
+		if err != nil {
+			return nil, err
+		}
+		if len(*list) == 0 {
+			return nil, fmt.Errorf("no servers match filter %q", filter)
+		}
+		return *list, nil
+	}
+
+	servers := []metalcloud.ServerSearchResult{}
+	seen := map[int]bool{}
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		list, err := client.ServersSearch(fmt.Sprintf("+server_id:%d", id))
+		if err != nil {
+			return nil, err
+		}
+		if len(*list) == 0 {
+			return nil, fmt.Errorf("server #%d not found", id)
+		}
+		servers = append(servers, (*list)[0])
+	}
+
+	return servers, nil
+}
+
+// parseServerIDs parses a list of server ids separated by commas, spaces or new lines
+func parseServerIDs(s string) ([]int, error) {
+	ids := []int{}
+
+	for _, idStr := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		id, err := strconv.Atoi(strings.TrimPrefix(idStr, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid server id %q", idStr)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func serverDecommissionCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+	return serverRetireCmd(c, client, "decommission", client.ServerDecomission)
+}
+
+func serverDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+	return serverRetireCmd(c, client, "delete", client.ServerDelete)
+}
+
+// serverRetireCmd decommissions or deletes the selected servers after checking none of them is allocated.
+// The switch interfaces of the servers are shown in the confirmation as they are freed by the operation.
+func serverRetireCmd(c *Command, client metalcloud.MetalCloudClient, action string, f func(serverID int, skipIPMI bool) error) (string, error) {
+
+	servers, err := getServerSelectionFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	allocated := []string{}
+	for _, s := range servers {
+		if len(s.InstanceID) > 0 || s.ServerStatus == "used" || s.ServerStatus == "used_registering" {
+			allocated = append(allocated, fmt.Sprintf("#%d (%s) is used by instance %s of infrastructure %s",
+				s.ServerID,
+				s.ServerSerialNumber,
+				intsToString(s.InstanceID),
+				intsToString(s.InfrastructureID)))
+		}
+	}
+
+	if len(allocated) > 0 {
+		return "", fmt.Errorf("cannot %s allocated servers. Delete their instances first:\n%s", action, strings.Join(allocated, "\n"))
+	}
+
+	interfaces := map[int][]metalcloud.SwitchInterfaceSearchResult{}
+	for _, s := range servers {
+		list, err := client.SwitchInterfaceSearch(fmt.Sprintf("server_id:%d", s.ServerID))
+		if err != nil {
+			return "", err
+		}
+		interfaces[s.ServerID] = *list
+	}
+
+	wipe := getBoolParam(c.Arguments["wipe"])
+
+	confirm, err := confirmCommand(c, func() string {
+
+		data := [][]interface{}{}
+		for _, s := range servers {
+			if len(interfaces[s.ServerID]) == 0 {
+				data = append(data, []interface{}{s.ServerID, s.ServerSerialNumber, s.ServerStatus, s.ServerRackName, "", ""})
+			}
+			for _, intf := range interfaces[s.ServerID] {
+				data = append(data, []interface{}{
+					s.ServerID,
+					s.ServerSerialNumber,
+					s.ServerStatus,
+					s.ServerRackName,
+					intf.NetworkEquipmentIdentifierString,
+					intf.NetworkEquipmentInterfaceIdentifierString,
+				})
+			}
+		}
+
+		table := tableformatter.Table{
+			Data: data,
+			Schema: []tableformatter.SchemaField{
+				{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+				{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+				{FieldName: "STATUS", FieldType: tableformatter.TypeString, FieldSize: 10},
+				{FieldName: "RACK", FieldType: tableformatter.TypeString, FieldSize: 10},
+				{FieldName: "SWITCH", FieldType: tableformatter.TypeString, FieldSize: 15},
+				{FieldName: "SWITCH INTERFACE", FieldType: tableformatter.TypeString, FieldSize: 15},
+			},
+		}
+
+		plan, _ := table.RenderTable("Servers", "The switch interfaces will be freed", "")
+
+		wipeMessage := ""
+		if wipe {
+			wipeMessage = " The servers will be cleaned first."
+		}
+
+		confirmationMessage := fmt.Sprintf("%sAbout to %s %d servers.%s Are you sure? Type \"yes\" to continue:",
+			plan,
+			action,
+			len(servers),
+			wipeMessage,
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	skipIPMI := getBoolParam(c.Arguments["skip_ipmi"])
+
+	errs := runConcurrently(len(servers), getIntParam(c.Arguments["concurrency"]), func(i int) error {
+		serverID := servers[i].ServerID
+
+		if wipe {
+			if err := client.ServerStatusUpdate(serverID, "cleaning"); err != nil {
+				return fmt.Errorf("could not start cleaning: %v", err)
+			}
+			fmt.Fprintf(GetStdout(), "Cleaning server #%d\n", serverID)
+
+			err := waitForServerStatus(serverID, "available", getIntParam(c.Arguments["block_timeout"]), getIntParam(c.Arguments["block_check_interval"]), client)
+			if err != nil {
+				return err
+			}
+		}
+
+		return f(serverID, skipIPMI)
+	})
+
+	data := [][]interface{}{}
+	failed := 0
+
+	for i, s := range servers {
+		status := "done"
+		message := ""
+		if errs[i] != nil {
+			status = "failed"
+			message = errs[i].Error()
+			failed++
+		}
+		data = append(data, []interface{}{
+			s.ServerID,
+			s.ServerSerialNumber,
+			len(interfaces[s.ServerID]),
+			status,
+			message,
+		})
+	}
+
+	table := tableformatter.Table{
+		Data: data,
+		Schema: []tableformatter.SchemaField{
+			{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+			{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+			{FieldName: "INTERFACES", FieldType: tableformatter.TypeInt, FieldSize: 6},
+			{FieldName: "STATUS", FieldType: tableformatter.TypeString, FieldSize: 8},
+			{FieldName: "ERROR", FieldType: tableformatter.TypeString, FieldSize: 30},
+		},
+	}
+
+	ret, err := table.RenderTable("Servers", fmt.Sprintf("%s succeeded for %d of %d servers", action, len(servers)-failed, len(servers)), "")
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("could not %s %d of %d servers", action, failed, len(servers))
+	}
+
+	return ret, nil
+}
+
+// waitForServerStatus polls a server until it reaches the given status
+func waitForServerStatus(serverID int, status string, timeoutSeconds int, checkIntervalSeconds int, client metalcloud.MetalCloudClient) error {
+
+	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+
+	for {
+		server, err := client.ServerGet(serverID, false)
+		if err != nil {
+			return err
+		}
+
+		if server.ServerStatus == status {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %d seconds while waiting for server #%d to become %s. Current status: %s", timeoutSeconds, serverID, status, server.ServerStatus)
+		}
+
+		time.Sleep(time.Duration(checkIntervalSeconds) * time.Second)
+	}
+}
+
+func intsToString(list []int) string {
+	s := []string{}
+	for _, v := range list {
+		s = append(s, fmt.Sprintf("#%d", v))
+	}
+	return strings.Join(s, ",")
+}
+
+// ipmiRotationEntry is the state of one server in an IPMI credentials rotation report
+type ipmiRotationEntry struct {
+	ServerID     int    `json:"server_id"`
+	SerialNumber string `json:"serial_number"`
+	IPMIHost     string `json:"ipmi_host"`
+	Username     string `json:"username"`
+	OldPassword  string `json:"old_password"`
+	NewPassword  string `json:"new_password"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+}
+
+const (
+	ipmiRotationPending = "pending"
+	ipmiRotationRotated = "rotated"
+	ipmiRotationFailed  = "failed"
+)
+
+// ipmiMaxPasswordLength is the maximum password length supported by IPMI 2.0
+const ipmiMaxPasswordLength = 20
+
+// serverRotateIPMICmd changes the IPMI password of the selected servers, both in the database and on the BMC.
+// The old credentials are saved in the encrypted report before any change is made so that they are never lost.
+func serverRotateIPMICmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	reportFile, ok := getStringParamOk(c.Arguments["report_file"])
+	if !ok {
+		return "", fmt.Errorf("-report is required")
+	}
+
+	keyRef, ok := getStringParamOk(c.Arguments["report_key"])
+	if !ok {
+		return "", fmt.Errorf("-report-key is required")
+	}
+
+	key, err := resolvePasswordReference(keyRef)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(reportFile); err == nil {
+		return "", fmt.Errorf("report file %s already exists", reportFile)
+	}
+
+	entries, err := getIPMIRotationEntries(c, client, reportFile, key)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		data := [][]interface{}{}
+		for _, e := range entries {
+			data = append(data, []interface{}{e.ServerID, e.SerialNumber, e.IPMIHost})
+		}
+
+		table := tableformatter.Table{
+			Data: data,
+			Schema: []tableformatter.SchemaField{
+				{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+				{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+				{FieldName: "IPMI_HOST", FieldType: tableformatter.TypeString, FieldSize: 15},
+			},
+		}
+
+		plan, _ := table.RenderTable("Servers", "", "")
+
+		confirmationMessage := fmt.Sprintf("%sAbout to rotate the IPMI credentials of %d servers. The credentials will also be changed on the BMC of each server. Are you sure? Type \"yes\" to continue:",
+			plan,
+			len(entries),
+		)
+
+		//this is simply so that we don't output a text on the command line under go test
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if !confirm {
+		return "", fmt.Errorf("Operation not confirmed. Aborting")
+	}
+
+	concurrency := getIntParam(c.Arguments["concurrency"])
+
+	//the current credentials are read and saved first
+	servers := make([]*metalcloud.Server, len(entries))
+
+	errs := runConcurrently(len(entries), concurrency, func(i int) error {
+		server, err := client.ServerGet(entries[i].ServerID, true)
+		if err != nil {
+			return fmt.Errorf("could not read the current credentials: %v", err)
+		}
+		servers[i] = server
+		entries[i].IPMIHost = server.ServerIPMIHost
+		entries[i].Username = server.ServerIPMInternalUsername
+		entries[i].OldPassword = server.ServerIPMInternalPassword
+		return nil
+	})
+
+	for i := range entries {
+		entries[i].Status = ipmiRotationPending
+		if errs[i] != nil {
+			entries[i].Status = ipmiRotationFailed
+			entries[i].Error = errs[i].Error()
+		}
+	}
+
+	if err := writeIPMIRotationReport(reportFile, key, entries); err != nil {
+		return "", fmt.Errorf("could not write the report, no credentials were changed: %v", err)
+	}
+
+	errs = runConcurrently(len(entries), concurrency, func(i int) error {
+		if entries[i].Status != ipmiRotationPending {
+			return nil
+		}
+
+		newServer := *servers[i]
+		newServer.ServerIPMInternalPassword = entries[i].NewPassword
+
+		if _, err := client.ServerEditIPMI(entries[i].ServerID, newServer, true); err != nil {
+			return err
+		}
+
+		server, err := client.ServerGet(entries[i].ServerID, true)
+		if err != nil {
+			return fmt.Errorf("could not verify the new credentials: %v", err)
+		}
+
+		if server.ServerIPMInternalUsername != entries[i].Username || server.ServerIPMInternalPassword != entries[i].NewPassword {
+			return fmt.Errorf("the new credentials were not saved")
+		}
+
+		return nil
+	})
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	failed := 0
+
+	for i := range entries {
+		if entries[i].Status == ipmiRotationPending {
+			entries[i].Status = ipmiRotationRotated
+			entries[i].UpdatedAt = now
+			if errs[i] != nil {
+				entries[i].Status = ipmiRotationFailed
+				entries[i].Error = errs[i].Error()
+			}
+		}
+		if entries[i].Status == ipmiRotationFailed {
+			failed++
+		}
+	}
+
+	if err := writeIPMIRotationReport(reportFile, key, entries); err != nil {
+		return "", fmt.Errorf("could not update the report %s: %v", reportFile, err)
+	}
+
+	ret, err := renderIPMIRotationEntries(entries, false, fmt.Sprintf("Rotated %d of %d servers. The report was written to %s", len(entries)-failed, len(entries), reportFile), "")
+	if err != nil {
+		return "", err
+	}
+
+	if failed > 0 {
+		fmt.Fprint(GetStdout(), ret)
+		return "", fmt.Errorf("could not rotate the IPMI credentials of %d of %d servers. Retry them with --retry-from %s", failed, len(entries), reportFile)
+	}
+
+	return ret, nil
+}
+
+// getIPMIRotationEntries returns the servers to rotate together with their new passwords
+func getIPMIRotationEntries(c *Command, client metalcloud.MetalCloudClient, reportFile string, key string) ([]ipmiRotationEntry, error) {
+
+	entries := []ipmiRotationEntry{}
+
+	if retryFrom, ok := getStringParamOk(c.Arguments["retry_from"]); ok {
+
+		for _, arg := range []string{"server_id", "server_ids", "server_ids_file", "filter", "passwords_file"} {
+			if _, ok := getPtrValueIfExistsOk(c.Arguments, arg); ok {
+				return nil, fmt.Errorf("-retry-from cannot be used together with -id, -ids, -ids-from-file, -filter or -passwords-from-file")
+			}
+		}
+
+		if retryFrom == reportFile {
+			return nil, fmt.Errorf("the report of the retry must be written to a different file than %s", retryFrom)
+		}
+
+		previous, err := readIPMIRotationReport(retryFrom, key)
+		if err != nil {
+			return nil, err
+		}
+
+		//the same new passwords are used as some BMCs might have been changed already
+		for _, e := range previous {
+			if e.Status != ipmiRotationRotated {
+				entries = append(entries, ipmiRotationEntry{
+					ServerID:     e.ServerID,
+					SerialNumber: e.SerialNumber,
+					IPMIHost:     e.IPMIHost,
+					NewPassword:  e.NewPassword,
+				})
+			}
+		}
+
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("all the servers in %s were rotated, there is nothing to retry", retryFrom)
+		}
+
+		return entries, nil
+	}
+
+	servers, err := getServerSelectionFromCommand(c, client)
+	if err != nil {
+		return nil, err
+	}
+
+	passwords := map[int]string{}
+
+	if passwordsFile, ok := getStringParamOk(c.Arguments["passwords_file"]); ok {
+		passwords, err = readIPMIPasswordsFile(passwordsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	passwordLength := getIntParam(c.Arguments["password_length"])
+	if passwordLength > ipmiMaxPasswordLength {
+		return nil, fmt.Errorf("-password-length cannot be larger than %d", ipmiMaxPasswordLength)
+	}
+
+	missing := []int{}
+
+	for _, s := range servers {
+
+		password, ok := passwords[s.ServerID]
+
+		if len(passwords) > 0 && !ok {
+			missing = append(missing, s.ServerID)
+			continue
+		}
+
+		if !ok {
+			password, err = generatePassword(passwordLength)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, ipmiRotationEntry{
+			ServerID:     s.ServerID,
+			SerialNumber: s.ServerSerialNumber,
+			IPMIHost:     s.ServerIPMIHost,
+			NewPassword:  password,
+		})
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the passwords file has no password for servers %s", intsToString(missing))
+	}
+
+	return entries, nil
+}
+
+// readIPMIPasswordsFile reads a CSV file with server_id,password rows. A header row is optional.
+func readIPMIPasswordsFile(path string) (map[int]string, error) {
+
+	content, err := readInputFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = 2
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	passwords := map[int]string{}
+
+	for i, record := range records {
+		if i == 0 && record[0] == "server_id" {
+			continue
+		}
+
+		id, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: invalid server id %q", path, i+1, record[0])
+		}
+
+		if record[1] == "" || len(record[1]) > ipmiMaxPasswordLength {
+			return nil, fmt.Errorf("%s: line %d: the password must have between 1 and %d characters", path, i+1, ipmiMaxPasswordLength)
+		}
+
+		passwords[id] = record[1]
+	}
+
+	if len(passwords) == 0 {
+		return nil, fmt.Errorf("no passwords found in %s", path)
+	}
+
+	return passwords, nil
+}
+
+func writeIPMIRotationReport(path string, key string, entries []ipmiRotationEntry) error {
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := encryptWithPassphrase(data, key)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, encrypted, 0600)
+}
+
+func readIPMIRotationReport(path string, key string) ([]ipmiRotationEntry, error) {
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decryptWithPassphrase(content, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	entries := []ipmiRotationEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return entries, nil
+}
+
+func renderIPMIRotationEntries(entries []ipmiRotationEntry, showPasswords bool, subtitle string, format string) (string, error) {
+
+	schema := []tableformatter.SchemaField{
+		{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+		{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "IPMI_HOST", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "USERNAME", FieldType: tableformatter.TypeString, FieldSize: 10},
+		{FieldName: "STATUS", FieldType: tableformatter.TypeString, FieldSize: 8},
+		{FieldName: "UPDATED", FieldType: tableformatter.TypeString, FieldSize: 20},
+		{FieldName: "ERROR", FieldType: tableformatter.TypeString, FieldSize: 30},
+	}
+
+	if showPasswords {
+		schema = append(schema,
+			tableformatter.SchemaField{FieldName: "OLD_PASSWORD", FieldType: tableformatter.TypeString, FieldSize: 20},
+			tableformatter.SchemaField{FieldName: "NEW_PASSWORD", FieldType: tableformatter.TypeString, FieldSize: 20},
+		)
+	}
+
+	data := [][]interface{}{}
+	for _, e := range entries {
+		row := []interface{}{
+			e.ServerID,
+			e.SerialNumber,
+			e.IPMIHost,
+			e.Username,
+			e.Status,
+			e.UpdatedAt,
+			e.Error,
+		}
+		if showPasswords {
+			row = append(row, e.OldPassword, e.NewPassword)
+		}
+		data = append(data, row)
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTable("IPMI credentials rotation", subtitle, format)
+}
+
+func serverRotateIPMIReportCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	reportFile, ok := getStringParamOk(c.Arguments["report_file"])
+	if !ok {
+		return "", fmt.Errorf("-report is required")
+	}
+
+	keyRef, ok := getStringParamOk(c.Arguments["report_key"])
+	if !ok {
+		return "", fmt.Errorf("-report-key is required")
+	}
+
+	key, err := resolvePasswordReference(keyRef)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := readIPMIRotationReport(reportFile, key)
+	if err != nil {
+		return "", err
+	}
+
+	rotated := 0
+	for _, e := range entries {
+		if e.Status == ipmiRotationRotated {
+			rotated++
+		}
+	}
+
+	subtitle := fmt.Sprintf("Rotated %d of %d servers", rotated, len(entries))
+
+	return renderIPMIRotationEntries(entries, getBoolParam(c.Arguments["show_passwords"]), subtitle, getStringParam(c.Arguments["format"]))
+}
+
+// serverProbeCmd checks the IPMI and Redfish services of the BMC of the selected servers.
+// Failed checks are reported in the table rather than as an error, as finding them is the purpose of the command.
+func serverProbeCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	servers, err := getServerSelectionFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	timeoutSeconds := getIntParam(c.Arguments["timeout"])
+	if timeoutSeconds <= 0 {
+		return "", fmt.Errorf("-timeout must be positive")
+	}
+
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	ipmiPort := getIntParam(c.Arguments["ipmi_port"])
+	redfishPort := getIntParam(c.Arguments["redfish_port"])
+	redfish := getBoolParam(c.Arguments["redfish"])
+
+	data := make([][]interface{}, len(servers))
+	reachable := make([]bool, len(servers))
+
+	runConcurrently(len(servers), getIntParam(c.Arguments["concurrency"]), func(i int) error {
+		s := servers[i]
+
+		ipmiStatus := ""
+		httpsStatus := ""
+		tlsSubject := ""
+		tlsIssuer := ""
+		tlsExpires := ""
+		vendor := ""
+		redfishStatus := ""
+
+		if s.ServerIPMIHost == "" {
+			ipmiStatus = "no IPMI host"
+		} else {
+
+			if latency, err := rmcpPing(s.ServerIPMIHost, ipmiPort, timeout); err != nil {
+				ipmiStatus = fmt.Sprintf("failed: %v", err)
+			} else {
+				ipmiStatus = formatLatency(latency)
+				reachable[i] = true
+			}
+
+			latency, cert, err := tlsProbe(s.ServerIPMIHost, redfishPort, timeout)
+			if latency > 0 {
+				httpsStatus = formatLatency(latency)
+				reachable[i] = true
+			}
+			if err != nil {
+				httpsStatus = strings.TrimSpace(fmt.Sprintf("%s failed: %v", httpsStatus, err))
+			}
+
+			if cert != nil {
+				tlsSubject = cert.Subject.CommonName
+				tlsIssuer = cert.Issuer.CommonName
+				if cert.Subject.String() == cert.Issuer.String() {
+					tlsIssuer = "self-signed"
+				}
+				tlsExpires = cert.NotAfter.Format("2006-01-02")
+				if time.Now().After(cert.NotAfter) {
+					tlsExpires += " (expired)"
+				}
+			}
+
+			if redfish && err == nil {
+				root, err := probeRedfish(s.ServerID, s.ServerIPMIHost, redfishPort, cert, timeout, client)
+				if err != nil {
+					redfishStatus = fmt.Sprintf("failed: %v", err)
+				} else {
+					vendor = strings.TrimSpace(fmt.Sprintf("%s %s", root.GetVendor(), root.Product))
+					redfishStatus = root.RedfishVersion
+				}
+			}
+		}
+
+		data[i] = []interface{}{
+			s.ServerID,
+			s.ServerSerialNumber,
+			s.ServerStatus,
+			s.ServerIPMIHost,
+			ipmiStatus,
+			httpsStatus,
+			tlsSubject,
+			tlsIssuer,
+			tlsExpires,
+			vendor,
+			redfishStatus,
+		}
+
+		return nil
+	})
+
+	reachableCount := 0
+	for _, r := range reachable {
+		if r {
+			reachableCount++
+		}
+	}
+
+	schema := []tableformatter.SchemaField{
+		{FieldName: "ID", FieldType: tableformatter.TypeInt, FieldSize: 6},
+		{FieldName: "SERIAL", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "STATUS", FieldType: tableformatter.TypeString, FieldSize: 10},
+		{FieldName: "IPMI_HOST", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "IPMI", FieldType: tableformatter.TypeString, FieldSize: 10},
+		{FieldName: "HTTPS", FieldType: tableformatter.TypeString, FieldSize: 10},
+		{FieldName: "TLS_SUBJECT", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "TLS_ISSUER", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "TLS_EXPIRES", FieldType: tableformatter.TypeString, FieldSize: 10},
+		{FieldName: "VENDOR", FieldType: tableformatter.TypeString, FieldSize: 15},
+		{FieldName: "REDFISH", FieldType: tableformatter.TypeString, FieldSize: 10},
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := fmt.Sprintf("%d of %d BMCs reachable. IPMI probed on UDP port %d, HTTPS on TCP port %d.", reachableCount, len(servers), ipmiPort, redfishPort)
+
+	return table.RenderTable("BMC probe", subtitle, getStringParam(c.Arguments["format"]))
+}
+
+// probeRedfish reads the Redfish service root of a server using its stored IPMI credentials.
+// The credentials are only sent to the BMC presenting the certificate seen by the probe.
+func probeRedfish(serverID int, host string, port int, cert *x509.Certificate, timeout time.Duration, client metalcloud.MetalCloudClient) (*redfishServiceRoot, error) {
+
+	server, err := client.ServerGet(serverID, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the credentials: %v", err)
+	}
+
+	return getRedfishServiceRoot(host, port, cert, server.ServerIPMInternalUsername, server.ServerIPMInternalPassword, timeout)
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	gomock "github.com/golang/mock/gomock"
@@ -451,3 +455,720 @@ func TestServerRegisterCmd(t *testing.T) {
 const _serverFixture1 = "{\"server_id\":310,\"agent_id\":44,\"datacenter_name\":\"es-madrid\",\"server_uuid\":\"44454C4C-5900-1033-8032-B9C04F434631\",\"server_serial_number\":\"9Y32CF1\",\"server_product_name\":\"PowerEdge 1950\",\"server_vendor\":\"Dell Inc.\",\"server_vendor_sku_id\":\"0\",\"server_ipmi_host\":\"10.255.237.28\",\"server_ipmi_internal_username\":\"ddd\",\"server_ipmi_internal_password_encrypted\":\"BSI\\\\JSONRPC\\\\Server\\\\Security\\\\Authorization\\\\DeveloperAuthorization: Not leaking database encrypted values for extra security.\",\"server_ipmi_version\":\"2\",\"server_ram_gbytes\":8,\"server_processor_count\":2,\"server_processor_core_mhz\":2333,\"server_processor_core_count\":4,\"server_processor_name\":\"Intel(R) Xeon(R) CPU           E5345  @ 2.33GHz\",\"server_processor_cpu_mark\":0,\"server_processor_threads\":1,\"server_type_id\":14,\"server_status\":\"available\",\"server_comments\":\"a\",\"server_details_xml\":null,\"server_network_total_capacity_mbps\":4000,\"server_ipmi_channel\":0,\"server_power_status\":\"off\",\"server_power_status_last_update_timestamp\":\"2020-08-19T08:42:22Z\",\"server_ilo_reset_timestamp\":\"0000-00-00T00:00:00Z\",\"server_boot_last_update_timestamp\":null,\"server_bdk_debug\":false,\"server_dhcp_status\":\"deny_requests\",\"server_bios_info_json\":\"{\\\"server_bios_vendor\\\":\\\"Dell Inc.\\\",\\\"server_bios_version\\\":\\\"2.7.0\\\"}\",\"server_vendor_info_json\":\"{\\\"management\\\":\\\"iDRAC\\\",\\\"version\\\":\\\"er] rpcRoundRobinConnectedAgentsOfType() failed with error: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n FetchError: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n\\\\n    at ClientRequest.<anonymous> (\\\\/var\\\\/datacenter-agents-binary-compiled-temp\\\\/Power\\\\/Power.portable.js:8:469877)\\\\n    at ClientRequest.emit (events.js:209:13)\\\\n    at TLSSocket.socketErrorListener (_http_client.js:406:9)\\\\n    at TLSSocket.emit (events.js:209:13)\\\\n    at errorOrDestroy (internal\\\\/streams\\\\/destroy.js:107:12)\\\\n    at onwriteError (_stream_writable.js:449:5)\\\\n    at onwrite (_stream_writable.js:470:5)\\\\n    at internal\\\\/streams\\\\/destroy.js:49:7\\\\n    at TLSSocket.Socket._destroy (net.js:595:3)\\\\n    at TLSSocket.destroy (internal\\\\/streams\\\\/destroy.js:37:8) Exception: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n FetchError: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n\\\\n    at ClientRequest.<anonymous> (\\\\/var\\\\/datacenter-agents-binary-compiled-temp\\\\/Power\\\\/Power.portable.js:8:469877)\\\\n    at ClientRequest.emit (events.js:209:13)\\\\n    at TLSSocket.socketErrorListener (_http_client.js:406:9)\\\\n    at TLSSocket.emit (events.js:209:13)\\\\n    at errorOrDestroy (internal\\\\/streams\\\\/destroy.js:107:12)\\\\n    at onwriteError (_stream_writable.js:449:5)\\\\n    at onwrite (_stream_writable.js:470:5)\\\\n    at internal\\\\/streams\\\\/destroy.js:49:7\\\\n    at TLSSocket.Socket._destroy (net.js:595:3)\\\\n    at TLSSocket.destroy (internal\\\\/streams\\\\/destroy.js:37:8)\\\\n    at \\\\/var\\\\/vhosts\\\\/bsiintegration.bigstepcloud.com\\\\/BSIWebSocketServer\\\\/node_modules\\\\/jsonrpc-bidirectional\\\\/src\\\\/Client.js:331:37\\\\n    at runMicrotasks (<anonymous>)\\\\n    at processTicksAndRejections (internal\\\\/process\\\\/task_queues.js:97:5) Exception: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n FetchError: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n\\\\n    at ClientRequest.<anonymous> (\\\\/var\\\\/datacenter-agents-binary-compiled-temp\\\\/Power\\\\/Power.portable.js:8:469877)\\\\n    at ClientRequest.emit (events.js:209:13)\\\\n    at TLSSocket.socketErrorListener (_http_client.js:406:9)\\\\n    at TLSSocket.emit (events.js:209:13)\\\\n    at errorOrDestroy (internal\\\\/streams\\\\/destroy.js:107:12)\\\\n    at onwriteError (_stream_writable.js:449:5)\\\\n    at onwrite (_stream_writable.js:470:5)\\\\n    at internal\\\\/streams\\\\/destroy.js:49:7\\\\n    at TLSSocket.Socket._destroy (net.js:595:3)\\\\n    at TLSSocket.destroy (internal\\\\/streams\\\\/destroy.js:37:8) Exception: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n FetchError: request to https:\\\\/\\\\/10.255.237.28\\\\/cgi-bin\\\\/webcgi\\\\/about failed, reason: write EPROTO 38858976:error:1425F102:SSL routines:ssl_choose_client_version:unsupported protocol:..\\\\/deps\\\\/openssl\\\\/openssl\\\\/ssl\\\\/statem\\\\/statem_lib.c:1922:\\\\n\\\\n    at ClientRequest.<anonymous> (\\\\/var\\\\/datacenter-agents-binary-compiled-temp\\\\/Power\\\\/Power.portable.js:8:469877)\\\\n    at ClientRequest.emit (events.js:209:13)\\\\n    at TLSSocket.socketErrorListener (_http_client.js:406:9)\\\\n    at TLSSocket.emit (events.js:209:13)\\\\n    at errorOrDestroy (internal\\\\/streams\\\\/destroy.js:107:12)\\\\n    at onwriteError (_stream_writable.js:449:5)\\\\n    at onwrite (_stream_writable.js:470:5)\\\\n    at internal\\\\/streams\\\\/destroy.js:49:7\\\\n    at TLSSocket.Socket._destroy (net.js:595:3)\\\\n    at TLSSocket.destroy (internal\\\\/streams\\\\/destroy.js:37:8)\\\\n    at \\\\/var\\\\/vhosts\\\\/bsiintegration.bigstepcloud.com\\\\/BSIWebSocketServer\\\\/node_modules\\\\/jsonrpc-bidirectional\\\\/src\\\\/Client.js:331:37\\\\n    at runMicrotasks (<anonymous>)\\\\n    at processTicksAndRejections (internal\\\\/process\\\\/task_queues.js:97:5)\\\\n    at \\\\/var\\\\/vhosts\\\\/bsiintegration.bigstepcloud.com\\\\/BSIWebSocketServer\\\\/node_modules\\\\/jsonrpc-bidirectional\\\\/src\\\\/Client.js:331:37\\\\n    at runMicrotasks (<anonymous>)\\\\n    at processTicksAndRejections (internal\\\\/process\\\\/tas\\\"}\",\"server_class\":\"bigdata\",\"server_created_timestamp\":\"2019-07-02T07:57:19Z\",\"subnet_oob_id\":2,\"subnet_oob_index\":28,\"server_boot_type\":\"classic\",\"server_disk_wipe\":true,\"server_disk_count\":0,\"server_disk_size_mbytes\":0,\"server_disk_type\":\"none\",\"server_requires_manual_cleaning\":false,\"chassis_rack_id\":null,\"server_custom_json\":\"{\\\"previous_ipmi_username\\\":\\\"a\\\",\\\"previous_ipmi_password_encrypted\\\":\\\"rq|aes-cbc|urfNNCbe2ouIRX3reLrILyM7tBD5I1aMPycR3YkCeFo1DGEGnNI3n6u7z63sBWpW\\\"}\",\"server_instance_custom_json\":null,\"server_last_cleanup_start\":\"2020-08-12T14:26:47Z\",\"server_allocation_timestamp\":null,\"server_dhcp_packet_sniffing_is_enabled\":true,\"snmp_community_password_dcencrypted\":null,\"server_mgmt_snmp_community_password_dcencrypted\":\"BSI\\\\JSONRPC\\\\Server\\\\Security\\\\Authorization\\\\DeveloperAuthorization: Not leaking database encrypted values for extra security.\",\"server_mgmt_snmp_port\":161,\"server_mgmt_snmp_version\":2,\"server_dhcp_relay_security_is_enabled\":true,\"server_keys_json\":\"{\\\"keys\\\": {\\\"r1\\\": {\\\"created\\\": \\\"2019-07-02T07:59:17Z\\\", \\\"salt_encrypted\\\": \\\"rq|aes-cbc|9721g561woNQzA0a3yWTcHcEYxJo7vXNc1SHmEUCxYdeOqsiVbT+X+leOHHP+XsR1gfOgs8lMhdXLOw0UUBP8g==\\\", \\\"aes_key_encrypted\\\": \\\"rq|aes-cbc|/V4Y7FMu9Uo4PyktBKl+jsAKpogNh+UC2F03jxMtJI2ieacgx/Ogso0Z9d3XlL99zh1pxAPVF24gzAogNIla0L0xBgUgLicJt41ajRYvdIo=\\\"}}, \\\"active_index\\\": \\\"r1\\\", \\\"keys_partition\\\": \\\"server_id_310\\\"}\",\"server_info_json\":null,\"server_ipmi_credentials_need_update\":false,\"server_gpu_count\":0,\"server_gpu_vendor\":\"\",\"server_gpu_model\":\"\",\"server_bmc_mac_address\":null,\"server_metrics_metadata_json\":null,\"server_interfaces\":[{\"server_interface_mac_address\":\"00:1d:09:64:f0:2b\",\"type\":\"ServerInterface\"},{\"server_interface_mac_address\":\"00:1d:09:64:f0:2d\",\"type\":\"ServerInterface\"},{\"server_interface_mac_address\":\"00:15:17:c0:4c:e6\",\"type\":\"ServerInterface\"},{\"server_interface_mac_address\":\"00:15:17:c0:4c:e7\",\"type\":\"ServerInterface\"}],\"server_disks\":[],\"server_tags\":[],\"type\":\"Server\"}"
 const _serverListFixture1 = "[\n                {\n                    \"server_id\": 16,\n                    \"server_type_name\": null,\n                    \"server_type_boot_type\": null,\n                    \"server_product_name\": null,\n                    \"datacenter_name\": \"us02-chi-qts01-dc\",\n                    \"server_status\": \"registering\",\n                    \"server_class\": \"bigdata\",\n                    \"server_created_timestamp\": \"2022-05-23T13:22:11Z\",\n                    \"server_vendor\": \"Dell Inc.\",\n                    \"server_serial_number\": null,\n                    \"server_uuid\": \"4c4c4544-0051-3810-8057-b7c04f533532\",\n                    \"server_vendor_sku_id\": null,\n                    \"server_boot_type\": \"classic\",\n                    \"server_allocation_timestamp\": null,\n                    \"instance_label\": [\n                        null\n                    ],\n                    \"instance_id\": [\n                        null\n                    ],\n                    \"instance_array_id\": [\n                        null\n                    ],\n                    \"infrastructure_id\": [\n                        null\n                    ],\n                    \"server_inventory_id\": null,\n                    \"server_rack_name\": null,\n                    \"server_rack_position_lower_unit\": null,\n                    \"server_rack_position_upper_unit\": null,\n                    \"server_ipmi_host\": \"172.18.44.42\",\n                    \"server_ipmi_internal_username\": \"root\",\n                    \"server_processor_name\": null,\n                    \"server_processor_count\": 0,\n                    \"server_processor_core_count\": 0,\n                    \"server_processor_core_mhz\": 0,\n                    \"server_processor_threads\": null,\n                    \"server_processor_cpu_mark\": null,\n                    \"server_disk_type\": \"none\",\n                    \"server_disk_count\": 0,\n                    \"server_disk_size_mbytes\": 0,\n                    \"server_ram_gbytes\": 0,\n                    \"server_network_total_capacity_mbps\": 0,\n                    \"server_dhcp_status\": \"quarantine\",\n                    \"server_dhcp_packet_sniffing_is_enabled\": true,\n                    \"server_dhcp_relay_security_is_enabled\": true,\n                    \"server_disk_wipe\": false,\n                    \"server_power_status\": \"off\",\n                    \"server_power_status_last_update_timestamp\": \"2022-05-23T13:24:41Z\",\n                    \"user_id\": [\n                        [\n                            null\n                        ]\n                    ],\n                    \"user_id_owner\": [\n                        null\n                    ],\n                    \"user_email\": [\n                        [\n                            null\n                        ]\n                    ],\n                    \"infrastructure_user_id\": [\n                        [\n                            null\n                        ]\n                    ]\n                }\n            ]"
 const _serverFixture2 = "{\n        \"server_id\": 16,\n        \"agent_id\": null,\n        \"datacenter_name\": \"us02-chi-qts01-dc\",\n        \"server_uuid\": \"4c4c4544-0051-3810-8057-b7c04f533532\",\n        \"server_serial_number\": null,\n        \"server_product_name\": null,\n        \"server_vendor\": \"Dell Inc.\",\n        \"server_vendor_sku_id\": null,\n        \"server_ipmi_host\": \"172.18.44.42\",\n        \"server_ipmi_internal_username\": \"root\",\n        \"server_ipmi_internal_password\": \"testcccc\",\n        \"server_ipmi_version\": \"2\",\n        \"server_ram_gbytes\": 0,\n        \"server_processor_count\": 0,\n        \"server_processor_core_mhz\": 0,\n        \"server_processor_core_count\": 0,\n        \"server_processor_name\": null,\n        \"server_processor_cpu_mark\": null,\n        \"server_processor_threads\": null,\n        \"server_type_id\": null,\n        \"server_status\": \"registering\",\n        \"server_comments\": null,\n        \"server_details_xml\": null,\n        \"server_network_total_capacity_mbps\": 0,\n        \"server_ipmi_channel\": 1,\n        \"server_power_status\": \"off\",\n        \"server_power_status_last_update_timestamp\": \"2022-05-23T13:24:41Z\",\n        \"server_ilo_reset_timestamp\": \"0000-00-00T00:00:00Z\",\n        \"server_boot_last_update_timestamp\": \"0000-00-00T00:00:00Z\",\n        \"server_bdk_debug\": false,\n        \"server_dhcp_status\": \"quarantine\",\n        \"server_bios_info_json\": null,\n        \"server_vendor_info_json\": null,\n        \"server_class\": \"bigdata\",\n        \"server_created_timestamp\": \"2022-05-23T13:22:11Z\",\n        \"subnet_oob_id\": 5,\n        \"subnet_oob_index\": 42,\n        \"server_boot_type\": \"classic\",\n        \"server_disk_wipe\": false,\n        \"server_disk_count\": 0,\n        \"server_disk_size_mbytes\": 0,\n        \"server_disk_type\": \"none\",\n        \"server_requires_manual_cleaning\": false,\n        \"chassis_rack_id\": null,\n        \"server_custom_json\": null,\n        \"server_instance_custom_json\": null,\n        \"server_last_cleanup_start\": null,\n        \"server_allocation_timestamp\": null,\n        \"server_dhcp_packet_sniffing_is_enabled\": true,\n        \"snmp_community_password_dcencrypted\": null,\n        \"server_mgmt_snmp_community_password_dcencrypted\": null,\n        \"server_mgmt_snmp_port\": 161,\n        \"server_mgmt_snmp_version\": 2,\n        \"server_dhcp_relay_security_is_enabled\": true,\n        \"server_keys_json\": null,\n        \"server_info_json\": null,\n        \"server_ipmi_credentials_need_update\": false,\n        \"server_gpu_count\": 0,\n        \"server_gpu_vendor\": null,\n        \"server_gpu_model\": null,\n        \"server_bmc_mac_address\": null,\n        \"server_metrics_metadata_json\": null,\n        \"server_secure_boot_is_enabled\": false,\n        \"server_chipset_name\": null,\n        \"server_requires_reregister\": false,\n        \"server_rack_name\": null,\n        \"server_rack_position_upper_unit\": null,\n        \"server_rack_position_lower_unit\": null,\n        \"server_inventory_id\": null,\n        \"server_registered_timestamp\": \"0000-00-00T00:00:00Z\",\n        \"server_interfaces\": [],\n        \"server_disks\": [],\n        \"server_tags\": [],\n        \"type\": \"Server\"\n    }"
+
+func TestServerRegisterFromFileCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	dir := t.TempDir()
+
+	os.Setenv("TEST_BMC_PASS", "secret1")
+	defer os.Unsetenv("TEST_BMC_PASS")
+
+	passFile := filepath.Join(dir, "bmc3")
+	Expect(os.WriteFile(passFile, []byte("secret3\n"), 0600)).To(BeNil())
+
+	inventory := filepath.Join(dir, "inventory.csv")
+	Expect(os.WriteFile(inventory, []byte(
+		"datacenter,vendor,mgmt_address,user,password,rack,u_range,inventory_id\n"+
+			"dc1,dell,10.0.0.1,root,env:TEST_BMC_PASS,R01,10-11,INV1\n"+
+			"dc1,dell,10.0.0.2,root,plaintext,,,\n"+
+			"dc1,hpe,10.0.0.3,admin,file:"+passFile+",,,\n"+
+			"dc1,dell,10.0.0.4,root,env:TEST_BMC_PASS,R01,bad,\n",
+	), 0600)).To(BeNil())
+
+	client.EXPECT().
+		ServerCreateAndRegister(metalcloud.ServerCreateAndRegister{
+			DatacenterName:           "dc1",
+			ServerVendor:             "dell",
+			ServerManagementAddress:  "10.0.0.1",
+			ServerManagementUser:     "root",
+			ServerManagementPassword: "secret1",
+		}).
+		Return(101, nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerCreateAndRegister(metalcloud.ServerCreateAndRegister{
+			DatacenterName:           "dc1",
+			ServerVendor:             "hpe",
+			ServerManagementAddress:  "10.0.0.3",
+			ServerManagementUser:     "admin",
+			ServerManagementPassword: "secret3",
+		}).
+		Return(0, fmt.Errorf("bmc unreachable")).
+		Times(1)
+
+	client.EXPECT().
+		ServerEditRack(101, gomock.Any()).
+		DoAndReturn(func(id int, rack metalcloud.ServerEditRack) (*metalcloud.Server, error) {
+			Expect(*rack.ServerRackName).To(Equal("R01"))
+			Expect(*rack.ServerRackPositionLowerUnit).To(Equal("10"))
+			Expect(*rack.ServerRackPositionUpperUnit).To(Equal("11"))
+			return nil, nil
+		}).
+		Times(1)
+
+	client.EXPECT().
+		ServerEditInventory(101, gomock.Any()).
+		DoAndReturn(func(id int, inventory metalcloud.ServerEditInventory) (*metalcloud.Server, error) {
+			Expect(*inventory.ServerInventoryId).To(Equal("INV1"))
+			return nil, nil
+		}).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"inventory_file": inventory,
+		"autoconfirm":    true,
+	})
+
+	_, err := serverRegisterCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("3 of 4 servers could not be registered"))
+
+	content, err := os.ReadFile(inventory + ".results.csv")
+	Expect(err).To(BeNil())
+
+	results, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	Expect(err).To(BeNil())
+	Expect(results).To(HaveLen(5))
+	Expect(results[1]).To(Equal([]string{"1", "10.0.0.1", "101", "registered", ""}))
+	Expect(results[2][3]).To(Equal("failed"))
+	Expect(results[2][4]).To(ContainSubstring("reference"))
+	Expect(results[3][4]).To(Equal("bmc unreachable"))
+	Expect(results[4][4]).To(ContainSubstring("invalid U range"))
+}
+
+func TestServerRegisterFromFilePostEditFailure(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	os.Setenv("TEST_BMC_PASS", "secret1")
+	defer os.Unsetenv("TEST_BMC_PASS")
+
+	inventory := filepath.Join(t.TempDir(), "inventory.csv")
+	Expect(os.WriteFile(inventory, []byte(
+		"datacenter,vendor,mgmt_address,user,password,rack,u_range,inventory_id\n"+
+			"dc1,dell,10.0.0.1,root,env:TEST_BMC_PASS,R01,10,\n",
+	), 0600)).To(BeNil())
+
+	client.EXPECT().
+		ServerCreateAndRegister(gomock.Any()).
+		Return(102, nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerEditRack(102, gomock.Any()).
+		Return(nil, fmt.Errorf("rack not found")).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"inventory_file": inventory,
+		"autoconfirm":    true,
+	})
+
+	//the server exists so the row must not be reported as a failed registration
+	_, err := serverRegisterCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("Do not register them again"))
+
+	content, err := os.ReadFile(inventory + ".results.csv")
+	Expect(err).To(BeNil())
+
+	results, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	Expect(err).To(BeNil())
+	Expect(results[1]).To(Equal([]string{"1", "10.0.0.1", "102", "registered, post-edit failed", "setting the rack info failed: rack not found"}))
+}
+
+func TestReadServerInventoryYAML(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir := t.TempDir()
+	inventory := filepath.Join(dir, "inventory.yaml")
+	Expect(os.WriteFile(inventory, []byte(`
+- datacenter: dc1
+  vendor: dell
+  mgmt_address: 10.0.0.1
+  user: root
+  password: env:BMC_PASS
+  rack: R01
+  u_range: "10"
+`), 0600)).To(BeNil())
+
+	rows, err := readServerInventory(inventory, "")
+	Expect(err).To(BeNil())
+	Expect(rows).To(HaveLen(1))
+	Expect(rows[0].Row).To(Equal(1))
+	Expect(rows[0].MgmtAddress).To(Equal("10.0.0.1"))
+	Expect(rows[0].URange).To(Equal("10"))
+
+	//missing columns
+	csvFile := filepath.Join(dir, "inventory.csv")
+	Expect(os.WriteFile(csvFile, []byte("datacenter,vendor\ndc1,dell\n"), 0600)).To(BeNil())
+
+	_, err = readServerInventory(csvFile, "")
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerComponentsCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	server := metalcloud.Server{
+		ServerID:                 100,
+		ServerSerialNumber:       "SN100",
+		ServerProcessorName:      "Xeon",
+		ServerProcessorCount:     2,
+		ServerProcessorCoreCount: 16,
+		ServerRAMGbytes:          256,
+		ServerDisks: []metalcloud.ServerDisk{
+			{ServerDiskID: 1, ServerDiskVendor: "Samsung", ServerDiskModel: "PM883", ServerDiskSerial: "D1", ServerDiskSizeGB: 960},
+			{ServerDiskID: 2, ServerDiskVendor: "Intel", ServerDiskModel: "S4510", ServerDiskSerial: "D2", ServerDiskSizeGB: 480},
+		},
+		ServerInterfaces: []metalcloud.ServerInterface{
+			{ServerInterfaceMACAddress: "aa:bb:cc:dd:ee:ff"},
+		},
+	}
+
+	server2 := server
+	server2.ServerID = 101
+	server2.ServerSerialNumber = "SN101"
+	server2.ServerDisks = server.ServerDisks[1:]
+
+	components := []metalcloud.ServerComponent{
+		{ServerComponentID: 2010, ServerComponentName: "BIOS", ServerComponentType: "bios", ServerComponentFirmwareVersion: "2.10.0"},
+	}
+
+	client.EXPECT().
+		ServerGet(100, false).
+		Return(&server, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerGet(101, false).
+		Return(&server2, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerGet(102, false).
+		Return(nil, fmt.Errorf("not found")).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerComponents(gomock.Any(), "").
+		Return(&components, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id_or_uuid": "100",
+		"format":            "json",
+	})
+
+	ret, err := serverComponentsCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(6))
+	Expect(rows[0]["TYPE"]).To(Equal("cpu"))
+	Expect(rows[2]["MODEL"]).To(Equal("Samsung PM883"))
+	Expect(rows[2]["SERIAL"]).To(Equal("D1"))
+	Expect(rows[5]["FIRMWARE"]).To(Equal("2.10.0"))
+
+	//which servers have a disk model
+	client.EXPECT().
+		ServersSearch(gomock.Any()).
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100}, {ServerID: 101}}, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter":          "datacenter_name:dc-1",
+		"component_type":  "disk",
+		"component_model": "s4510",
+		"format":          "csv",
+	})
+
+	ret, err = serverComponentsCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("SN100"))
+	Expect(ret).To(ContainSubstring("SN101"))
+	Expect(ret).NotTo(ContainSubstring("PM883"))
+
+	//failures are reported after the table
+	client.EXPECT().
+		ServersSearch(gomock.Any()).
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100}, {ServerID: 102}}, nil).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	_, err = serverComponentsCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("server #102"))
+	Expect(stdout.String()).To(ContainSubstring("SN100"))
+
+	cmd = MakeEmptyCommand()
+	_, err = serverComponentsCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerRetireCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServersSearch("+server_id:100").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_id:101").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 101, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_id:102").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 102, ServerStatus: "used", InstanceID: []int{200}, InfrastructureID: []int{300}}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_rack_name:rack-01").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100, ServerStatus: "available"}, {ServerID: 101, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SwitchInterfaceSearch(gomock.Any()).
+		Return(&[]metalcloud.SwitchInterfaceSearchResult{{NetworkEquipmentIdentifierString: "leaf-1", NetworkEquipmentInterfaceIdentifierString: "Ethernet1"}}, nil).
+		AnyTimes()
+
+	//allocated servers are refused
+	cmd := MakeCommand(map[string]interface{}{
+		"server_ids":  "100,102",
+		"autoconfirm": true,
+	})
+
+	_, err := serverDecommissionCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("#102"))
+	Expect(err.Error()).To(ContainSubstring("#300"))
+
+	client.EXPECT().
+		ServerDecomission(100, false).
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerDecomission(101, false).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_ids":  "100,101",
+		"concurrency": 2,
+		"autoconfirm": true,
+	})
+
+	ret, err := serverDecommissionCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("2 of 2"))
+
+	//wipe first, then delete
+	gomock.InOrder(
+		client.EXPECT().ServerStatusUpdate(100, "cleaning").Return(nil),
+		client.EXPECT().ServerGet(100, false).Return(&metalcloud.Server{ServerID: 100, ServerStatus: "cleaning"}, nil),
+		client.EXPECT().ServerGet(100, false).Return(&metalcloud.Server{ServerID: 100, ServerStatus: "available"}, nil),
+		client.EXPECT().ServerDelete(100, true).Return(nil),
+	)
+
+	gomock.InOrder(
+		client.EXPECT().ServerStatusUpdate(101, "cleaning").Return(nil),
+		client.EXPECT().ServerGet(101, false).Return(&metalcloud.Server{ServerID: 101, ServerStatus: "available"}, nil),
+		client.EXPECT().ServerDelete(101, true).Return(fmt.Errorf("failed")),
+	)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter":               "server_rack_name:rack-01",
+		"wipe":                 true,
+		"skip_ipmi":            true,
+		"concurrency":          2,
+		"block_timeout":        10,
+		"block_check_interval": 0,
+		"autoconfirm":          true,
+	})
+
+	_, err = serverDeleteCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("1 of 2"))
+	Expect(stdout.String()).To(ContainSubstring("failed"))
+
+	//selection errors
+	cmd = MakeEmptyCommand()
+	_, err = serverDeleteCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_id": 100,
+		"filter":    "server_rack_name:rack-01",
+	})
+	_, err = serverDeleteCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerBatchEditCmds(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	client.EXPECT().
+		ServersSearch("+server_id:100").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_id:101").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 101, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServersSearch("+server_rack_name:rack-01").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100, ServerStatus: "available"}, {ServerID: 101, ServerStatus: "available"}}, nil).
+		AnyTimes()
+
+	//single server
+	client.EXPECT().
+		ServerStatusUpdate(100, "decommissioned").
+		Return(nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id":   100,
+		"status":      "decommissioned",
+		"autoconfirm": true,
+	})
+
+	ret, err := serverStatusSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal(""))
+
+	//ids from a file
+	f := filepath.Join(t.TempDir(), "ids.txt")
+	Expect(os.WriteFile(f, []byte("100\n101\n"), 0600)).To(BeNil())
+
+	inventoryID := "INV-1"
+
+	client.EXPECT().
+		ServerEditInventory(100, metalcloud.ServerEditInventory{ServerInventoryId: &inventoryID}).
+		Return(&metalcloud.Server{}, nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerEditInventory(101, metalcloud.ServerEditInventory{ServerInventoryId: &inventoryID}).
+		Return(&metalcloud.Server{}, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_ids_file": f,
+		"inventory_id":    inventoryID,
+		"concurrency":     2,
+		"autoconfirm":     true,
+	})
+
+	ret, err = serverInventoryInfoSetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("2 of 2"))
+
+	//filter with a partial failure
+	st := metalcloud.ServerType{ServerTypeID: 10, ServerTypeName: "M.8.8.1"}
+
+	client.EXPECT().
+		ServerTypeGetByLabel("m-8-8-1").
+		Return(&st, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerEditProperty(100, "server_type_id", 10).
+		Return(nil).
+		Times(1)
+
+	client.EXPECT().
+		ServerEditProperty(101, "server_type_id", 10).
+		Return(fmt.Errorf("failed")).
+		Times(1)
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter":      "server_rack_name:rack-01",
+		"server_type": "m-8-8-1",
+		"autoconfirm": true,
+	})
+
+	_, err = serverServerTypeSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("1 of 2"))
+	Expect(stdout.String()).To(ContainSubstring("failed"))
+
+	//ids and filter cannot be combined
+	cmd = MakeCommand(map[string]interface{}{
+		"server_ids":  "100,101",
+		"filter":      "server_rack_name:rack-01",
+		"rack_name":   "rack-02",
+		"lower_u":     1,
+		"upper_u":     2,
+		"autoconfirm": true,
+	})
+
+	_, err = serverRackInfoSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//invalid ids
+	Expect(os.WriteFile(f, []byte("100,abc"), 0600)).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"server_ids_file": f,
+		"status":          "available",
+		"autoconfirm":     true,
+	})
+
+	_, err = serverStatusSetCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerRotateIPMICmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	os.Setenv("METALCLOUD_TEST_ROTATION_KEY", "rotation-key")
+	defer os.Unsetenv("METALCLOUD_TEST_ROTATION_KEY")
+
+	client.EXPECT().
+		ServersSearch("+datacenter_name:dc-1").
+		Return(&[]metalcloud.ServerSearchResult{{ServerID: 100, ServerSerialNumber: "S100"}, {ServerID: 101, ServerSerialNumber: "S101"}}, nil).
+		AnyTimes()
+
+	//the stored credentials of each server
+	var mu sync.Mutex
+	passwords := map[int]string{100: "old-100", 101: "old-101"}
+	unreachable := map[int]bool{101: true}
+
+	client.EXPECT().
+		ServerGet(gomock.Any(), true).
+		DoAndReturn(func(serverID int, decryptPasswd bool) (*metalcloud.Server, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return &metalcloud.Server{
+				ServerID:                  serverID,
+				ServerIPMIHost:            fmt.Sprintf("10.0.0.%d", serverID),
+				ServerIPMInternalUsername: "admin",
+				ServerIPMInternalPassword: passwords[serverID],
+			}, nil
+		}).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerEditIPMI(gomock.Any(), gomock.Any(), true).
+		DoAndReturn(func(serverID int, server metalcloud.Server, updateInBMC bool) (*metalcloud.Server, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if unreachable[serverID] {
+				return nil, fmt.Errorf("BMC unreachable")
+			}
+			passwords[serverID] = server.ServerIPMInternalPassword
+			return &server, nil
+		}).
+		AnyTimes()
+
+	dir := t.TempDir()
+	report := filepath.Join(dir, "rotation.enc")
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"filter":          "datacenter_name:dc-1",
+		"password_length": 16,
+		"report_file":     report,
+		"report_key":      "env:METALCLOUD_TEST_ROTATION_KEY",
+		"concurrency":     2,
+		"autoconfirm":     true,
+	})
+
+	_, err := serverRotateIPMICmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("1 of 2"))
+	Expect(err.Error()).To(ContainSubstring("--retry-from"))
+
+	content, err := os.ReadFile(report)
+	Expect(err).To(BeNil())
+	Expect(string(content)).NotTo(ContainSubstring("old-100"))
+
+	entries, err := readIPMIRotationReport(report, "rotation-key")
+	Expect(err).To(BeNil())
+	Expect(entries).To(HaveLen(2))
+	Expect(entries[0].Status).To(Equal(ipmiRotationRotated))
+	Expect(entries[0].OldPassword).To(Equal("old-100"))
+	Expect(entries[0].NewPassword).To(HaveLen(16))
+	Expect(passwords[100]).To(Equal(entries[0].NewPassword))
+	Expect(entries[1].Status).To(Equal(ipmiRotationFailed))
+	Expect(entries[1].Error).To(ContainSubstring("BMC unreachable"))
+	Expect(passwords[101]).To(Equal("old-101"))
+
+	//the report is never overwritten
+	_, err = serverRotateIPMICmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("already exists"))
+
+	//retry the failed server with the same password
+	unreachable[101] = false
+	retryReport := filepath.Join(dir, "rotation-retry.enc")
+
+	cmd = MakeCommand(map[string]interface{}{
+		"retry_from":  report,
+		"report_file": retryReport,
+		"report_key":  "env:METALCLOUD_TEST_ROTATION_KEY",
+		"autoconfirm": true,
+	})
+
+	ret, err := serverRotateIPMICmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("Rotated 1 of 1"))
+	Expect(passwords[101]).To(Equal(entries[1].NewPassword))
+
+	//nothing left to retry
+	cmd = MakeCommand(map[string]interface{}{
+		"retry_from":  retryReport,
+		"report_file": filepath.Join(dir, "rotation-retry-2.enc"),
+		"report_key":  "env:METALCLOUD_TEST_ROTATION_KEY",
+		"autoconfirm": true,
+	})
+
+	_, err = serverRotateIPMICmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	//passwords from a file
+	passwordsFile := filepath.Join(dir, "passwords.csv")
+	Expect(os.WriteFile(passwordsFile, []byte("server_id,password\n100,Pass-100\n"), 0600)).To(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter":         "datacenter_name:dc-1",
+		"passwords_file": passwordsFile,
+		"report_file":    filepath.Join(dir, "rotation-file.enc"),
+		"report_key":     "env:METALCLOUD_TEST_ROTATION_KEY",
+		"autoconfirm":    true,
+	})
+
+	_, err = serverRotateIPMICmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("#101"))
+
+	Expect(os.WriteFile(passwordsFile, []byte("100,Pass-100\n101,Pass-101\n"), 0600)).To(BeNil())
+
+	_, err = serverRotateIPMICmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(passwords[100]).To(Equal("Pass-100"))
+	Expect(passwords[101]).To(Equal("Pass-101"))
+
+	//show the report
+	cmd = MakeCommand(map[string]interface{}{
+		"report_file":    report,
+		"report_key":     "env:METALCLOUD_TEST_ROTATION_KEY",
+		"show_passwords": true,
+		"format":         "json",
+	})
+
+	ret, err = serverRotateIPMIReportCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(2))
+	Expect(rows[0]["OLD_PASSWORD"]).To(Equal("old-100"))
+
+	os.Setenv("METALCLOUD_TEST_ROTATION_KEY", "wrong-key")
+
+	_, err = serverRotateIPMIReportCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestServerProbeCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ipmiPort := startRMCPResponder(t)
+	host, redfishPort := startMockRedfishServer(t, "admin", "pass", `{"RedfishVersion":"1.11.0","Vendor":"HPE","Product":"ProLiant DL380 Gen10"}`)
+
+	client.EXPECT().
+		ServersSearch("+server_status:registering").
+		Return(&[]metalcloud.ServerSearchResult{
+			{ServerID: 100, ServerStatus: "registering", ServerIPMIHost: host},
+			{ServerID: 101, ServerStatus: "registering"},
+		}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerGet(100, true).
+		Return(&metalcloud.Server{ServerID: 100, ServerIPMInternalUsername: "admin", ServerIPMInternalPassword: "pass"}, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"filter":       "server_status:registering",
+		"redfish":      true,
+		"ipmi_port":    ipmiPort,
+		"redfish_port": redfishPort,
+		"timeout":      2,
+		"concurrency":  2,
+		"format":       "json",
+	})
+
+	ret, err := serverProbeCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(2))
+	Expect(rows[0]["IPMI"]).To(HaveSuffix(" ms"))
+	Expect(rows[0]["HTTPS"]).To(HaveSuffix(" ms"))
+	Expect(rows[0]["TLS_ISSUER"]).To(Equal("self-signed"))
+	Expect(rows[0]["TLS_EXPIRES"]).NotTo(BeEmpty())
+	Expect(rows[0]["VENDOR"]).To(Equal("HPE ProLiant DL380 Gen10"))
+	Expect(rows[0]["REDFISH"]).To(Equal("1.11.0"))
+	Expect(rows[1]["IPMI"]).To(Equal("no IPMI host"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"filter":  "server_status:registering",
+		"timeout": 0,
+	})
+
+	_, err = serverProbeCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
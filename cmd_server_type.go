@@ -0,0 +1,392 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"github.com/metalsoft-io/tableformatter"
+)
+
+var serverTypeCmds = []Command{
+
+	{
+		Description:  "Lists server types.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "list",
+		AltPredicate: "ls",
+		FlagSet:      flag.NewFlagSet("list server types", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"datacenter_name": c.FlagSet.String("datacenter", _nilDefaultStr, "Only list the server types of servers found in this datacenter."),
+				"show_all":        c.FlagSet.Bool("show-all", false, green("(Flag)")+" If set server types without servers are also returned."),
+				"format":          c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverTypeListCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Get server type details.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get server type", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_type": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Server type's id or label."),
+				"format":      c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverTypeGetCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Lists the server types a server qualifies for.",
+		Subject:      "server-type",
+		AltSubject:   "st",
+		Predicate:    "match",
+		AltPredicate: "matches",
+		FlagSet:      flag.NewFlagSet("match server types", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"server_id_or_uuid": c.FlagSet.String("server", _nilDefaultStr, red("(Required)")+" Server's ID or UUID."),
+				"format":            c.FlagSet.String("format", _nilDefaultStr, "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: serverTypeMatchCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+}
+
+func serverTypeListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	serverTypes, err := client.ServerTypes(false)
+	if err != nil {
+		return "", err
+	}
+
+	datacenterName, datacenterOk := getStringParamOk(c.Arguments["datacenter_name"])
+
+	filter := "*"
+	inDatacenter := map[int]bool{}
+
+	if datacenterOk {
+		ids, err := client.ServerTypeDatacenter(datacenterName)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range *ids {
+			inDatacenter[id] = true
+		}
+		filter = fmt.Sprintf("+datacenter_name:%s", datacenterName)
+	}
+
+	servers, err := client.ServersSearch(filter)
+	if err != nil {
+		return "", err
+	}
+
+	//servers are counted by status for each server type
+	counts := map[int]map[string]int{}
+	for _, s := range *servers {
+		if counts[s.ServerTypeID] == nil {
+			counts[s.ServerTypeID] = map[string]int{}
+		}
+		counts[s.ServerTypeID][s.ServerStatus]++
+		counts[s.ServerTypeID]["total"]++
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "NAME",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "CLASS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "PROCESSORS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RAM_GB",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "DISKS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "AVAILABLE",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "USED",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "TOTAL",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+	}
+
+	showAll := getBoolParam(c.Arguments["show_all"])
+
+	data := [][]interface{}{}
+	for _, st := range *serverTypes {
+
+		if datacenterOk && !inDatacenter[st.ServerTypeID] {
+			continue
+		}
+
+		count := counts[st.ServerTypeID]
+		if count["total"] == 0 && !showAll {
+			continue
+		}
+
+		data = append(data, []interface{}{
+			st.ServerTypeID,
+			st.ServerTypeLabel,
+			st.ServerTypeDisplayName,
+			st.ServerClass,
+			getServerTypeProcessorsString(st),
+			st.ServerRAMGbytes,
+			getServerTypeDisksString(st),
+			count["available"],
+			count["used"] + count["used_registering"],
+			count["total"],
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := ""
+	if datacenterOk {
+		subtitle = fmt.Sprintf("Datacenter %s", datacenterName)
+	}
+
+	return table.RenderTable("Server types", subtitle, getStringParam(c.Arguments["format"]))
+}
+
+func serverTypeGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	st, err := getServerTypeFromCommand("id", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "NAME",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "CLASS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "PROCESSOR",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "PROCESSORS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RAM_GB",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "DISKS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "NETWORK_MBPS",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "OOB_PROVISIONING",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "EXPERIMENTAL",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "SERVERS",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 5,
+		},
+	}
+
+	data := [][]interface{}{
+		{
+			st.ServerTypeID,
+			st.ServerTypeLabel,
+			st.ServerTypeDisplayName,
+			st.ServerClass,
+			st.ServerProcessorName,
+			getServerTypeProcessorsString(*st),
+			st.ServerRAMGbytes,
+			getServerTypeDisksString(*st),
+			st.ServerNetworkTotalCapacityMBps,
+			st.ServerTypeSupportsOOBProvisioning,
+			st.ServerTypeIsExperimental,
+			st.ServerCount,
+		},
+	}
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	return table.RenderTransposedTable("Server type", "", getStringParam(c.Arguments["format"]))
+}
+
+func serverTypeMatchCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	server, err := getServerFromCommand("server", c, client, false)
+	if err != nil {
+		return "", err
+	}
+
+	hardwareConfiguration := metalcloud.HardwareConfiguration{
+		InstanceArrayRAMGbytes:          server.ServerRAMGbytes,
+		InstanceArrayProcessorCount:     server.ServerProcessorCount,
+		InstanceArrayProcessorCoreCount: server.ServerProcessorCoreCount,
+		InstanceArrayProcessorCoreMHZ:   server.ServerProcessorCoreMhz,
+		InstanceArrayDiskCount:          server.ServerDiskCount,
+		InstanceArrayDiskSizeMBytes:     server.ServerDiskSizeMbytes,
+	}
+
+	if server.ServerDiskType != "" {
+		hardwareConfiguration.InstanceArrayDiskTypes = []string{server.ServerDiskType}
+	}
+
+	serverTypes, err := client.ServerTypesMatchHardwareConfiguration(server.DatacenterName, hardwareConfiguration)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "NAME",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "PROCESSORS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+		{
+			FieldName: "RAM_GB",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "DISKS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 15,
+		},
+		{
+			FieldName: "CURRENT",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 5,
+		},
+	}
+
+	data := [][]interface{}{}
+	for _, st := range *serverTypes {
+		data = append(data, []interface{}{
+			st.ServerTypeID,
+			st.ServerTypeLabel,
+			st.ServerTypeDisplayName,
+			getServerTypeProcessorsString(st),
+			st.ServerRAMGbytes,
+			getServerTypeDisksString(st),
+			st.ServerTypeID == server.ServerTypeID,
+		})
+	}
+
+	tableformatter.TableSorter(schema).OrderBy(schema[0].FieldName).Sort(data)
+
+	table := tableformatter.Table{
+		Data:   data,
+		Schema: schema,
+	}
+
+	subtitle := fmt.Sprintf("Server types matching server #%d (%s) in datacenter %s", server.ServerID, server.ServerSerialNumber, server.DatacenterName)
+
+	return table.RenderTable("Server types", subtitle, getStringParam(c.Arguments["format"]))
+}
+
+// getServerTypeProcessorsString describes the processors of a server type, such as 2x16 cores @ 2600 MHz
+func getServerTypeProcessorsString(st metalcloud.ServerType) string {
+	return fmt.Sprintf("%dx%d cores @ %d MHz", st.ServerProcessorCount, st.ServerProcessorCoreCount, st.ServerProcessorCoreMHz)
+}
+
+// getServerTypeDisksString describes the local disks of a server type, such as 2x960 GB SSD
+func getServerTypeDisksString(st metalcloud.ServerType) string {
+	if st.ServerDiskCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d GB %s", st.ServerDiskCount, st.ServerDiskSizeMBytes/1024, st.ServerDiskType)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+func getTestServerTypes() map[int]metalcloud.ServerType {
+	return map[int]metalcloud.ServerType{
+		10: {
+			ServerTypeID:             10,
+			ServerTypeName:           "M.8.8.1",
+			ServerTypeLabel:          "m-8-8-1",
+			ServerProcessorCount:     1,
+			ServerProcessorCoreCount: 8,
+			ServerRAMGbytes:          8,
+			ServerDiskCount:          1,
+			ServerDiskSizeMBytes:     480 * 1024,
+			ServerDiskType:           "SSD",
+		},
+		11: {
+			ServerTypeID:             11,
+			ServerTypeName:           "M.16.16.1",
+			ServerTypeLabel:          "m-16-16-1",
+			ServerProcessorCount:     1,
+			ServerProcessorCoreCount: 16,
+			ServerRAMGbytes:          16,
+		},
+		12: {
+			ServerTypeID:    12,
+			ServerTypeName:  "M.unused",
+			ServerTypeLabel: "m-unused",
+		},
+	}
+}
+
+func TestServerTypeListCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	serverTypes := getTestServerTypes()
+
+	client.EXPECT().
+		ServerTypes(false).
+		Return(&serverTypes, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypeDatacenter("dc-1").
+		Return(&[]int{10, 12}, nil).
+		AnyTimes()
+
+	servers := []metalcloud.ServerSearchResult{
+		{ServerID: 1, ServerTypeID: 10, ServerTypeName: "M.8.8.1", ServerStatus: "available"},
+		{ServerID: 2, ServerTypeID: 10, ServerTypeName: "M.8.8.1", ServerStatus: "available"},
+		{ServerID: 3, ServerTypeID: 10, ServerTypeName: "M.8.8.1", ServerStatus: "used"},
+		{ServerID: 4, ServerTypeID: 11, ServerTypeName: "M.16.16.1", ServerStatus: "cleaning"},
+		//the counts do not depend on the name, which is not unique
+		{ServerID: 5, ServerTypeID: 11, ServerTypeName: "M.8.8.1", ServerStatus: "used"},
+	}
+
+	client.EXPECT().
+		ServersSearch(gomock.Any()).
+		Return(&servers, nil).
+		AnyTimes()
+
+	expectedFirstRow := map[string]interface{}{
+		"ID":        10,
+		"LABEL":     "m-8-8-1",
+		"AVAILABLE": 2,
+		"USED":      1,
+		"TOTAL":     3,
+	}
+
+	testListCommand(serverTypeListCmd, nil, client, expectedFirstRow, t)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"format":          "json",
+	})
+
+	ret, err := serverTypeListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(1))
+	Expect(rows[0]["DISKS"]).To(Equal("1x480 GB SSD"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"datacenter_name": "dc-1",
+		"show_all":        true,
+		"format":          "json",
+	})
+
+	ret, err = serverTypeListCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(2))
+}
+
+func TestServerTypeGetCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	st := getTestServerTypes()[10]
+
+	client.EXPECT().
+		ServerTypeGet(10).
+		Return(&st, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		ServerTypeGetByLabel("m-8-8-1").
+		Return(&st, nil).
+		AnyTimes()
+
+	cases := []CommandTestCase{
+		{
+			name: "by id",
+			cmd:  MakeCommand(map[string]interface{}{"server_type": 10}),
+			good: true,
+		},
+		{
+			name: "by label",
+			cmd:  MakeCommand(map[string]interface{}{"server_type": "m-8-8-1"}),
+			good: true,
+		},
+		{
+			name: "no id",
+			cmd:  MakeCommand(map[string]interface{}{}),
+			good: false,
+		},
+	}
+
+	testGetCommand(serverTypeGetCmd, cases, client, map[string]interface{}{"ID": 10}, t)
+}
+
+func TestServerTypeMatchCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	server := metalcloud.Server{
+		ServerID:                 100,
+		ServerTypeID:             10,
+		DatacenterName:           "dc-1",
+		ServerRAMGbytes:          16,
+		ServerProcessorCount:     1,
+		ServerProcessorCoreCount: 16,
+		ServerDiskCount:          1,
+		ServerDiskSizeMbytes:     480 * 1024,
+		ServerDiskType:           "SSD",
+	}
+
+	client.EXPECT().
+		ServerGet(100, false).
+		Return(&server, nil).
+		AnyTimes()
+
+	serverTypes := getTestServerTypes()
+	delete(serverTypes, 12)
+
+	client.EXPECT().
+		ServerTypesMatchHardwareConfiguration("dc-1", metalcloud.HardwareConfiguration{
+			InstanceArrayRAMGbytes:          16,
+			InstanceArrayProcessorCount:     1,
+			InstanceArrayProcessorCoreCount: 16,
+			InstanceArrayDiskCount:          1,
+			InstanceArrayDiskSizeMBytes:     480 * 1024,
+			InstanceArrayDiskTypes:          []string{"SSD"},
+		}).
+		Return(&serverTypes, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"server_id_or_uuid": 100,
+		"format":            "json",
+	})
+
+	ret, err := serverTypeMatchCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var rows []map[string]interface{}
+	Expect(json.Unmarshal([]byte(ret), &rows)).To(BeNil())
+	Expect(rows).To(HaveLen(2))
+	Expect(rows[0]["ID"]).To(Equal(10.0))
+	Expect(rows[0]["CURRENT"]).To(Equal(true))
+	Expect(rows[1]["CURRENT"]).To(Equal(false))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"strings"
 
 	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
@@ -26,6 +27,113 @@ var sharedDriveCmds = []Command{
 		ExecuteFunc: sharedDriveListCmd,
 		Endpoint:    DeveloperEndpoint,
 	},
+	{
+		Description:  "Creates a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "create",
+		AltPredicate: "new",
+		FlagSet:      flag.NewFlagSet("create shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"infrastructure_id_or_label": c.FlagSet.String("infra", _nilDefaultStr, red("(Required)")+" Infrastructure's id or label. Note that the 'label' this be ambiguous in certain situations."),
+				"shared_drive_label":         c.FlagSet.String("label", _nilDefaultStr, red("(Required)")+" The label of the shared drive"),
+				"shared_drive_size_mbytes":   c.FlagSet.Int("size", _nilDefaultInt, red("(Required)")+" Shared drive's size in MBytes"),
+				"shared_drive_storage_type":  c.FlagSet.String("type", _nilDefaultStr, "Possible values: iscsi_ssd, iscsi_hdd"),
+				"shared_drive_has_gfs":       c.FlagSet.Bool("gfs", false, green("(Flag)")+" If set, the shared drive will be formatted with a GFS2 clustered file system"),
+				"shared_drive_io_limit":      c.FlagSet.String("io-limit", _nilDefaultStr, "Shared drive's IO limit policy"),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, "Comma separated list of ids or labels of instance arrays to attach the shared drive to"),
+				"return_id":                  c.FlagSet.Bool("return-id", false, green("(Flag)")+" If set will print the ID of the created shared drive. Useful for automating tasks."),
+			}
+		},
+		ExecuteFunc: sharedDriveCreateCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Gets a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "get",
+		AltPredicate: "show",
+		FlagSet:      flag.NewFlagSet("get shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Shared drive's ID or label. Note that using the label can be ambiguous and is slower."),
+				"show_credentials":         c.FlagSet.Bool("show-credentials", false, green("(Flag)")+" If set returns the shared drive's iscsi credentials"),
+				"format":                   c.FlagSet.String("format", "", "The output format. Supported values are 'json','csv','yaml'. The default format is human readable."),
+			}
+		},
+		ExecuteFunc: sharedDriveGetCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Edits a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "edit",
+		AltPredicate: "alter",
+		FlagSet:      flag.NewFlagSet("edit shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":  c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Shared drive's ID or label. Note that using the label can be ambiguous and is slower."),
+				"shared_drive_label":        c.FlagSet.String("label", _nilDefaultStr, "The new label of the shared drive"),
+				"shared_drive_size_mbytes":  c.FlagSet.Int("size", _nilDefaultInt, "Shared drive's new size in MBytes"),
+				"shared_drive_storage_type": c.FlagSet.String("type", _nilDefaultStr, "Possible values: iscsi_ssd, iscsi_hdd"),
+				"shared_drive_io_limit":     c.FlagSet.String("io-limit", _nilDefaultStr, "Shared drive's IO limit policy"),
+			}
+		},
+		ExecuteFunc: sharedDriveEditCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Delete a shared drive.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "delete",
+		AltPredicate: "rm",
+		FlagSet:      flag.NewFlagSet("delete shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label": c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Shared drive's ID or label. Note that using the label can be ambiguous and is slower."),
+				"autoconfirm":              c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: sharedDriveDeleteCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Attach a shared drive to an instance array.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "attach",
+		AltPredicate: "attach",
+		FlagSet:      flag.NewFlagSet("attach shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":   c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Shared drive's ID or label. Note that using the label can be ambiguous and is slower."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+			}
+		},
+		ExecuteFunc: sharedDriveAttachCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
+	{
+		Description:  "Detach a shared drive from an instance array.",
+		Subject:      "shared-drive",
+		AltSubject:   "shared-drives",
+		Predicate:    "detach",
+		AltPredicate: "detach",
+		FlagSet:      flag.NewFlagSet("detach shared drive", flag.ExitOnError),
+		InitFunc: func(c *Command) {
+			c.Arguments = map[string]interface{}{
+				"shared_drive_id_or_label":   c.FlagSet.String("id", _nilDefaultStr, red("(Required)")+" Shared drive's ID or label. Note that using the label can be ambiguous and is slower."),
+				"instance_array_id_or_label": c.FlagSet.String("ia", _nilDefaultStr, red("(Required)")+" InstanceArray's id or label. Note that the label can be ambigous."),
+				"autoconfirm":                c.FlagSet.Bool("autoconfirm", false, green("(Flag)")+" If set it will assume action is confirmed"),
+			}
+		},
+		ExecuteFunc: sharedDriveDetachCmd,
+		Endpoint:    DeveloperEndpoint,
+	},
 }
 
 func sharedDriveListCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
@@ -97,32 +205,15 @@ func sharedDriveListCmd(c *Command, client metalcloud.MetalCloudClient) (string,
 
 	data := [][]interface{}{}
 	for _, sd := range *sdList {
-		status := sd.SharedDriveServiceStatus
-
-		if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveServiceStatus == "edit" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
-			status = "edited"
-		}
-
-		if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveServiceStatus == "delete" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
-			status = "marked for delete"
-		}
-
-		attachedInstanceArrays := []string{}
-
-		for _, instanceArrayID := range sd.SharedDriveAttachedInstanceArrays {
-			ia, err := client.InstanceArrayGet(instanceArrayID)
-			if err != nil {
-				return "", err
-			}
-			attachedInstanceArrays = append(attachedInstanceArrays, fmt.Sprintf("%s (#%d)", ia.InstanceArrayLabel, ia.InstanceArrayID))
+		attachedInstanceArraysList, err := getSharedDriveAttachedInstanceArrays(sd, client)
+		if err != nil {
+			return "", err
 		}
 
-		attachedInstanceArraysList := strings.Join(attachedInstanceArrays, ",")
-
 		data = append(data, []interface{}{
 			sd.SharedDriveID,
 			sd.SharedDriveOperation.SharedDriveLabel,
-			status,
+			getSharedDriveStatus(sd),
 			sd.SharedDriveOperation.SharedDriveSizeMbytes,
 			sd.SharedDriveOperation.SharedDriveStorageType,
 			attachedInstanceArraysList,
@@ -139,3 +230,336 @@ func sharedDriveListCmd(c *Command, client metalcloud.MetalCloudClient) (string,
 
 	return table.RenderTable("Shared drives", "", getStringParam(c.Arguments["format"]))
 }
+
+func getSharedDriveStatus(sd metalcloud.SharedDrive) string {
+	status := sd.SharedDriveServiceStatus
+
+	if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveServiceStatus == "edit" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
+		status = "edited"
+	}
+
+	if sd.SharedDriveServiceStatus != "ordered" && sd.SharedDriveOperation.SharedDriveServiceStatus == "delete" && sd.SharedDriveOperation.SharedDriveDeployStatus == "not_started" {
+		status = "marked for delete"
+	}
+
+	return status
+}
+
+func getSharedDriveAttachedInstanceArrays(sd metalcloud.SharedDrive, client metalcloud.MetalCloudClient) (string, error) {
+	attachedInstanceArrays := []string{}
+
+	for _, instanceArrayID := range sd.SharedDriveAttachedInstanceArrays {
+		ia, err := client.InstanceArrayGet(instanceArrayID)
+		if err != nil {
+			return "", err
+		}
+		attachedInstanceArrays = append(attachedInstanceArrays, fmt.Sprintf("%s (#%d)", ia.InstanceArrayLabel, ia.InstanceArrayID))
+	}
+
+	return strings.Join(attachedInstanceArrays, ","), nil
+}
+
+func sharedDriveCreateCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	infra, err := getInfrastructureFromCommand("infra", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	label, ok := getStringParamOk(c.Arguments["shared_drive_label"])
+	if !ok {
+		return "", fmt.Errorf("-label is required")
+	}
+
+	size, ok := getIntParamOk(c.Arguments["shared_drive_size_mbytes"])
+	if !ok {
+		return "", fmt.Errorf("-size is required")
+	}
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveLabel:         label,
+		SharedDriveSizeMbytes:    size,
+		SharedDriveStorageType:   getStringParam(c.Arguments["shared_drive_storage_type"]),
+		SharedDriveHasGFS:        getBoolParam(c.Arguments["shared_drive_has_gfs"]),
+		SharedDriveIOLimitPolicy: getStringParam(c.Arguments["shared_drive_io_limit"]),
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["instance_array_id_or_label"]); ok {
+		for _, iaIDOrLabel := range strings.Split(v, ",") {
+			iaID, err := getIDOrDo(strings.TrimSpace(iaIDOrLabel), func(label string) (int, error) {
+				ia, err := client.InstanceArrayGetByLabel(label)
+				if err != nil {
+					return 0, err
+				}
+				return ia.InstanceArrayID, nil
+			})
+			if err != nil {
+				return "", err
+			}
+			sd.SharedDriveAttachedInstanceArrays = append(sd.SharedDriveAttachedInstanceArrays, iaID)
+		}
+	}
+
+	retSD, err := client.SharedDriveCreate(infra.InfrastructureID, sd)
+	if err != nil {
+		return "", err
+	}
+
+	if getBoolParam(c.Arguments["return_id"]) {
+		return fmt.Sprintf("%d", retSD.SharedDriveID), nil
+	}
+
+	return "", nil
+}
+
+func sharedDriveGetCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	attachedInstanceArraysList, err := getSharedDriveAttachedInstanceArrays(*retSD, client)
+	if err != nil {
+		return "", err
+	}
+
+	schema := []tableformatter.SchemaField{
+		{
+			FieldName: "ID",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 6,
+		},
+		{
+			FieldName: "LABEL",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 30,
+		},
+		{
+			FieldName: "STATUS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "SIZE (MB)",
+			FieldType: tableformatter.TypeInt,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "TYPE",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "GFS",
+			FieldType: tableformatter.TypeBool,
+			FieldSize: 5,
+		},
+		{
+			FieldName: "ATTACHED TO",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 40,
+		},
+		{
+			FieldName: "IO LIMIT",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 10,
+		},
+		{
+			FieldName: "WWN",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 20,
+		},
+	}
+
+	dataRow := []interface{}{
+		retSD.SharedDriveID,
+		retSD.SharedDriveOperation.SharedDriveLabel,
+		getSharedDriveStatus(*retSD),
+		retSD.SharedDriveOperation.SharedDriveSizeMbytes,
+		retSD.SharedDriveOperation.SharedDriveStorageType,
+		retSD.SharedDriveOperation.SharedDriveHasGFS,
+		attachedInstanceArraysList,
+		retSD.SharedDriveIOLimitPolicy,
+		retSD.SharedDriveWWN,
+	}
+
+	if getBoolParam(c.Arguments["show_credentials"]) {
+		iscsi := retSD.SharedDriveCredentials.ISCSI
+
+		schema = append(schema, tableformatter.SchemaField{
+			FieldName: "CREDENTIALS",
+			FieldType: tableformatter.TypeString,
+			FieldSize: 5,
+		})
+
+		dataRow = append(dataRow, fmt.Sprintf("Target: %s Port:%d IQN:%s LUN ID:%d",
+			iscsi.StorageIPAddress,
+			iscsi.StoragePort,
+			iscsi.TargetIQN,
+			iscsi.LunID))
+	}
+
+	table := tableformatter.Table{
+		Data:   [][]interface{}{dataRow},
+		Schema: schema,
+	}
+
+	return table.RenderTable("Shared drive", "", getStringParam(c.Arguments["format"]))
+}
+
+func sharedDriveEditCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	sdo := retSD.SharedDriveOperation
+
+	updateIfStringParamSet(c.Arguments["shared_drive_label"], &sdo.SharedDriveLabel)
+	updateIfIntParamSet(c.Arguments["shared_drive_size_mbytes"], &sdo.SharedDriveSizeMbytes)
+	updateIfStringParamSet(c.Arguments["shared_drive_storage_type"], &sdo.SharedDriveStorageType)
+	updateIfStringParamSet(c.Arguments["shared_drive_io_limit"], &sdo.SharedDriveIOLimitPolicy)
+
+	_, err = client.SharedDriveEdit(retSD.SharedDriveID, sdo)
+
+	return "", err
+}
+
+func sharedDriveDeleteCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retInfra, err := client.InfrastructureGet(retSD.InfrastructureID)
+	if err != nil {
+		return "", err
+	}
+
+	attachedInstanceArraysList, err := getSharedDriveAttachedInstanceArrays(*retSD, client)
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		var confirmationMessage string
+
+		if attachedInstanceArraysList != "" {
+			confirmationMessage = fmt.Sprintf("Deleting shared drive %s (%d), attached to instance arrays %s - from infrastructure %s (%d).  Are you sure? Type \"yes\" to continue:",
+				retSD.SharedDriveLabel, retSD.SharedDriveID,
+				attachedInstanceArraysList,
+				retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+		} else {
+			confirmationMessage = fmt.Sprintf("Deleting shared drive %s (%d), unattached - from infrastructure %s (%d).  Are you sure? Type \"yes\" to continue:",
+				retSD.SharedDriveLabel, retSD.SharedDriveID,
+				retInfra.InfrastructureLabel, retInfra.InfrastructureID)
+		}
+
+		//this is simply so that we don't output a text on the command line
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		return "", client.SharedDriveDelete(retSD.SharedDriveID)
+	}
+
+	return "", fmt.Errorf("Operation not confirmed. Aborting")
+}
+
+func sharedDriveAttachCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	for _, iaID := range retSD.SharedDriveAttachedInstanceArrays {
+		if iaID == retIA.InstanceArrayID {
+			return "", fmt.Errorf("shared drive %d is already attached to instance array %d", retSD.SharedDriveID, retIA.InstanceArrayID)
+		}
+	}
+
+	_, err = client.SharedDriveAttachInstanceArray(retSD.SharedDriveID, retIA.InstanceArrayID)
+
+	return "", err
+}
+
+func sharedDriveDetachCmd(c *Command, client metalcloud.MetalCloudClient) (string, error) {
+
+	retSD, err := getSharedDriveFromCommand(c, client)
+	if err != nil {
+		return "", err
+	}
+
+	retIA, err := getInstanceArrayFromCommand("ia", c, client)
+	if err != nil {
+		return "", err
+	}
+
+	attached := false
+	for _, iaID := range retSD.SharedDriveAttachedInstanceArrays {
+		if iaID == retIA.InstanceArrayID {
+			attached = true
+		}
+	}
+
+	if !attached {
+		return "", fmt.Errorf("shared drive %d is not attached to instance array %d", retSD.SharedDriveID, retIA.InstanceArrayID)
+	}
+
+	confirm, err := confirmCommand(c, func() string {
+
+		confirmationMessage := fmt.Sprintf("Detaching shared drive %s (%d) from instance array %s (%d).  Are you sure? Type \"yes\" to continue:",
+			retSD.SharedDriveLabel, retSD.SharedDriveID,
+			retIA.InstanceArrayLabel, retIA.InstanceArrayID)
+
+		//this is simply so that we don't output a text on the command line
+		if strings.HasSuffix(os.Args[0], ".test") {
+			confirmationMessage = ""
+		}
+
+		return confirmationMessage
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		_, err = client.SharedDriveDetachInstanceArray(retSD.SharedDriveID, retIA.InstanceArrayID)
+		return "", err
+	}
+
+	return "", fmt.Errorf("Operation not confirmed. Aborting")
+}
+
+func getSharedDriveFromCommand(c *Command, client metalcloud.MetalCloudClient) (*metalcloud.SharedDrive, error) {
+
+	m, err := getParam(c, "shared_drive_id_or_label", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	id, label, isID := idOrLabel(m)
+
+	if isID {
+		return client.SharedDriveGet(id)
+	}
+
+	return client.SharedDriveGetByLabel(label)
+}
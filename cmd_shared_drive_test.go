@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+)
+
+func TestSharedDriveCreateCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	infra := metalcloud.Infrastructure{
+		InfrastructureID:    10002,
+		InfrastructureLabel: "testinfra",
+	}
+
+	client.EXPECT().
+		InfrastructureGet(infra.InfrastructureID).
+		Return(&infra, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGetByLabel("ia2").
+		Return(&metalcloud.InstanceArray{InstanceArrayID: 101}, nil).
+		AnyTimes()
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveLabel:                  "gfs-01",
+		SharedDriveSizeMbytes:             2048,
+		SharedDriveStorageType:            "iscsi_ssd",
+		SharedDriveHasGFS:                 true,
+		SharedDriveAttachedInstanceArrays: []int{100, 101},
+	}
+
+	retSD := sd
+	retSD.SharedDriveID = 500
+
+	client.EXPECT().
+		SharedDriveCreate(infra.InfrastructureID, sd).
+		Return(&retSD, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"shared_drive_label":         "gfs-01",
+		"shared_drive_size_mbytes":   2048,
+		"shared_drive_storage_type":  "iscsi_ssd",
+		"shared_drive_has_gfs":       true,
+		"instance_array_id_or_label": "100,ia2",
+		"return_id":                  true,
+	})
+
+	ret, err := sharedDriveCreateCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(Equal("500"))
+
+	//size is required
+	cmd = MakeCommand(map[string]interface{}{
+		"infrastructure_id_or_label": "10002",
+		"shared_drive_label":         "gfs-01",
+	})
+
+	_, err = sharedDriveCreateCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestSharedDriveGetEditDeleteCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveID:                     500,
+		SharedDriveLabel:                  "gfs-01",
+		InfrastructureID:                  10002,
+		SharedDriveServiceStatus:          "active",
+		SharedDriveAttachedInstanceArrays: []int{100},
+		SharedDriveOperation: metalcloud.SharedDriveOperation{
+			SharedDriveID:          500,
+			SharedDriveLabel:       "gfs-01",
+			SharedDriveSizeMbytes:  2048,
+			SharedDriveStorageType: "iscsi_ssd",
+		},
+	}
+
+	client.EXPECT().
+		SharedDriveGetByLabel("gfs-01").
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDriveGet(500).
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&metalcloud.InstanceArray{InstanceArrayID: 100, InstanceArrayLabel: "ia1"}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InfrastructureGet(10002).
+		Return(&metalcloud.Infrastructure{InfrastructureID: 10002}, nil).
+		AnyTimes()
+
+	cmd := MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": "gfs-01",
+		"format":                   "json",
+	})
+
+	ret, err := sharedDriveGetCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	var m []interface{}
+	err = json.Unmarshal([]byte(ret), &m)
+	Expect(err).To(BeNil())
+
+	r := m[0].(map[string]interface{})
+	Expect(int(r["ID"].(float64))).To(Equal(500))
+	Expect(r["ATTACHED TO"].(string)).To(Equal("ia1 (#100)"))
+
+	sdo := sd.SharedDriveOperation
+	sdo.SharedDriveSizeMbytes = 4096
+
+	client.EXPECT().
+		SharedDriveEdit(500, sdo).
+		Return(&sd, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": "500",
+		"shared_drive_size_mbytes": 4096,
+	})
+
+	_, err = sharedDriveEditCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	client.EXPECT().
+		SharedDriveDelete(500).
+		Return(nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label": "500",
+		"autoconfirm":              true,
+	})
+
+	_, err = sharedDriveDeleteCmd(&cmd, client)
+	Expect(err).To(BeNil())
+}
+
+func TestSharedDriveAttachDetachCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	sd := metalcloud.SharedDrive{
+		SharedDriveID:                     500,
+		SharedDriveAttachedInstanceArrays: []int{100},
+	}
+
+	client.EXPECT().
+		SharedDriveGet(500).
+		Return(&sd, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&metalcloud.InstanceArray{InstanceArrayID: 100}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		InstanceArrayGet(101).
+		Return(&metalcloud.InstanceArray{InstanceArrayID: 101}, nil).
+		AnyTimes()
+
+	client.EXPECT().
+		SharedDriveAttachInstanceArray(500, 101).
+		Return(&sd, nil).
+		Times(1)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label":   "500",
+		"instance_array_id_or_label": "101",
+	})
+
+	_, err := sharedDriveAttachCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//already attached
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label":   "500",
+		"instance_array_id_or_label": "100",
+	})
+
+	_, err = sharedDriveAttachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	client.EXPECT().
+		SharedDriveDetachInstanceArray(500, 100).
+		Return(&sd, nil).
+		Times(1)
+
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label":   "500",
+		"instance_array_id_or_label": "100",
+		"autoconfirm":                true,
+	})
+
+	_, err = sharedDriveDetachCmd(&cmd, client)
+	Expect(err).To(BeNil())
+
+	//not attached
+	cmd = MakeCommand(map[string]interface{}{
+		"shared_drive_id_or_label":   "500",
+		"instance_array_id_or_label": "101",
+		"autoconfirm":                true,
+	})
+
+	_, err = sharedDriveDetachCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
@@ -0,0 +1,31 @@
+package main
+
+import "sync"
+
+// runConcurrently calls f for every index in [0, count) using at most concurrency goroutines at a time.
+// The returned slice holds the error returned by f for each index.
+func runConcurrently(count int, concurrency int, f func(i int) error) []error {
+
+	errs := make([]error, count)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = f(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return errs
+}
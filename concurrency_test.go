@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	RegisterTestingT(t)
+
+	var mu sync.Mutex
+	running := 0
+	maxRunning := 0
+
+	errs := runConcurrently(10, 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if i%2 == 0 {
+			return fmt.Errorf("error %d", i)
+		}
+		return nil
+	})
+
+	Expect(errs).To(HaveLen(10))
+	Expect(maxRunning).To(BeNumerically("<=", 3))
+	Expect(errs[0]).NotTo(BeNil())
+	Expect(errs[1]).To(BeNil())
+	Expect(errs[8].Error()).To(Equal("error 8"))
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression with the standard 5 fields: minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	//when both day fields are restricted a time matches if either of them matches, as in cron
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+var cronScheduleAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// parseCronSchedule parses expressions such as "0 2 * * *", "*/15 * * * 1-5" or "@daily"
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+
+	if v, ok := cronScheduleAliases[strings.TrimSpace(expr)]; ok {
+		expr = v
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	limits := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	names := []string{"minute", "hour", "day of month", "month", "day of week"}
+
+	sets := []map[int]bool{}
+	for i, field := range fields {
+		set, err := parseCronField(field, limits[i][0], limits[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in schedule %q: %v", names[i], expr, err)
+		}
+		sets = append(sets, set)
+	}
+
+	//both 0 and 7 are sunday
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		minutes:               sets[0],
+		hours:                 sets[1],
+		daysOfMonth:           sets[2],
+		months:                sets[3],
+		daysOfWeek:            sets[4],
+		daysOfMonthRestricted: fields[2] != "*",
+		daysOfWeekRestricted:  fields[4] != "*",
+	}, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges (a-b) and steps (*/n, a-b/n)
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+
+	set := map[int]bool{}
+
+	for _, part := range strings.Split(field, ",") {
+
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			v, err := strconv.Atoi(part[i+1:])
+			if err != nil || v <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			step = v
+			part = part[:i]
+		}
+
+		start, end := min, max
+
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", bounds[0])
+			}
+			end = start
+
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q", bounds[1])
+				}
+			} else if step > 1 {
+				//"5/10" means starting from 5 every 10
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is outside of %d-%d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Matches returns true if the schedule fires in the minute of t
+func (s cronSchedule) Matches(t time.Time) bool {
+
+	if !s.minutes[t.Minute()] || !s.hours[t.Hour()] || !s.months[int(t.Month())] {
+		return false
+	}
+
+	dom := s.daysOfMonth[t.Day()]
+	dow := s.daysOfWeek[int(t.Weekday())]
+
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// Next returns the first time after t at which the schedule fires. Returns false if there is none within 5 years,
+// which happens for dates such as February 30th.
+func (s cronSchedule) Next(t time.Time) (time.Time, bool) {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.Matches(time.Date(t.Year(), t.Month(), t.Day(), firstKey(s.hours), firstKey(s.minutes), 0, 0, t.Location())) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.Matches(t) {
+			return t, true
+		}
+
+		t = t.Add(time.Minute)
+	}
+
+	return time.Time{}, false
+}
+
+// firstKey returns the smallest key of a set
+func firstKey(set map[int]bool) int {
+	first := -1
+	for k := range set {
+		if first == -1 || k < first {
+			first = k
+		}
+	}
+	return first
+}
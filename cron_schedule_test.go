@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	RegisterTestingT(t)
+
+	good := []string{
+		"* * * * *",
+		"0 2 * * *",
+		"*/15 * * * 1-5",
+		"0,30 8-18/2 1 1,6 7",
+		"@daily",
+	}
+
+	for _, expr := range good {
+		_, err := parseCronSchedule(expr)
+		Expect(err).To(BeNil(), expr)
+	}
+
+	bad := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@never",
+	}
+
+	for _, expr := range bad {
+		_, err := parseCronSchedule(expr)
+		Expect(err).NotTo(BeNil(), expr)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	RegisterTestingT(t)
+
+	//2022-03-02 is a wednesday
+	base := time.Date(2022, 3, 2, 10, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2022, 3, 2, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, 3, 2, 10, 15, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2022, 3, 3, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2022, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2022, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"30 9 1 * *", time.Date(2022, 4, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		//either the day of month or the day of week matches
+		{"0 12 15 * 5", time.Date(2022, 3, 4, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := parseCronSchedule(c.expr)
+		Expect(err).To(BeNil())
+
+		next, ok := s.Next(base)
+		Expect(ok).To(BeTrue(), c.expr)
+		Expect(next).To(Equal(c.next), c.expr)
+	}
+
+	s, err := parseCronSchedule("0 0 30 2 *")
+	Expect(err).To(BeNil())
+
+	_, ok := s.Next(base)
+	Expect(ok).To(BeFalse())
+}
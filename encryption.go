@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedFile is the envelope written to disk by encryptWithPassphrase
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+const encryptedFileVersion = 1
+
+// encryptWithPassphrase encrypts data with AES-256-GCM using a key derived from the passphrase with scrypt
+func encryptWithPassphrase(data []byte, passphrase string) ([]byte, error) {
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newPassphraseCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(encryptedFile{
+		Version: encryptedFileVersion,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, data, nil),
+	}, "", "  ")
+}
+
+// decryptWithPassphrase reverses encryptWithPassphrase
+func decryptWithPassphrase(content []byte, passphrase string) ([]byte, error) {
+
+	var f encryptedFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("not an encrypted file: %v", err)
+	}
+
+	if f.Version != encryptedFileVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", f.Version)
+	}
+
+	gcm, err := newPassphraseCipher(passphrase, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %d", len(f.Nonce))
+	}
+
+	data, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt file, the key is wrong or the file is corrupted")
+	}
+
+	return data, nil
+}
+
+func newPassphraseCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("the encryption key cannot be empty")
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+const (
+	passwordLowercase = "abcdefghijkmnopqrstuvwxyz"
+	passwordUppercase = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordDigits    = "23456789"
+	passwordSymbols   = "!#%+-=_"
+)
+
+// generatePassword returns a random password with at least one lowercase letter, uppercase letter, digit and symbol.
+// Characters that are easily confused (0, O, 1, l, I) and characters that need quoting in a shell are not used.
+func generatePassword(length int) (string, error) {
+
+	classes := []string{passwordLowercase, passwordUppercase, passwordDigits, passwordSymbols}
+
+	if length < len(classes) {
+		return "", fmt.Errorf("the password length must be at least %d", len(classes))
+	}
+
+	all := strings.Join(classes, "")
+
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(all))))
+			if err != nil {
+				return "", err
+			}
+			password[i] = all[n.Int64()]
+		}
+
+		complete := true
+		for _, class := range classes {
+			if !strings.ContainsAny(string(password), class) {
+				complete = false
+				break
+			}
+		}
+
+		if complete {
+			return string(password), nil
+		}
+	}
+}
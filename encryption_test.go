@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	RegisterTestingT(t)
+
+	data := []byte("secret data")
+
+	encrypted, err := encryptWithPassphrase(data, "key")
+	Expect(err).To(BeNil())
+	Expect(string(encrypted)).NotTo(ContainSubstring("secret"))
+
+	decrypted, err := decryptWithPassphrase(encrypted, "key")
+	Expect(err).To(BeNil())
+	Expect(decrypted).To(Equal(data))
+
+	_, err = decryptWithPassphrase(encrypted, "other key")
+	Expect(err).NotTo(BeNil())
+
+	_, err = decryptWithPassphrase([]byte("secret data"), "key")
+	Expect(err).NotTo(BeNil())
+
+	_, err = encryptWithPassphrase(data, "")
+	Expect(err).NotTo(BeNil())
+}
+
+func TestGeneratePassword(t *testing.T) {
+	RegisterTestingT(t)
+
+	seen := map[string]bool{}
+
+	for i := 0; i < 20; i++ {
+		password, err := generatePassword(16)
+		Expect(err).To(BeNil())
+		Expect(password).To(HaveLen(16))
+		Expect(strings.ContainsAny(password, passwordLowercase)).To(BeTrue())
+		Expect(strings.ContainsAny(password, passwordUppercase)).To(BeTrue())
+		Expect(strings.ContainsAny(password, passwordDigits)).To(BeTrue())
+		Expect(strings.ContainsAny(password, passwordSymbols)).To(BeTrue())
+		Expect(seen[password]).To(BeFalse())
+		seen[password] = true
+	}
+
+	_, err := generatePassword(3)
+	Expect(err).NotTo(BeNil())
+}
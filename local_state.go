@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// getLocalStateDir returns the directory where the cli keeps local state such as scheduled operations.
+// It defaults to ~/.metalcloud and can be overridden with the METALCLOUD_STATE_DIR environment variable.
+func getLocalStateDir() (string, error) {
+	if v := os.Getenv("METALCLOUD_STATE_DIR"); v != "" {
+		return v, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".metalcloud"), nil
+}
+
+// readLocalState reads a json state file from the local state dir into obj. A missing file leaves obj untouched.
+func readLocalState(name string, obj interface{}) error {
+	dir, err := getLocalStateDir()
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, obj)
+}
+
+// writeLocalState writes obj as json into a state file in the local state dir
+func writeLocalState(name string, obj interface{}) error {
+	dir, err := getLocalStateDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, name), content, 0600)
+}
+
+// removeLocalState deletes a state file from the local state dir. A missing file is not an error.
+func removeLocalState(name string) error {
+	dir, err := getLocalStateDir()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// lockLocalState takes an exclusive lock on a state file so that concurrent runs of the cli, such as overlapping cron
+// jobs, do not act on the same state. The returned function releases the lock.
+func lockLocalState(name string) (func(), error) {
+	dir, err := getLocalStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	lockFile := filepath.Join(dir, name+".lock")
+
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s is in use by another run of the cli. If no other run is active remove %s", name, lockFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	f.Close()
+	if err != nil {
+		os.Remove(lockFile)
+		return nil, err
+	}
+
+	return func() { os.Remove(lockFile) }, nil
+}
+
+// getLocalStatePath returns the full path of a state file, used when pointing the user to it
+func getLocalStatePath(name string) string {
+	dir, err := getLocalStateDir()
+	if err != nil {
+		return name
+	}
+
+	return filepath.Join(dir, name)
+}
@@ -143,6 +143,11 @@ func executeCommand(args []string, commands []Command, clients map[string]metalc
 	setColoringEnabled(true)
 
 	for _, a := range args {
+		//the arguments after -- belong to the remote command, not to the CLI
+		if a == "--" {
+			break
+		}
+
 		if a == "-h" || a == "-help" || a == "--help" {
 			return fmt.Errorf(getCommandHelp(*cmd, true))
 		}
@@ -392,6 +397,7 @@ func getCommands(clients map[string]metalcloud.MetalCloudClient) []Command {
 		infrastructureCmds,
 		instanceArrayCmds,
 		instanceCmds,
+		customVariablesCmds,
 		driveArrayCmds,
 		sharedDriveCmds,
 		driveSnapshotCmds,
@@ -402,6 +408,9 @@ func getCommands(clients map[string]metalcloud.MetalCloudClient) []Command {
 		osAssetsCmds,
 		osTemplatesCmds,
 		serversCmds,
+		firmwareCmds,
+		serverTypeCmds,
+		rackCmds,
 		switchCmds,
 		switchPairCmds,
 		storageCmds,
@@ -411,11 +420,13 @@ func getCommands(clients map[string]metalcloud.MetalCloudClient) []Command {
 		versionCmds,
 		applyCmds,
 		networkProfileCmds,
+		externalConnectionCmds,
 		networkCmds,
 		jobsCmds,
 		shellCompletionCmds,
 		userCmds,
 		reportsCmds,
+		scheduleCmds,
 	}
 
 	filteredCommands := []Command{}
@@ -264,6 +264,16 @@ func TestExecuteCommand(t *testing.T) {
 	initFuncExecuted = false
 	execFuncExecutedOnDeveloperEndpoint = false
 
+	//a help flag after -- is passed on instead of showing the help
+	err = executeCommand([]string{"", "tests", "testp", "--", "ls", "--help"}, commands, clients)
+	Expect(err).To(BeNil())
+	Expect(execFuncExecuted).To(BeTrue())
+
+	execFuncExecuted = false
+
+	err = executeCommand([]string{"", "tests", "testp", "--help"}, commands, clients)
+	Expect(err).NotTo(BeNil())
+	Expect(execFuncExecuted).To(BeFalse())
 }
 
 func TestGetCommandHelp(t *testing.T) {
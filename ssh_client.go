@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// sshTarget holds the connection details of an instance
+type sshTarget struct {
+	Label    string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// Address returns the host:port string used to dial the target
+func (t sshTarget) Address() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// getInstanceSSHTarget returns the WAN ip, port and initial credentials of an instance.
+// The user and port can be overridden with the ssh_user and ssh_port arguments.
+func getInstanceSSHTarget(instance metalcloud.Instance, c *Command) (sshTarget, error) {
+
+	target := sshTarget{
+		Label:    instance.InstanceLabel,
+		Port:     22,
+		Username: "root",
+	}
+
+	for _, ip := range instance.InstanceCredentials.IPAddressesPublic {
+		if ip.IPType == "ipv4" {
+			target.Host = ip.IPHumanReadable
+			break
+		}
+		if target.Host == "" {
+			target.Host = ip.IPHumanReadable
+		}
+	}
+
+	if target.Host == "" {
+		return target, fmt.Errorf("instance %s (#%d) has no WAN ip address", instance.InstanceLabel, instance.InstanceID)
+	}
+
+	if v := instance.InstanceCredentials.SSH; v != nil {
+		if v.Port != 0 {
+			target.Port = v.Port
+		}
+		if v.Username != "" {
+			target.Username = v.Username
+		}
+		target.Password = v.InitialPassword
+	}
+
+	if v, ok := getStringParamOk(c.Arguments["ssh_user"]); ok {
+		target.Username = v
+	}
+
+	if v, ok := getIntParamOk(c.Arguments["ssh_port"]); ok {
+		target.Port = v
+	}
+
+	return target, nil
+}
+
+// getSSHClientConfig builds the client configuration for a target. Authentication is attempted, in order, with the
+// ssh agent, the identity file (or the default keys in ~/.ssh) and the instance's initial password.
+// Host keys are checked against the known_hosts file and the password is only sent to hosts whose key is already known.
+// The returned function closes the connection to the ssh agent and must be called once the connection is no longer used.
+func getSSHClientConfig(target sshTarget, c *Command) (*ssh.ClientConfig, func(), error) {
+
+	auth := []ssh.AuthMethod{}
+	cleanup := func() {}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			cleanup = func() { conn.Close() }
+		}
+	}
+
+	config, err := buildSSHClientConfig(target, c, auth)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return config, cleanup, nil
+}
+
+func buildSSHClientConfig(target sshTarget, c *Command, auth []ssh.AuthMethod) (*ssh.ClientConfig, error) {
+
+	keyFiles := []string{}
+	if v, ok := getStringParamOk(c.Arguments["identity_file"]); ok {
+		keyFiles = append(keyFiles, v)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			keyFiles = append(keyFiles, filepath.Join(home, ".ssh", name))
+		}
+	}
+
+	signers := []ssh.Signer{}
+	for _, keyFile := range keyFiles {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			if getStringParam(c.Arguments["identity_file"]) != "" {
+				return nil, err
+			}
+			continue
+		}
+
+		signer, err := ssh.ParsePrivateKey(content)
+		if err != nil {
+			if getStringParam(c.Arguments["identity_file"]) != "" {
+				return nil, fmt.Errorf("could not use identity file %s: %v", keyFile, err)
+			}
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	knownHostsFile, ok := getStringParamOk(c.Arguments["known_hosts_file"])
+	if !ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	verifier := &hostKeyVerifier{
+		path:      knownHostsFile,
+		acceptNew: getBoolParam(c.Arguments["accept_new_host_key"]),
+		insecure:  getBoolParam(c.Arguments["insecure_ignore_host_key"]),
+	}
+
+	if target.Password != "" {
+		auth = append(auth, ssh.PasswordCallback(func() (string, error) {
+			if !verifier.verified {
+				return "", fmt.Errorf("the initial password is not sent to %s as its host key is not in %s", target.Label, knownHostsFile)
+			}
+			return target.Password, nil
+		}))
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no ssh credentials available for %s. Use --identity-file to specify an identity file", target.Label)
+	}
+
+	return &ssh.ClientConfig{
+		User:            target.Username,
+		Auth:            auth,
+		HostKeyCallback: verifier.check,
+		Timeout:         15 * time.Second,
+	}, nil
+}
+
+// knownHostsMutex serializes the reads and updates of known_hosts files by concurrent connections
+var knownHostsMutex sync.Mutex
+
+// hostKeyVerifier checks host keys against a known_hosts file.
+// verified is only set when the key of the last connection was found in the file.
+type hostKeyVerifier struct {
+	path      string
+	acceptNew bool
+	insecure  bool
+	verified  bool
+}
+
+func (v *hostKeyVerifier) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+
+	v.verified = false
+
+	if v.insecure {
+		return nil
+	}
+
+	knownHostsMutex.Lock()
+	defer knownHostsMutex.Unlock()
+
+	callback, err := knownhosts.New(v.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if callback != nil {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			v.verified = true
+			return nil
+		}
+
+		keyErr, ok := err.(*knownhosts.KeyError)
+		if !ok {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return fmt.Errorf("the host key of %s does not match the one in %s, the host might be impersonated: %v", hostname, v.path, err)
+		}
+	}
+
+	if !v.acceptNew {
+		return fmt.Errorf("the host key of %s is not in %s. Add it, for example with ssh-keyscan, or use --accept-new-host-key", hostname, v.path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(v.path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(v.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+
+	return err
+}
+
+// sshRun runs a command on the target and copies its output to stdout and stderr
+func sshRun(target sshTarget, config *ssh.ClientConfig, command string, stdout io.Writer, stderr io.Writer) error {
+
+	client, err := ssh.Dial("tcp", target.Address(), config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	return session.Run(command)
+}
+
+// sshInteractive opens an interactive shell on the target using the current terminal
+func sshInteractive(target sshTarget, config *ssh.ClientConfig) error {
+
+	client, err := ssh.Dial("tcp", target.Address(), config)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+
+	fd := int(os.Stdin.Fd())
+
+	if terminal.IsTerminal(fd) {
+		oldState, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(fd, oldState)
+
+		width, height, err := terminal.GetSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+
+		term := os.Getenv("TERM")
+		if term == "" {
+			term = "xterm-256color"
+		}
+
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+
+		if err := session.RequestPty(term, height, width, modes); err != nil {
+			return err
+		}
+	}
+
+	if err := session.Shell(); err != nil {
+		return err
+	}
+
+	return session.Wait()
+}
+
+// prefixWriter writes every complete line to the underlying writer prefixed with a label.
+// Writers sharing the same mutex never interleave their lines.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(prefix string, out io.Writer, mu *sync.Mutex) *prefixWriter {
+	return &prefixWriter{
+		prefix: prefix,
+		out:    out,
+		mu:     mu,
+	}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			//incomplete line, keep it for the next write
+			w.buf.Reset()
+			w.buf.Write(line)
+			break
+		}
+		if err := w.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any remaining incomplete line
+func (w *prefixWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	line := append(w.buf.Bytes(), '\n')
+	w.buf.Reset()
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := fmt.Fprintf(w.out, "%s %s", w.prefix, line)
+	return err
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	metalcloud "github.com/metalsoft-io/metal-cloud-sdk-go/v2"
+	mock_metalcloud "github.com/metalsoft-io/metalcloud-cli/helpers"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startTestSSHServer starts an ssh server that accepts the given password and answers every exec request
+// by echoing the command. Commands starting with "fail" exit with status 3. The port and the host key are returned.
+func startTestSSHServer(t *testing.T, password string) (int, ssh.PublicKey) {
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).To(BeNil())
+
+	signer, err := ssh.NewSignerFromKey(key)
+	Expect(err).To(BeNil())
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleTestSSHConn(conn, config)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, signer.PublicKey()
+}
+
+func handleTestSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+
+				command := string(req.Payload[4:])
+				req.Reply(true, nil)
+
+				status := uint32(0)
+				if strings.HasPrefix(command, "fail") {
+					status = 3
+					fmt.Fprintf(channel.Stderr(), "failed: %s\n", command)
+				} else {
+					fmt.Fprintf(channel, "ran: %s\nsecond line", command)
+				}
+
+				payload := make([]byte, 4)
+				binary.BigEndian.PutUint32(payload, status)
+				channel.SendRequest("exit-status", false, payload)
+				channel.Close()
+			}
+		}()
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	RegisterTestingT(t)
+
+	var out bytes.Buffer
+	var mu sync.Mutex
+
+	w := newPrefixWriter("[test]", &out, &mu)
+
+	fmt.Fprint(w, "first line\nsec")
+	fmt.Fprint(w, "ond line\nincomplete")
+	Expect(out.String()).To(Equal("[test] first line\n[test] second line\n"))
+
+	Expect(w.Flush()).To(BeNil())
+	Expect(out.String()).To(Equal("[test] first line\n[test] second line\n[test] incomplete\n"))
+}
+
+func TestGetInstanceSSHTarget(t *testing.T) {
+	RegisterTestingT(t)
+
+	instance := metalcloud.Instance{
+		InstanceID:    100,
+		InstanceLabel: "instance-100",
+		InstanceCredentials: metalcloud.InstanceCredentials{
+			IPAddressesPublic: []metalcloud.IP{
+				{IPType: "ipv6", IPHumanReadable: "2a02::1"},
+				{IPType: "ipv4", IPHumanReadable: "192.168.0.1"},
+			},
+			SSH: &metalcloud.SSH{
+				Port:            2222,
+				Username:        "admin",
+				InitialPassword: "secret",
+			},
+		},
+	}
+
+	cmd := MakeEmptyCommand()
+
+	target, err := getInstanceSSHTarget(instance, &cmd)
+	Expect(err).To(BeNil())
+	Expect(target.Address()).To(Equal("192.168.0.1:2222"))
+	Expect(target.Username).To(Equal("admin"))
+	Expect(target.Password).To(Equal("secret"))
+
+	cmd = MakeCommand(map[string]interface{}{
+		"ssh_user": "root",
+		"ssh_port": 22,
+	})
+
+	target, err = getInstanceSSHTarget(instance, &cmd)
+	Expect(err).To(BeNil())
+	Expect(target.Address()).To(Equal("192.168.0.1:22"))
+	Expect(target.Username).To(Equal("root"))
+
+	instance.InstanceCredentials.IPAddressesPublic = nil
+
+	_, err = getInstanceSSHTarget(instance, &cmd)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestInstanceArrayExecCmd(t *testing.T) {
+	RegisterTestingT(t)
+	ctrl := gomock.NewController(t)
+
+	//make sure only the password is used
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", "")
+	defer os.Setenv("SSH_AUTH_SOCK", sock)
+
+	port, hostKey := startTestSSHServer(t, "secret")
+
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	Expect(os.WriteFile(knownHostsFile, []byte(knownhosts.Line([]string{knownhosts.Normalize(fmt.Sprintf("127.0.0.1:%d", port))}, hostKey)+"\n"), 0600)).To(BeNil())
+
+	client := mock_metalcloud.NewMockMetalCloudClient(ctrl)
+
+	ia := metalcloud.InstanceArray{
+		InstanceArrayID:    100,
+		InstanceArrayLabel: "ia",
+	}
+
+	client.EXPECT().
+		InstanceArrayGet(100).
+		Return(&ia, nil).
+		AnyTimes()
+
+	credentials := metalcloud.InstanceCredentials{
+		IPAddressesPublic: []metalcloud.IP{
+			{IPType: "ipv4", IPHumanReadable: "127.0.0.1"},
+		},
+		SSH: &metalcloud.SSH{
+			Port:            port,
+			Username:        "root",
+			InitialPassword: "secret",
+		},
+	}
+
+	client.EXPECT().
+		InstanceArrayInstances(100).
+		Return(&map[string]metalcloud.Instance{
+			"i1": {InstanceID: 201, InstanceLabel: "instance-201", ServerID: 1, InstanceServiceStatus: "active", InstanceCredentials: credentials},
+			"i2": {InstanceID: 202, InstanceLabel: "instance-202", ServerID: 2, InstanceServiceStatus: "active", InstanceCredentials: credentials},
+			"i3": {InstanceID: 203, InstanceLabel: "instance-203", InstanceServiceStatus: "ordered"},
+		}, nil).
+		AnyTimes()
+
+	var stdin, stdout bytes.Buffer
+	SetConsoleIOChannel(&stdin, &stdout)
+	defer SetConsoleIOChannel(os.Stdin, os.Stdout)
+
+	cmd := MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"identity_file":              os.DevNull,
+		"known_hosts_file":           knownHostsFile,
+	})
+
+	//an unusable identity file is reported
+	cmd.FlagSet = flag.NewFlagSet("exec", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"--", "uptime"})).To(BeNil())
+
+	_, err := instanceArrayExecCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+
+	cmd = MakeCommand(map[string]interface{}{
+		"instance_array_id_or_label": "100",
+		"known_hosts_file":           knownHostsFile,
+	})
+	cmd.FlagSet = flag.NewFlagSet("exec", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"--", "uptime", "-p"})).To(BeNil())
+
+	ret, err := instanceArrayExecCmd(&cmd, client)
+	Expect(err).To(BeNil())
+	Expect(ret).To(ContainSubstring("succeeded on 2 instances"))
+	Expect(stdout.String()).To(ContainSubstring("[instance-201] ran: uptime -p\n"))
+	Expect(stdout.String()).To(ContainSubstring("[instance-202] ran: uptime -p\n"))
+	Expect(stdout.String()).To(ContainSubstring("[instance-202] second line\n"))
+
+	cmd.FlagSet = flag.NewFlagSet("exec", flag.ContinueOnError)
+	Expect(cmd.FlagSet.Parse([]string{"--", "fail"})).To(BeNil())
+
+	_, err = instanceArrayExecCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("instance-201: exited with status 3"))
+
+	//no command
+	cmd.FlagSet = flag.NewFlagSet("exec", flag.ContinueOnError)
+
+	_, err = instanceArrayExecCmd(&cmd, client)
+	Expect(err).NotTo(BeNil())
+}
+
+func TestSSHHostKeyVerification(t *testing.T) {
+	RegisterTestingT(t)
+
+	//make sure only the password is used
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", "")
+	defer os.Setenv("SSH_AUTH_SOCK", sock)
+
+	port, _ := startTestSSHServer(t, "secret")
+
+	target := sshTarget{
+		Label:    "instance-100",
+		Host:     "127.0.0.1",
+		Port:     port,
+		Username: "root",
+		Password: "secret",
+	}
+
+	knownHostsFile := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+
+	run := func(arguments map[string]interface{}) error {
+		arguments["known_hosts_file"] = knownHostsFile
+		cmd := MakeCommand(arguments)
+
+		config, cleanup, err := getSSHClientConfig(target, &cmd)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		var stdout bytes.Buffer
+		return sshRun(target, config, "uptime", &stdout, &stdout)
+	}
+
+	//unknown hosts are refused by default
+	err := run(map[string]interface{}{})
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("--accept-new-host-key"))
+
+	//the key is accepted but the password is not sent to a host seen for the first time
+	err = run(map[string]interface{}{"accept_new_host_key": true})
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("not sent"))
+
+	content, err := os.ReadFile(knownHostsFile)
+	Expect(err).To(BeNil())
+	Expect(string(content)).To(ContainSubstring(fmt.Sprintf("[127.0.0.1]:%d", port)))
+
+	//now the key is known
+	Expect(run(map[string]interface{}{})).To(BeNil())
+
+	//the password is never sent when host keys are not checked
+	err = run(map[string]interface{}{"insecure_ignore_host_key": true})
+	Expect(err).NotTo(BeNil())
+
+	//a different server on the same address is refused even with accept-new
+	otherPort, _ := startTestSSHServer(t, "secret")
+	other, err := os.ReadFile(knownHostsFile)
+	Expect(err).To(BeNil())
+	Expect(os.WriteFile(knownHostsFile, []byte(strings.Replace(string(other), fmt.Sprintf(":%d", port), fmt.Sprintf(":%d", otherPort), 1)), 0600)).To(BeNil())
+	target.Port = otherPort
+
+	err = run(map[string]interface{}{"accept_new_host_key": true})
+	Expect(err).NotTo(BeNil())
+	Expect(err.Error()).To(ContainSubstring("does not match"))
+}